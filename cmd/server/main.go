@@ -2,76 +2,286 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
+	"github.com/burakcan/ipburack/internal/clientip"
 	"github.com/burakcan/ipburack/internal/config"
 	"github.com/burakcan/ipburack/internal/geodb"
+	"github.com/burakcan/ipburack/internal/grpcserver"
 	"github.com/burakcan/ipburack/internal/handlers"
 	"github.com/burakcan/ipburack/internal/logger"
+	"github.com/burakcan/ipburack/internal/metrics"
 	"github.com/burakcan/ipburack/internal/middleware"
+	"github.com/burakcan/ipburack/internal/tracing"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Set at build time, e.g.
+// -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
 )
 
 func main() {
 	log := logger.New()
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		log.Error("invalid configuration", map[string]any{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	// Validate has already checked the level name
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	log = logger.NewWithLevel(level)
 
 	log.Info("starting server", map[string]any{
-		"host":                  cfg.Host,
-		"port":                  cfg.Port,
-		"country_db_path":       cfg.CountryDBPath,
-		"city_db_ipv4_path":     cfg.CityDBIPv4Path,
-		"city_db_ipv6_path":     cfg.CityDBIPv6Path,
-		"update_interval_hours": cfg.UpdateIntervalHours,
-		"api_key_enabled":       cfg.APIKey != "",
+		"version":                version,
+		"commit":                 commit,
+		"host":                   cfg.Host,
+		"port":                   cfg.Port,
+		"log_level":              cfg.LogLevel,
+		"log_redact_ip":          cfg.LogRedactIP,
+		"otel_enabled":           cfg.OTelEnabled,
+		"pprof_enabled":          cfg.PprofEnabled,
+		"listen_socket":          cfg.ListenSocket,
+		"grpc_port":              cfg.GRPCPort,
+		"admin_addr":             cfg.AdminAddr,
+		"tls":                    cfg.TLSEnabled(),
+		"h2c":                    cfg.EnableH2C,
+		"http_write_timeout":     cfg.HTTPWriteTimeout.String(),
+		"shutdown_timeout":       cfg.ShutdownTimeout.String(),
+		"db_format":              cfg.DBFormat,
+		"country_db_path":        cfg.CountryDBPath,
+		"city_db_ipv4_path":      cfg.CityDBIPv4Path,
+		"city_db_ipv6_path":      cfg.CityDBIPv6Path,
+		"asn_db_path":            cfg.ASNDBPath,
+		"update_interval_hours":  cfg.UpdateIntervalHours,
+		"db_offline":             cfg.DBOffline,
+		"download_max_retries":   cfg.DownloadMaxRetries,
+		"download_timeout":       cfg.DownloadTimeout.String(),
+		"api_key_enabled":        len(cfg.APIKeys) > 0,
+		"api_keys":               len(cfg.APIKeys),
+		"api_key_scopes":         len(cfg.APIKeyScopes),
+		"auth_scheme":            cfg.AuthScheme,
+		"max_batch_size":         cfg.MaxBatchSize,
+		"max_bulk_lines":         cfg.MaxBulkLines,
+		"default_lookup_mode":    cfg.DefaultLookupMode,
+		"self_lookup_mode":       cfg.SelfLookupMode,
+		"ip_lookup_mode":         cfg.IPLookupMode,
+		"strict_lookup":          cfg.StrictLookup,
+		"not_found_as_ok":        cfg.NotFoundAsOK,
+		"json_field_case":        cfg.JSONFieldCase,
+		"health_check_db":        cfg.HealthCheckDB,
+		"trusted_proxies":        len(cfg.TrustedProxies),
+		"client_ip_headers":      cfg.ClientIPHeaders,
+		"allow_ip_override":      cfg.AllowIPOverride,
+		"anonymize_ips":          cfg.AnonymizeIPs,
+		"rate_limit_rps":         cfg.RateLimitRPS,
+		"max_concurrent_lookups": cfg.MaxConcurrentLookups,
+		"cors_allowed_origins":   cfg.CORSAllowedOrigins,
+		"lookup_cache_size":      cfg.LookupCacheSize,
+		"cache_warmup_file":      cfg.CacheWarmupFile,
+		"detect_private_ips":     cfg.DetectPrivateIPs,
+		"db_max_age":             cfg.DBMaxAge.String(),
+		"self_test_probes":       cfg.SelfTestProbes,
 	})
 
-	// Initialize the geo database (country + city IPv4/IPv6)
-	updateInterval := time.Duration(cfg.UpdateIntervalHours) * time.Hour
-	geo := geodb.New(
-		cfg.CountryDBPath, cfg.CountryDBURL,
-		cfg.CityDBIPv4Path, cfg.CityDBIPv4URL,
-		cfg.CityDBIPv6Path, cfg.CityDBIPv6URL,
-		updateInterval, log,
-	)
+	// Without this the global tracer is a no-op
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.OTelEnabled {
+		if shutdownTracing, err = tracing.Setup(context.Background()); err != nil {
+			log.Error("failed to set up tracing", map[string]any{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	m := metrics.New()
 
-	if err := geo.Start(ctx); err != nil {
-		log.Error("failed to start geo database", map[string]any{
-			"error": err.Error(),
-		})
-		os.Exit(1)
-	}
+	// Initialize the geo database (country + city IPv4/IPv6, optional ASN)
+	geo := geodb.New(geodb.Options{
+		Format:       geodb.Format(cfg.DBFormat),
+		CountryPath:  cfg.CountryDBPath,
+		CountryURL:   cfg.CountryDBURL,
+		CityIPv4Path: cfg.CityDBIPv4Path,
+		CityIPv4URL:  cfg.CityDBIPv4URL,
+		CityIPv6Path: cfg.CityDBIPv6Path,
+		CityIPv6URL:  cfg.CityDBIPv6URL,
+		ASNPath:      cfg.ASNDBPath,
+		ASNURL:       cfg.ASNDBURL,
+
+		CountrySHA256URL:  cfg.CountryDBSHA256URL,
+		CityIPv4SHA256URL: cfg.CityDBIPv4SHA256URL,
+		CityIPv6SHA256URL: cfg.CityDBIPv6SHA256URL,
+		ASNSHA256URL:      cfg.ASNDBSHA256URL,
+
+		UpdateInterval:     time.Duration(cfg.UpdateIntervalHours) * time.Hour,
+		DownloadRetries:    cfg.DownloadMaxRetries,
+		DownloadRetryDelay: cfg.DownloadRetryDelay,
+		DownloadTimeout:    cfg.DownloadTimeout,
+		CacheSize:          cfg.LookupCacheSize,
+		Metrics:            m,
+		Offline:            cfg.DBOffline,
+		DetectPrivate:      cfg.DetectPrivateIPs,
+		MaxAge:             cfg.DBMaxAge,
+		SelfTest:           selfTestProbes(cfg.SelfTestProbes),
+	}, log)
+
+	// Cancelled on SIGINT/SIGTERM, so a signal during a slow startup download
+	// aborts it instead of waiting out the retries
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Initialize handlers and auth middleware
-	h := handlers.New(geo)
-	auth := middleware.NewAuth(cfg.APIKey)
+	// Handlers and the rate limiter share client IP extraction
+	clientIP := clientip.New(cfg.ClientIPHeaders, cfg.TrustedProxies)
 
-	// Set up routes (health is public, lookup requires auth)
+	// Initialize handlers and middleware
+	h := handlers.New(geo, handlers.Options{
+		MaxBatchSize:    cfg.MaxBatchSize,
+		MaxBulkLines:    cfg.MaxBulkLines,
+		DefaultMode:     handlers.LookupMode(cfg.DefaultLookupMode),
+		SelfMode:        handlers.LookupMode(cfg.SelfLookupMode),
+		IPMode:          handlers.LookupMode(cfg.IPLookupMode),
+		Strict:          cfg.StrictLookup,
+		NotFoundAsOK:    cfg.NotFoundAsOK,
+		FieldCase:       handlers.FieldCase(cfg.JSONFieldCase),
+		CheckDB:         cfg.HealthCheckDB,
+		ClientIP:        clientIP,
+		Metrics:         m,
+		Logger:          log,
+		RedactIPs:       cfg.LogRedactIP,
+		AllowIPOverride: cfg.AllowIPOverride,
+		AnonymizeIPs:    cfg.AnonymizeIPs,
+
+		Build: handlers.BuildInfo{
+			Version:   version,
+			Commit:    commit,
+			BuildDate: buildDate,
+		},
+	})
+	admin := handlers.NewAdmin(geo)
+	auth := middleware.NewAuth(cfg.APIKeys, middleware.AuthScheme(cfg.AuthScheme))
+	auth.SetScopes(cfg.APIKeyScopes)
+	// SIGUSR1 reloads the API keys. It is caught from here on, since its
+	// default action would kill the server while databases still load
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	limit := middleware.NewRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst, clientIP.ClientIP)
+	busy := middleware.NewConcurrencyLimiter(cfg.MaxConcurrentLookups)
+
+	// Set up routes (health, readiness, version, metrics and the lookup page
+	// are public, lookup and admin require auth). With ADMIN_ADDR the
+	// control-plane routes move to their own listener.
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /health", h.Health)
-	mux.HandleFunc("GET /lookup", auth.Wrap(h.LookupSelf))
-	mux.HandleFunc("GET /lookup/{ip}", auth.Wrap(h.LookupIP))
+	adminMux := mux
+	if cfg.AdminAddr != "" {
+		adminMux = http.NewServeMux()
+	}
+	adminMux.HandleFunc("GET /health", h.Health)
+	adminMux.HandleFunc("GET /ready", h.Readiness)
+	adminMux.HandleFunc("GET /version", h.Version)
+	// {$} matches / alone, so unknown paths still get a 404
+	mux.HandleFunc("GET /{$}", h.UI)
+	adminMux.Handle("GET /metrics", m)
+	// Lookups are rate limited before auth so key guessing is throttled
+	// too; only authenticated requests count towards the concurrency limit
+	lookup := func(next http.HandlerFunc) http.HandlerFunc {
+		return limit.Wrap(auth.Wrap(busy.Wrap(next)))
+	}
+	mux.HandleFunc("GET /lookup", lookup(h.LookupSelf))
+	mux.HandleFunc("POST /lookup", lookup(h.LookupPost))
+	mux.HandleFunc("GET /lookup/{ip...}", lookup(h.LookupIP))
+	mux.HandleFunc("GET /lookup/debug", lookup(h.LookupDebug))
+	mux.HandleFunc("POST /lookup/batch", lookup(h.LookupBatch))
+	mux.HandleFunc("POST /lookup/bulk", lookup(h.LookupBulk))
+	// GET routes also match HEAD, which the server answers without a body;
+	// OPTIONS lists each lookup path's methods and needs no API key
+	mux.HandleFunc("OPTIONS /lookup", handlers.Allow("GET", "HEAD", "POST", "OPTIONS"))
+	mux.HandleFunc("OPTIONS /lookup/{ip...}", handlers.Allow("GET", "HEAD", "OPTIONS"))
+	mux.HandleFunc("OPTIONS /lookup/debug", handlers.Allow("GET", "HEAD", "OPTIONS"))
+	mux.HandleFunc("OPTIONS /lookup/batch", handlers.Allow("POST", "OPTIONS"))
+	mux.HandleFunc("OPTIONS /lookup/bulk", handlers.Allow("POST", "OPTIONS"))
+	adminMux.HandleFunc("POST /admin/refresh", auth.Wrap(admin.Refresh))
+	adminMux.HandleFunc("GET /admin/databases", auth.Wrap(admin.Databases))
+	if cfg.PprofEnabled {
+		registerPprof(adminMux, auth)
+	}
+
+	// Panic recovery wraps the request logger so it also covers it, and
+	// request IDs are assigned first so every log line can carry one
+	loggedIP := clientIP.ClientIP
+	switch {
+	case cfg.LogRedactIP:
+		loggedIP = func(r *http.Request) string { return clientip.Redact(clientIP.ClientIP(r)) }
+	case cfg.AnonymizeIPs:
+		loggedIP = func(r *http.Request) string { return handlers.AnonymizeIP(clientIP.ClientIP(r)) }
+	}
+	common := func(handler http.Handler) http.Handler {
+		handler = middleware.NewRequestLogger(log, loggedIP).Wrap(handler)
+		if cfg.OTelEnabled {
+			handler = middleware.NewTracing().Wrap(handler)
+		}
+		handler = middleware.NewRecoverer(log).Wrap(handler)
+		return middleware.NewRequestIDs().Wrap(handler)
+	}
+	var handler http.Handler = mux
+	handler = middleware.NewCompressor(middleware.DefaultCompressMinSize, "/health").Wrap(handler)
+	handler = middleware.NewCORS(cfg.CORSAllowedOrigins).Wrap(handler)
+	handler = common(handler)
+	// h2c serves HTTP/2 over plaintext to clients that ask for it, by
+	// upgrade or with prior knowledge; everyone else keeps HTTP/1.1. TLS
+	// connections negotiate HTTP/2 on their own
+	if cfg.EnableH2C && !cfg.TLSEnabled() {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: cfg.HTTPIdleTimeout})
+	}
 
 	server := &http.Server{
 		Addr:         cfg.Addr(),
-		Handler:      mux,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Handler:      handler,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+
+	ln, err := listen(cfg)
+	if err != nil {
+		log.Error("failed to listen", map[string]any{
+			"error": err.Error(),
+		})
+		os.Exit(1)
 	}
 
 	// Start server in a goroutine
 	go func() {
 		log.Info("server listening", map[string]any{
-			"addr": cfg.Addr(),
+			"addr": ln.Addr().String(),
+			"tls":  cfg.TLSEnabled(),
 		})
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.TLSEnabled() {
+			err = server.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error("server error", map[string]any{
 				"error": err.Error(),
 			})
@@ -79,15 +289,106 @@ func main() {
 		}
 	}()
 
+	// The admin listener is optional; without it adminMux is mux
+	var adminServer *http.Server
+	if cfg.AdminAddr != "" {
+		adminServer, err = serveAdmin(cfg, common(adminMux), log)
+		if err != nil {
+			log.Error("failed to start admin server", map[string]any{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+	}
+
+	// The gRPC API is optional and served on its own port
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		grpcServer, err = serveGRPC(cfg, geo, grpcserver.Options{
+			Auth:         auth,
+			RateLimit:    limit,
+			Concurrency:  busy,
+			MaxBatchSize: cfg.MaxBatchSize,
+		}, log)
+		if err != nil {
+			log.Error("failed to start gRPC server", map[string]any{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+	}
+
+	// Load databases while already serving, so liveness probes pass and
+	// /ready reports 503 until the country database is available
+	if err := geo.Start(ctx); err != nil {
+		log.Error("failed to start geo database", map[string]any{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+
+	// Warm the cache in the background with the options requests without
+	// flags use, once per route mode, since those are the results they will
+	// find
+	if cfg.CacheWarmupFile != "" {
+		go func() {
+			for _, mode := range warmupModes(cfg) {
+				opts := geodb.LookupOptions{
+					UseCity: mode == string(handlers.ModeCity),
+					Strict:  cfg.StrictLookup,
+				}
+				if err := geo.WarmCache(ctx, cfg.CacheWarmupFile, opts); err != nil {
+					log.Error("cache warmup failed", map[string]any{
+						"path":  cfg.CacheWarmupFile,
+						"mode":  mode,
+						"error": err.Error(),
+					})
+					return
+				}
+			}
+		}()
+	}
+
+	// SIGHUP reloads the database files from disk, for operators who
+	// replace them out-of-band
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Info("SIGHUP received, reloading databases", nil)
+				var reloaded []string
+				failed := 0
+				for _, s := range geo.Reload() {
+					if s.Error != "" {
+						failed++
+						continue
+					}
+					reloaded = append(reloaded, s.Database)
+				}
+				log.Info("database reload completed", map[string]any{
+					"reloaded": reloaded,
+					"failed":   failed,
+				})
+			case <-usr1:
+				reloadAPIKeys(auth, log)
+			}
+		}
+	}()
+
 	// Wait for shutdown signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
+	signal.Stop(hup)
+	signal.Stop(usr1)
+	stop()
 
 	log.Info("shutting down server", nil)
 
 	// Give outstanding requests time to complete
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer shutdownCancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
@@ -95,9 +396,187 @@ func main() {
 			"error": err.Error(),
 		})
 	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+	// Last, so /health and /metrics stay up while lookups drain
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			log.Error("admin server shutdown error", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}
 
 	// Stop the geo database (stops background updates)
 	geo.Stop()
 
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error("tracing shutdown error", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
 	log.Info("server stopped", nil)
 }
+
+// listen binds the configured Unix socket, or host:port when none is set.
+// Shutting the server down closes the listener, which also removes the
+// socket file.
+func listen(cfg *config.Config) (net.Listener, error) {
+	if cfg.ListenSocket == "" {
+		return net.Listen("tcp", cfg.Addr())
+	}
+
+	// A socket left behind by an unclean exit would make the bind fail.
+	// Anything that isn't a socket is left alone.
+	if fi, err := os.Lstat(cfg.ListenSocket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(cfg.ListenSocket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	return net.Listen("unix", cfg.ListenSocket)
+}
+
+// selfTestProbes converts the validated SELF_TEST_PROBES entries.
+func selfTestProbes(entries []string) []geodb.Probe {
+	probes := make([]geodb.Probe, 0, len(entries))
+	for _, entry := range entries {
+		ip, country, _ := config.ParseProbe(entry)
+		probes = append(probes, geodb.Probe{IP: ip.String(), Country: country})
+	}
+	return probes
+}
+
+// warmupModes lists the distinct lookup modes of requests without flags:
+// DEFAULT_LOOKUP_MODE, and the route modes that replace it.
+func warmupModes(cfg *config.Config) []string {
+	modes := []string{cfg.DefaultLookupMode}
+	for _, mode := range []string{cfg.SelfLookupMode, cfg.IPLookupMode} {
+		if mode != "" && !slices.Contains(modes, mode) {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// reloadAPIKeys re-reads the API keys from the config file, API_KEY_FILE and
+// the environment, and swaps them into auth. On failure the current keys
+// stay, as they do when the new set is empty: that would silently turn
+// authentication off.
+func reloadAPIKeys(auth *middleware.AuthMiddleware, log *logger.Logger) {
+	cfg, err := config.Load()
+	if err == nil {
+		// Catches scopes naming keys or fields that don't exist
+		err = cfg.Validate()
+	}
+	if err != nil {
+		log.Error("API key reload failed, keeping the current keys", map[string]any{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(cfg.APIKeys) == 0 && auth.Enabled() {
+		log.Error("API key reload found no keys, keeping the current keys", nil)
+		return
+	}
+	// Scopes first, so a new key is never accepted without its scope
+	auth.SetScopes(cfg.APIKeyScopes)
+	auth.SetKeys(cfg.APIKeys)
+	log.Info("API keys reloaded", map[string]any{
+		"api_keys":       len(cfg.APIKeys),
+		"api_key_scopes": len(cfg.APIKeyScopes),
+	})
+}
+
+// serveAdmin starts the admin server on ADMIN_ADDR, with the main server's
+// timeouts and TLS certificate.
+func serveAdmin(cfg *config.Config, handler http.Handler, log *logger.Logger) (*http.Server, error) {
+	ln, err := net.Listen("tcp", cfg.AdminAddr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+
+	go func() {
+		log.Info("admin server listening", map[string]any{
+			"addr": ln.Addr().String(),
+			"tls":  cfg.TLSEnabled(),
+		})
+		var err error
+		if cfg.TLSEnabled() {
+			err = server.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Error("admin server error", map[string]any{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+	}()
+	return server, nil
+}
+
+// serveGRPC starts the gRPC server on its own listener, sharing the HTTP
+// server's API keys, limits and TLS certificate.
+func serveGRPC(cfg *config.Config, geo grpcserver.GeoLookup, opts grpcserver.Options, log *logger.Logger) (*grpc.Server, error) {
+	if cfg.TLSEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		opts.Creds = creds
+	}
+
+	ln, err := net.Listen("tcp", cfg.GRPCAddr())
+	if err != nil {
+		return nil, err
+	}
+
+	s := grpcserver.New(geo, opts)
+	go func() {
+		log.Info("gRPC server listening", map[string]any{
+			"addr": ln.Addr().String(),
+			"tls":  cfg.TLSEnabled(),
+		})
+		if err := s.Serve(ln); err != nil {
+			log.Error("gRPC server error", map[string]any{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+	}()
+	return s, nil
+}
+
+// stopGRPC lets in-flight RPCs finish, cutting them off once ctx is done.
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Stop()
+	}
+}
+
+// registerPprof serves the runtime profiles under /debug/pprof/, behind auth.
+func registerPprof(mux *http.ServeMux, auth *middleware.AuthMiddleware) {
+	mux.HandleFunc("GET /debug/pprof/", auth.Wrap(pprof.Index))
+	mux.HandleFunc("GET /debug/pprof/cmdline", auth.Wrap(pprof.Cmdline))
+	mux.HandleFunc("GET /debug/pprof/profile", auth.Wrap(pprof.Profile))
+	mux.HandleFunc("GET /debug/pprof/symbol", auth.Wrap(pprof.Symbol))
+	mux.HandleFunc("POST /debug/pprof/symbol", auth.Wrap(pprof.Symbol))
+	mux.HandleFunc("GET /debug/pprof/trace", auth.Wrap(pprof.Trace))
+}
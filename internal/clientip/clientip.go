@@ -0,0 +1,132 @@
+// Package clientip determines the address of the client behind a request,
+// taking forwarding headers and trusted proxies into account.
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// DefaultHeaders are consulted when no headers are configured.
+var DefaultHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// SourceRemoteAddr is the source reported by Resolve when no header was used.
+const SourceRemoteAddr = "RemoteAddr"
+
+type Resolver struct {
+	headers []string
+	trusted []netip.Prefix
+}
+
+// New returns a Resolver that consults headers in priority order, only when
+// the peer is a trusted proxy. With no trusted proxies headers are never
+// honored, so clients can't pick their own address. Empty headers selects
+// DefaultHeaders.
+func New(headers []string, trusted []netip.Prefix) *Resolver {
+	if len(headers) == 0 {
+		headers = DefaultHeaders
+	}
+	return &Resolver{
+		headers: headers,
+		trusted: trusted,
+	}
+}
+
+// ClientIP returns the caller's IP from the first header that yields a
+// parseable address, falling back to RemoteAddr. The result is in canonical
+// form. Comma-separated chains are walked from the right so a client can't
+// spoof its address by prepending entries.
+func (c *Resolver) ClientIP(r *http.Request) string {
+	ip, _ := c.Resolve(r)
+	return ip
+}
+
+// Resolve is ClientIP that also reports where the address came from: the
+// name of the header that won, or SourceRemoteAddr.
+func (c *Resolver) Resolve(r *http.Request) (ip, source string) {
+	// Fall back to RemoteAddr
+	remote := remoteIP(r.RemoteAddr)
+
+	if !c.honorsHeaders(remote) {
+		return remote, SourceRemoteAddr
+	}
+
+	for _, name := range c.headers {
+		if ip := c.headerIP(r.Header.Values(name)); ip != "" {
+			return ip, name
+		}
+	}
+
+	return remote, SourceRemoteAddr
+}
+
+// Headers returns the headers consulted, in priority order.
+func (c *Resolver) Headers() []string {
+	return c.headers
+}
+
+// HonorsHeaders reports whether headers are consulted for r, which they are
+// only when the peer is a trusted proxy.
+func (c *Resolver) HonorsHeaders(r *http.Request) bool {
+	return c.honorsHeaders(remoteIP(r.RemoteAddr))
+}
+
+func (c *Resolver) honorsHeaders(remote string) bool {
+	return c.isTrusted(remote)
+}
+
+// remoteIP extracts the host from a RemoteAddr, which is normally host:port
+// but may lack the port, with or without brackets around an IPv6 host.
+// Parseable addresses are canonicalized.
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(remoteAddr, "["), "]")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.String()
+	}
+	return host
+}
+
+// headerIP picks the client entry from an X-Forwarded-For style header.
+// Single-value headers are just a chain of length one. Returns "" when the
+// header is absent or the chosen entry isn't an IP, so the caller moves on to
+// the next source.
+func (c *Resolver) headerIP(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	// Proxies may append separate header lines, so consider all of them
+	hops := strings.Split(strings.Join(values, ","), ",")
+	var candidate string
+	for i := len(hops) - 1; i >= 0; i-- {
+		candidate = strings.TrimSpace(hops[i])
+		if !c.isTrusted(candidate) {
+			break
+		}
+	}
+
+	addr, err := netip.ParseAddr(candidate)
+	if err != nil {
+		return ""
+	}
+	return addr.String()
+}
+
+func (c *Resolver) isTrusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
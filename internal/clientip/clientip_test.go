@@ -0,0 +1,384 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// trustAll trusts every peer, so header parsing can be tested on its own.
+var trustAll = []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		xff        string
+		xRealIP    string
+		remoteAddr string
+		want       string
+	}{
+		{
+			name: "X-Forwarded-For single",
+			xff:  "203.0.113.1",
+			want: "203.0.113.1",
+		},
+		{
+			name: "X-Forwarded-For multiple",
+			xff:  "203.0.113.1, 198.51.100.1, 192.0.2.1",
+			want: "203.0.113.1",
+		},
+		{
+			name: "X-Forwarded-For with spaces",
+			xff:  "  203.0.113.1  ",
+			want: "203.0.113.1",
+		},
+		{
+			name:    "X-Real-IP",
+			xRealIP: "203.0.113.50",
+			want:    "203.0.113.50",
+		},
+		{
+			name:       "RemoteAddr with port",
+			remoteAddr: "203.0.113.100:12345",
+			want:       "203.0.113.100",
+		},
+		{
+			name:       "RemoteAddr without port",
+			remoteAddr: "203.0.113.100",
+			want:       "203.0.113.100",
+		},
+		{
+			name:       "X-Forwarded-For takes precedence",
+			xff:        "203.0.113.1",
+			xRealIP:    "203.0.113.50",
+			remoteAddr: "203.0.113.100:12345",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "X-Real-IP takes precedence over RemoteAddr",
+			xRealIP:    "203.0.113.50",
+			remoteAddr: "203.0.113.100:12345",
+			want:       "203.0.113.50",
+		},
+		{
+			name:       "malformed X-Forwarded-For falls through to X-Real-IP",
+			xff:        "garbage, 203.0.113.1",
+			xRealIP:    "203.0.113.50",
+			remoteAddr: "203.0.113.100:12345",
+			want:       "203.0.113.50",
+		},
+		{
+			name:       "malformed headers fall through to RemoteAddr",
+			xff:        "unknown",
+			xRealIP:    "not-an-ip",
+			remoteAddr: "203.0.113.100:12345",
+			want:       "203.0.113.100",
+		},
+		{
+			name: "X-Forwarded-For with port is rejected",
+			xff:  "203.0.113.1:8080",
+			want: "192.0.2.1",
+		},
+		{
+			name: "IPv6 is canonicalized",
+			xff:  "2001:DB8:0:0::0001",
+			want: "2001:db8::1",
+		},
+		{
+			name:    "X-Real-IP IPv4 is canonicalized",
+			xRealIP: " 203.0.113.50 ",
+			want:    "203.0.113.50",
+		},
+		{
+			name:       "RemoteAddr IPv6 is canonicalized",
+			remoteAddr: "[2001:DB8::0001]:12345",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "RemoteAddr bracketed IPv6 with port",
+			remoteAddr: "[2001:db8::1]:443",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "RemoteAddr bracketed IPv6 without port",
+			remoteAddr: "[2001:db8::1]",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "RemoteAddr bare IPv6",
+			remoteAddr: "2001:db8::1",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "RemoteAddr that isn't an IP is passed through",
+			remoteAddr: "@",
+			want:       "@",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+
+			got := New(nil, trustAll).ClientIP(req)
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIP_NoTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "198.51.100.7:12345"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	req.Header.Set("X-Real-IP", "203.0.113.50")
+
+	// Without trusted proxies, a spoofed header from any peer is ignored
+	c := New(nil, nil)
+	if got, source := c.Resolve(req); got != "198.51.100.7" || source != SourceRemoteAddr {
+		t.Errorf("Resolve() = %q, %q, want the peer address", got, source)
+	}
+	if c.HonorsHeaders(req) {
+		t.Error("expected headers not to be honored without trusted proxies")
+	}
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+
+	tests := []struct {
+		name       string
+		xff        []string
+		xRealIP    string
+		remoteAddr string
+		want       string
+	}{
+		{
+			name:       "untrusted peer ignores X-Forwarded-For",
+			xff:        []string{"203.0.113.1"},
+			remoteAddr: "198.51.100.7:12345",
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "untrusted peer ignores X-Real-IP",
+			xRealIP:    "203.0.113.50",
+			remoteAddr: "198.51.100.7:12345",
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "trusted peer honors X-Forwarded-For",
+			xff:        []string{"203.0.113.1"},
+			remoteAddr: "10.0.0.1:12345",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "spoofed leftmost entry is skipped",
+			xff:        []string{"1.2.3.4, 203.0.113.1"},
+			remoteAddr: "10.0.0.1:12345",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "trusted hops are skipped from the right",
+			xff:        []string{"1.2.3.4, 203.0.113.1, 10.0.0.2, 10.0.0.3"},
+			remoteAddr: "10.0.0.1:12345",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "multiple header lines are joined",
+			xff:        []string{"203.0.113.1", "10.0.0.2"},
+			remoteAddr: "10.0.0.1:12345",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "all hops trusted returns leftmost",
+			xff:        []string{"10.0.0.3, 10.0.0.2"},
+			remoteAddr: "10.0.0.1:12345",
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "trusted peer honors X-Real-IP",
+			xRealIP:    "203.0.113.50",
+			remoteAddr: "10.0.0.1:12345",
+			want:       "203.0.113.50",
+		},
+		{
+			name:       "trusted IPv6 peer",
+			xff:        []string{"203.0.113.1"},
+			remoteAddr: "[2001:db8::1]:12345",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.0.0.1:12345",
+			want:       "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+			req.RemoteAddr = tt.remoteAddr
+
+			got := New(nil, trusted).ClientIP(req)
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIP_CustomHeaders(t *testing.T) {
+	headers := []string{"CF-Connecting-IP", "True-Client-IP", "X-Forwarded-For"}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name: "first configured header wins",
+			headers: map[string]string{
+				"CF-Connecting-IP": "203.0.113.1",
+				"True-Client-IP":   "203.0.113.2",
+				"X-Forwarded-For":  "203.0.113.3",
+			},
+			want: "203.0.113.1",
+		},
+		{
+			name: "later header used when earlier is missing",
+			headers: map[string]string{
+				"True-Client-IP":  "203.0.113.2",
+				"X-Forwarded-For": "203.0.113.3",
+			},
+			want: "203.0.113.2",
+		},
+		{
+			name: "unparseable header is skipped",
+			headers: map[string]string{
+				"CF-Connecting-IP": "not-an-ip",
+				"X-Forwarded-For":  "203.0.113.3, 198.51.100.1",
+			},
+			want: "203.0.113.3",
+		},
+		{
+			name: "unlisted headers are ignored",
+			headers: map[string]string{
+				"X-Real-IP": "203.0.113.50",
+			},
+			want: "192.0.2.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			got := New(headers, trustAll).ClientIP(req)
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve_Source(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xRealIP    string
+		want       string
+		wantSource string
+	}{
+		{"header from trusted proxy", "10.0.0.1:1234", "203.0.113.1", "203.0.113.1", "X-Real-IP"},
+		{"header from untrusted peer", "192.0.2.1:1234", "203.0.113.1", "192.0.2.1", SourceRemoteAddr},
+		{"no headers", "10.0.0.1:1234", "", "10.0.0.1", SourceRemoteAddr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+
+			got, source := New(nil, trusted).Resolve(req)
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("Resolve() = %q, %q, want %q, %q", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.77", "203.0.113.0/24"},
+		{"::ffff:203.0.113.77", "203.0.113.0/24"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234::/48"},
+		{"fe80::1%eth0", "fe80::/48"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.ip); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+
+	// Non-addresses are hashed, consistently and without leaking the input
+	got := Redact("not an ip")
+	if !strings.HasPrefix(got, "sha256:") || strings.Contains(got, "not") || got != Redact("not an ip") {
+		t.Errorf("Redact() of a non-address = %q", got)
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.77", "203.0.113.0"},
+		{"203.0.113.0", "203.0.113.0"},
+		{"::ffff:203.0.113.77", "203.0.113.0"},
+		{"2001:db8:abcd:1234:5678::1", "2001:db8:abcd::"},
+		{"fe80::1%eth0", "fe80::"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := Anonymize(netip.MustParseAddr(tt.ip)); got.String() != tt.want {
+				t.Errorf("Anonymize(%s) = %s, want %s", tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	if got := Anonymize(netip.Addr{}); got.IsValid() {
+		t.Errorf("expected the zero Addr to stay invalid, got %s", got)
+	}
+}
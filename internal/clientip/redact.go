@@ -0,0 +1,51 @@
+package clientip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+)
+
+// Prefix lengths kept by Redact and Anonymize: enough to tell networks
+// apart, and for country-level lookups, but not to name a host.
+const (
+	redactBitsIPv4 = 24
+	redactBitsIPv6 = 48
+)
+
+// Redact returns a form of ip that is safe to log: the /24 (IPv4) or /48
+// (IPv6) network it belongs to. Anything that isn't an address, which could
+// hold whatever a client sent, is replaced by a short hash so repeats can
+// still be matched up. Empty stays empty.
+func Redact(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		sum := sha256.Sum256([]byte(ip))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return network(addr).String()
+}
+
+// Anonymize zeroes the last octet of an IPv4 address, or the last 80 bits of
+// an IPv6 one, leaving the network Redact would log. IPv4-mapped addresses
+// are unmapped first and zones dropped. The zero Addr is returned as is.
+func Anonymize(addr netip.Addr) netip.Addr {
+	if !addr.IsValid() {
+		return addr
+	}
+	return network(addr).Addr()
+}
+
+// network returns the /24 or /48 addr belongs to.
+func network(addr netip.Addr) netip.Prefix {
+	addr = addr.Unmap().WithZone("")
+	bits := redactBitsIPv6
+	if addr.Is4() {
+		bits = redactBitsIPv4
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix
+}
@@ -1,8 +1,17 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -14,41 +23,371 @@ const (
 	DefaultCityDBIPv4URL       = "https://cdn.jsdelivr.net/npm/@ip-location-db/geolite2-city-mmdb/geolite2-city-ipv4.mmdb"
 	DefaultCityDBIPv6Path      = "/data/city-ipv6.mmdb"
 	DefaultCityDBIPv6URL       = "https://cdn.jsdelivr.net/npm/@ip-location-db/geolite2-city-mmdb/geolite2-city-ipv6.mmdb"
+	DefaultASNDBURL            = "https://cdn.jsdelivr.net/npm/@ip-location-db/asn-mmdb/asn.mmdb"
 	DefaultUpdateIntervalHours = 24
+	DefaultDownloadMaxRetries  = 3
+	DefaultDownloadRetryDelay  = time.Second
+	DefaultDownloadTimeout     = 5 * time.Minute
+	DefaultMaxBatchSize        = 100
+	DefaultMaxBulkLines        = 100000
+	DefaultLookupCacheSize     = 10000
+	DefaultClientIPHeaders     = "X-Forwarded-For,X-Real-IP"
+	DefaultAuthScheme          = "apikey"
+	DefaultHTTPReadTimeout     = 5 * time.Second
+	DefaultHTTPWriteTimeout    = 10 * time.Second
+	DefaultHTTPIdleTimeout     = 120 * time.Second
+	DefaultShutdownTimeout     = 30 * time.Second
+	DefaultLogLevel            = "info"
+	DefaultLookupMode          = "country"
+	DefaultJSONFieldCase       = "snake"
+	DefaultDBFormat            = "mmdb"
 )
 
+// logLevels are the LOG_LEVEL names the logger accepts, in any case.
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// Config holds the server settings. Field tags name the keys accepted in a
+// CONFIG_FILE; JSON files use the same keys.
 type Config struct {
-	Host                string
-	Port                string
-	CountryDBPath    string
-	CountryDBURL     string
-	CityDBIPv4Path   string
-	CityDBIPv4URL    string
-	CityDBIPv6Path   string
-	CityDBIPv6URL    string
-	UpdateIntervalHours int
-	APIKey              string
-}
-
-func Load() *Config {
+	Host                 string              `yaml:"host"`
+	Port                 string              `yaml:"port"`
+	ListenSocket         string              `yaml:"listen_socket"`
+	GRPCPort             string              `yaml:"grpc_port"`
+	AdminAddr            string              `yaml:"admin_addr"`
+	TLSCertFile          string              `yaml:"tls_cert_file"`
+	TLSKeyFile           string              `yaml:"tls_key_file"`
+	EnableH2C            bool                `yaml:"enable_h2c"`
+	HTTPReadTimeout      time.Duration       `yaml:"http_read_timeout"`
+	HTTPWriteTimeout     time.Duration       `yaml:"http_write_timeout"`
+	HTTPIdleTimeout      time.Duration       `yaml:"http_idle_timeout"`
+	ShutdownTimeout      time.Duration       `yaml:"shutdown_timeout"`
+	LogLevel             string              `yaml:"log_level"`
+	LogRedactIP          bool                `yaml:"log_redact_ip"`
+	OTelEnabled          bool                `yaml:"otel_enabled"`
+	PprofEnabled         bool                `yaml:"pprof_enabled"`
+	DBFormat             string              `yaml:"db_format"`
+	CountryDBPath        string              `yaml:"country_db_path"`
+	CountryDBURL         string              `yaml:"country_db_url"`
+	CityDBIPv4Path       string              `yaml:"city_db_ipv4_path"`
+	CityDBIPv4URL        string              `yaml:"city_db_ipv4_url"`
+	CityDBIPv6Path       string              `yaml:"city_db_ipv6_path"`
+	CityDBIPv6URL        string              `yaml:"city_db_ipv6_url"`
+	ASNDBPath            string              `yaml:"asn_db_path"`
+	ASNDBURL             string              `yaml:"asn_db_url"`
+	CountryDBSHA256URL   string              `yaml:"country_db_sha256_url"`
+	CityDBIPv4SHA256URL  string              `yaml:"city_db_ipv4_sha256_url"`
+	CityDBIPv6SHA256URL  string              `yaml:"city_db_ipv6_sha256_url"`
+	ASNDBSHA256URL       string              `yaml:"asn_db_sha256_url"`
+	UpdateIntervalHours  int                 `yaml:"update_interval_hours"`
+	DBOffline            bool                `yaml:"db_offline"`
+	DownloadMaxRetries   int                 `yaml:"db_download_max_retries"`
+	DownloadRetryDelay   time.Duration       `yaml:"db_download_retry_delay"`
+	DownloadTimeout      time.Duration       `yaml:"db_download_timeout"`
+	DBMaxAge             time.Duration       `yaml:"db_max_age"`
+	SelfTestProbes       []string            `yaml:"self_test_probes"`
+	APIKeys              map[string]string   `yaml:"api_keys"`
+	APIKeyScopes         map[string][]string `yaml:"api_key_scopes"`
+	AuthScheme           string              `yaml:"auth_scheme"`
+	MaxBatchSize         int                 `yaml:"max_batch_size"`
+	MaxBulkLines         int                 `yaml:"max_bulk_lines"`
+	DefaultLookupMode    string              `yaml:"default_lookup_mode"`
+	SelfLookupMode       string              `yaml:"self_lookup_mode"`
+	IPLookupMode         string              `yaml:"ip_lookup_mode"`
+	StrictLookup         bool                `yaml:"strict_lookup"`
+	NotFoundAsOK         bool                `yaml:"not_found_as_ok"`
+	JSONFieldCase        string              `yaml:"json_field_case"`
+	HealthCheckDB        bool                `yaml:"health_check_db"`
+	TrustedProxies       []netip.Prefix      `yaml:"trusted_proxies"`
+	ClientIPHeaders      []string            `yaml:"client_ip_headers"`
+	AllowIPOverride      bool                `yaml:"allow_ip_override"`
+	AnonymizeIPs         bool                `yaml:"anonymize_ips"`
+	CORSAllowedOrigins   []string            `yaml:"cors_allowed_origins"`
+	RateLimitRPS         float64             `yaml:"rate_limit_rps"`
+	RateLimitBurst       int                 `yaml:"rate_limit_burst"`
+	MaxConcurrentLookups int                 `yaml:"max_concurrent_lookups"`
+	LookupCacheSize      int                 `yaml:"lookup_cache_size"`
+	CacheWarmupFile      string              `yaml:"cache_warmup_file"`
+	DetectPrivateIPs     bool                `yaml:"detect_private_ips"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() *Config {
 	return &Config{
-		Host:                getEnv("HOST", DefaultHost),
-		Port:                getEnv("PORT", DefaultPort),
-		CountryDBPath:    getEnv("COUNTRY_DB_PATH", DefaultCountryDBPath),
-		CountryDBURL:     getEnv("COUNTRY_DB_URL", DefaultCountryDBURL),
-		CityDBIPv4Path:   getEnv("CITY_DB_IPV4_PATH", DefaultCityDBIPv4Path),
-		CityDBIPv4URL:    getEnv("CITY_DB_IPV4_URL", DefaultCityDBIPv4URL),
-		CityDBIPv6Path:   getEnv("CITY_DB_IPV6_PATH", DefaultCityDBIPv6Path),
-		CityDBIPv6URL:    getEnv("CITY_DB_IPV6_URL", DefaultCityDBIPv6URL),
-		UpdateIntervalHours: getEnvInt("UPDATE_INTERVAL_HOURS", DefaultUpdateIntervalHours),
-		APIKey:              os.Getenv("API_KEY"),
+		Host:                DefaultHost,
+		Port:                DefaultPort,
+		HTTPReadTimeout:     DefaultHTTPReadTimeout,
+		HTTPWriteTimeout:    DefaultHTTPWriteTimeout,
+		HTTPIdleTimeout:     DefaultHTTPIdleTimeout,
+		ShutdownTimeout:     DefaultShutdownTimeout,
+		LogLevel:            DefaultLogLevel,
+		DBFormat:            DefaultDBFormat,
+		CountryDBPath:       DefaultCountryDBPath,
+		CountryDBURL:        DefaultCountryDBURL,
+		CityDBIPv4Path:      DefaultCityDBIPv4Path,
+		CityDBIPv4URL:       DefaultCityDBIPv4URL,
+		CityDBIPv6Path:      DefaultCityDBIPv6Path,
+		CityDBIPv6URL:       DefaultCityDBIPv6URL,
+		ASNDBURL:            DefaultASNDBURL,
+		UpdateIntervalHours: DefaultUpdateIntervalHours,
+		DownloadMaxRetries:  DefaultDownloadMaxRetries,
+		DownloadRetryDelay:  DefaultDownloadRetryDelay,
+		DownloadTimeout:     DefaultDownloadTimeout,
+		APIKeys:             make(map[string]string),
+		APIKeyScopes:        make(map[string][]string),
+		AuthScheme:          DefaultAuthScheme,
+		MaxBatchSize:        DefaultMaxBatchSize,
+		MaxBulkLines:        DefaultMaxBulkLines,
+		DefaultLookupMode:   DefaultLookupMode,
+		JSONFieldCase:       DefaultJSONFieldCase,
+		ClientIPHeaders:     strings.Split(DefaultClientIPHeaders, ","),
+		LookupCacheSize:     DefaultLookupCacheSize,
+	}
+}
+
+// Load builds the configuration from the defaults, then the file named by
+// CONFIG_FILE (if any), then environment variables, each overriding the last.
+// An unreadable or malformed config file is an error.
+func Load() (*Config, error) {
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides c with every environment variable that is set.
+func (c *Config) applyEnv() error {
+	c.Host = getEnv("HOST", c.Host)
+	c.Port = getEnv("PORT", c.Port)
+	c.ListenSocket = getEnv("LISTEN_SOCKET", c.ListenSocket)
+	c.GRPCPort = getEnv("GRPC_PORT", c.GRPCPort)
+	c.AdminAddr = getEnv("ADMIN_ADDR", c.AdminAddr)
+	c.TLSCertFile = getEnv("TLS_CERT_FILE", c.TLSCertFile)
+	c.TLSKeyFile = getEnv("TLS_KEY_FILE", c.TLSKeyFile)
+	c.EnableH2C = getEnvBool("ENABLE_H2C", c.EnableH2C)
+	c.HTTPReadTimeout = getEnvDuration("HTTP_READ_TIMEOUT", c.HTTPReadTimeout)
+	c.HTTPWriteTimeout = getEnvDuration("HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout)
+	c.HTTPIdleTimeout = getEnvDuration("HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout)
+	c.ShutdownTimeout = getEnvDuration("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	c.LogLevel = getEnv("LOG_LEVEL", c.LogLevel)
+	c.LogRedactIP = getEnvBool("LOG_REDACT_IP", c.LogRedactIP)
+	c.OTelEnabled = getEnvBool("OTEL_ENABLED", c.OTelEnabled)
+	c.PprofEnabled = getEnvBool("PPROF_ENABLED", c.PprofEnabled)
+	c.DBFormat = getEnv("DB_FORMAT", c.DBFormat)
+	c.CountryDBPath = getEnv("COUNTRY_DB_PATH", c.CountryDBPath)
+	c.CountryDBURL = getEnvAllowEmpty("COUNTRY_DB_URL", c.CountryDBURL)
+	c.CityDBIPv4Path = getEnv("CITY_DB_IPV4_PATH", c.CityDBIPv4Path)
+	c.CityDBIPv4URL = getEnvAllowEmpty("CITY_DB_IPV4_URL", c.CityDBIPv4URL)
+	c.CityDBIPv6Path = getEnv("CITY_DB_IPV6_PATH", c.CityDBIPv6Path)
+	c.CityDBIPv6URL = getEnvAllowEmpty("CITY_DB_IPV6_URL", c.CityDBIPv6URL)
+	c.ASNDBPath = getEnv("ASN_DB_PATH", c.ASNDBPath)
+	c.ASNDBURL = getEnvAllowEmpty("ASN_DB_URL", c.ASNDBURL)
+	c.CountryDBSHA256URL = getEnv("COUNTRY_DB_SHA256_URL", c.CountryDBSHA256URL)
+	c.CityDBIPv4SHA256URL = getEnv("CITY_DB_IPV4_SHA256_URL", c.CityDBIPv4SHA256URL)
+	c.CityDBIPv6SHA256URL = getEnv("CITY_DB_IPV6_SHA256_URL", c.CityDBIPv6SHA256URL)
+	c.ASNDBSHA256URL = getEnv("ASN_DB_SHA256_URL", c.ASNDBSHA256URL)
+	c.DBOffline = getEnvBool("DB_OFFLINE", c.DBOffline) || !getEnvBool("DB_AUTO_UPDATE", true)
+	c.UpdateIntervalHours = getEnvInt("UPDATE_INTERVAL_HOURS", c.UpdateIntervalHours)
+	c.DownloadMaxRetries = getEnvInt("DB_DOWNLOAD_MAX_RETRIES", c.DownloadMaxRetries)
+	c.DownloadRetryDelay = getEnvDuration("DB_DOWNLOAD_RETRY_DELAY", c.DownloadRetryDelay)
+	c.DownloadTimeout = getEnvDuration("DB_DOWNLOAD_TIMEOUT", c.DownloadTimeout)
+	c.DBMaxAge = getEnvDuration("DB_MAX_AGE", c.DBMaxAge)
+	// Set but empty disables a self-test from the config file
+	if value, ok := os.LookupEnv("SELF_TEST_PROBES"); ok {
+		c.SelfTestProbes = splitList(value)
+	}
+	if err := addAPIKeys(c.APIKeys); err != nil {
+		return err
+	}
+	if err := addAPIKeyScopes(c.APIKeyScopes); err != nil {
+		return err
+	}
+	c.AuthScheme = getEnv("AUTH_SCHEME", c.AuthScheme)
+	c.MaxBatchSize = getEnvInt("MAX_BATCH_SIZE", c.MaxBatchSize)
+	c.MaxBulkLines = getEnvInt("MAX_BULK_LINES", c.MaxBulkLines)
+	c.DefaultLookupMode = getEnv("DEFAULT_LOOKUP_MODE", c.DefaultLookupMode)
+	c.SelfLookupMode = getEnv("SELF_LOOKUP_MODE", c.SelfLookupMode)
+	c.IPLookupMode = getEnv("IP_LOOKUP_MODE", c.IPLookupMode)
+	c.StrictLookup = getEnvBool("STRICT_LOOKUP", c.StrictLookup)
+	c.NotFoundAsOK = getEnvBool("NOT_FOUND_AS_OK", c.NotFoundAsOK)
+	c.JSONFieldCase = getEnv("JSON_FIELD_CASE", c.JSONFieldCase)
+	c.HealthCheckDB = getEnvBool("HEALTH_CHECK_DB", c.HealthCheckDB)
+	c.TrustedProxies = getEnvPrefixes("TRUSTED_PROXIES", c.TrustedProxies)
+	c.ClientIPHeaders = getEnvList("CLIENT_IP_HEADERS", c.ClientIPHeaders)
+	c.AllowIPOverride = getEnvBool("ALLOW_IP_OVERRIDE", c.AllowIPOverride)
+	c.AnonymizeIPs = getEnvBool("ANONYMIZE_IPS", c.AnonymizeIPs)
+	c.CORSAllowedOrigins = getEnvList("CORS_ALLOWED_ORIGINS", c.CORSAllowedOrigins)
+	c.RateLimitRPS = getEnvFloat("RATE_LIMIT_RPS", c.RateLimitRPS)
+	c.RateLimitBurst = getEnvInt("RATE_LIMIT_BURST", c.RateLimitBurst)
+	c.MaxConcurrentLookups = getEnvInt("MAX_CONCURRENT_LOOKUPS", c.MaxConcurrentLookups)
+	c.LookupCacheSize = getEnvInt("LOOKUP_CACHE_SIZE", c.LookupCacheSize)
+	c.CacheWarmupFile = getEnv("CACHE_WARMUP_FILE", c.CacheWarmupFile)
+	c.DetectPrivateIPs = getEnvBool("DETECT_PRIVATE_IPS", c.DetectPrivateIPs)
+	return nil
+}
+
+// Validate checks the configuration for values the server can't run with.
+// Every problem found is reported in the returned error, not just the first.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a number between 1 and 65535, got %q", c.Port))
+	}
+	if c.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("GRPC_PORT must be a number between 1 and 65535, got %q", c.GRPCPort))
+		} else if c.GRPCPort == c.Port && c.ListenSocket == "" {
+			errs = append(errs, errors.New("GRPC_PORT must differ from PORT"))
+		}
+	}
+	if c.AdminAddr != "" {
+		_, port, err := net.SplitHostPort(c.AdminAddr)
+		if n, perr := strconv.Atoi(port); err != nil || perr != nil || n < 1 || n > 65535 {
+			errs = append(errs, fmt.Errorf("ADMIN_ADDR must be host:port, got %q", c.AdminAddr))
+		} else if port == c.GRPCPort || (port == c.Port && c.ListenSocket == "") {
+			// gRPC listens on TCP even when HTTP uses LISTEN_SOCKET
+			errs = append(errs, errors.New("ADMIN_ADDR must use a port other than PORT and GRPC_PORT"))
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTPReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"DB_MAX_AGE", c.DBMaxAge},
+	}
+	for _, t := range durations {
+		if t.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %s", t.name, t.value))
+		}
+	}
+	for _, probe := range c.SelfTestProbes {
+		if _, _, err := ParseProbe(probe); err != nil {
+			errs = append(errs, fmt.Errorf("SELF_TEST_PROBES: %w", err))
+		}
+	}
+	if !slices.ContainsFunc(logLevels, func(lv string) bool { return strings.EqualFold(lv, c.LogLevel) }) {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel))
+	}
+	if c.DefaultLookupMode != "country" && c.DefaultLookupMode != "city" {
+		errs = append(errs, fmt.Errorf("DEFAULT_LOOKUP_MODE must be country or city, got %q", c.DefaultLookupMode))
 	}
+	// The per-route modes may be left empty to follow DEFAULT_LOOKUP_MODE
+	routeModes := []struct{ name, mode string }{
+		{"SELF_LOOKUP_MODE", c.SelfLookupMode},
+		{"IP_LOOKUP_MODE", c.IPLookupMode},
+	}
+	for _, r := range routeModes {
+		if r.mode != "" && r.mode != "country" && r.mode != "city" {
+			errs = append(errs, fmt.Errorf("%s must be country or city, got %q", r.name, r.mode))
+		}
+	}
+	if c.JSONFieldCase != "snake" && c.JSONFieldCase != "camel" {
+		errs = append(errs, fmt.Errorf("JSON_FIELD_CASE must be snake or camel, got %q", c.JSONFieldCase))
+	}
+	// A request is matched to a key by value, so a shared value would make
+	// the key name, and with it the scope, depend on map order
+	keyNames := make(map[string]string, len(c.APIKeys))
+	for _, name := range slices.Sorted(maps.Keys(c.APIKeys)) {
+		if other, ok := keyNames[c.APIKeys[name]]; ok {
+			errs = append(errs, fmt.Errorf("API keys %q and %q must not share a value", other, name))
+			continue
+		}
+		keyNames[c.APIKeys[name]] = name
+	}
+	for name, fields := range c.APIKeyScopes {
+		if _, ok := c.APIKeys[name]; !ok {
+			errs = append(errs, fmt.Errorf("API_KEY_SCOPES names unknown API key %q", name))
+		}
+		for _, field := range fields {
+			if !slices.Contains(ScopeFields, field) {
+				errs = append(errs, fmt.Errorf("API_KEY_SCOPES for %q has unknown field %q", name, field))
+			}
+		}
+	}
+	// Profiles expose internals, so they're never served unauthenticated
+	if c.PprofEnabled && len(c.APIKeys) == 0 {
+		errs = append(errs, errors.New("PPROF_ENABLED requires an API key"))
+	}
+	if c.DBFormat != "mmdb" && c.DBFormat != "ip2location" {
+		errs = append(errs, fmt.Errorf("DB_FORMAT must be mmdb or ip2location, got %q", c.DBFormat))
+	}
+	if c.UpdateIntervalHours <= 0 {
+		errs = append(errs, fmt.Errorf("UPDATE_INTERVAL_HOURS must be positive, got %d", c.UpdateIntervalHours))
+	}
+
+	paths := []struct{ name, value string }{
+		{"COUNTRY_DB_PATH", c.CountryDBPath},
+		{"CITY_DB_IPV4_PATH", c.CityDBIPv4Path},
+		{"CITY_DB_IPV6_PATH", c.CityDBIPv6Path},
+	}
+	for _, p := range paths {
+		if p.value == "" {
+			errs = append(errs, fmt.Errorf("%s must not be empty", p.name))
+		}
+	}
+
+	// URLs are only used when downloads are enabled. An empty database URL
+	// means the file is managed externally and only loaded from disk.
+	if !c.DBOffline {
+		urls := []struct{ name, value string }{
+			{"COUNTRY_DB_URL", c.CountryDBURL},
+			{"CITY_DB_IPV4_URL", c.CityDBIPv4URL},
+			{"CITY_DB_IPV6_URL", c.CityDBIPv6URL},
+			{"ASN_DB_URL", c.ASNDBURL},
+			{"COUNTRY_DB_SHA256_URL", c.CountryDBSHA256URL},
+			{"CITY_DB_IPV4_SHA256_URL", c.CityDBIPv4SHA256URL},
+			{"CITY_DB_IPV6_SHA256_URL", c.CityDBIPv6SHA256URL},
+			{"ASN_DB_SHA256_URL", c.ASNDBSHA256URL},
+		}
+		for _, u := range urls {
+			if u.value == "" {
+				continue
+			}
+			if !validURL(u.value) {
+				errs = append(errs, fmt.Errorf("%s must be an absolute http(s) URL, got %q", u.name, u.value))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validURL reports whether s is an absolute http or https URL.
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// TLSEnabled reports whether the server should terminate TLS itself.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
 }
 
 func (c *Config) Addr() string {
 	return c.Host + ":" + c.Port
 }
 
+// GRPCAddr is the gRPC listen address, empty when gRPC is disabled.
+func (c *Config) GRPCAddr() string {
+	if c.GRPCPort == "" {
+		return ""
+	}
+	return c.Host + ":" + c.GRPCPort
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -56,6 +395,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAllowEmpty is getEnv for settings where an empty value means
+// something: a variable that is set, even to "", overrides the default.
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intVal, err := strconv.Atoi(value); err == nil {
@@ -64,3 +412,158 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// DefaultAPIKeyName names the key set via the legacy API_KEY variable.
+const DefaultAPIKeyName = "default"
+
+// addAPIKeys adds keys from API_KEYS, comma-separated name:key pairs, and
+// the legacy API_KEY, which is named "default". API_KEY_FILE takes precedence
+// over API_KEY so the key can come from a mounted secret. A malformed API_KEYS
+// entry is an error rather than skipped, so a typo can't quietly drop a key.
+func addAPIKeys(keys map[string]string) error {
+	if path := os.Getenv("API_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read API_KEY_FILE: %w", err)
+		}
+		// Secret files usually end with a newline that isn't part of the key
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return fmt.Errorf("API_KEY_FILE %s is empty", path)
+		}
+		keys[DefaultAPIKeyName] = key
+	} else if key := os.Getenv("API_KEY"); key != "" {
+		keys[DefaultAPIKeyName] = key
+	}
+	for i, entry := range getEnvList("API_KEYS", nil) {
+		name, key, ok := strings.Cut(entry, ":")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			// By position, since the entry may hold the key itself
+			return fmt.Errorf("API_KEYS entry %d must be name:key", i+1)
+		}
+		keys[name] = key
+	}
+	return nil
+}
+
+// ScopeFields are the lookup response fields, by JSON name, that an API key
+// scope can allow.
+var ScopeFields = []string{
+	"country_code", "country_name", "continent_code", "flag", "is_in_eu",
+	"postal_code", "region", "city", "latitude", "longitude",
+	"accuracy_radius", "asn", "as_org", "hostname", "timezone",
+	"ip_version", "network",
+}
+
+// addAPIKeyScopes adds scopes from API_KEY_SCOPES, comma-separated
+// name:field|field entries naming an API key and the response fields it may
+// see. A malformed entry is an error rather than skipped, since dropping it
+// would leave the key unscoped.
+func addAPIKeyScopes(scopes map[string][]string) error {
+	for _, entry := range getEnvList("API_KEY_SCOPES", nil) {
+		name, list, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		var fields []string
+		for _, field := range strings.Split(list, "|") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		if !ok || name == "" || len(fields) == 0 {
+			return fmt.Errorf("API_KEY_SCOPES entry %q must be name:field|field", entry)
+		}
+		scopes[name] = fields
+	}
+	return nil
+}
+
+// getEnvList parses a comma-separated list, ignoring empty entries.
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return splitList(value)
+}
+
+// splitList splits a comma-separated list, ignoring empty entries.
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// ParseProbe parses a self-test probe, an IP optionally followed by "=" and
+// the two-letter country code it must resolve to.
+func ParseProbe(s string) (ip netip.Addr, country string, err error) {
+	addr, country, _ := strings.Cut(s, "=")
+	ip, err = netip.ParseAddr(strings.TrimSpace(addr))
+	if err != nil {
+		return netip.Addr{}, "", fmt.Errorf("invalid probe %q: %w", s, err)
+	}
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country != "" && len(country) != 2 {
+		return netip.Addr{}, "", fmt.Errorf("invalid probe %q: country must be a two-letter code", s)
+	}
+	return ip, country, nil
+}
+
+// getEnvPrefixes parses a comma-separated list of CIDRs. Bare IPs are treated
+// as single-address prefixes and invalid entries are skipped.
+func getEnvPrefixes(key string, defaultValue []netip.Prefix) []netip.Prefix {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		if prefix, ok := parsePrefix(entry); ok {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// parsePrefix accepts a CIDR or a bare IP, which becomes a single-address prefix.
+func parsePrefix(s string) (netip.Prefix, bool) {
+	s = strings.TrimSpace(s)
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), true
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
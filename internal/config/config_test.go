@@ -0,0 +1,374 @@
+package config
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != DefaultPort || cfg.MaxBatchSize != DefaultMaxBatchSize || cfg.DetectPrivateIPs {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if !slices.Equal(cfg.ClientIPHeaders, []string{"X-Forwarded-For", "X-Real-IP"}) {
+		t.Errorf("unexpected default client IP headers: %v", cfg.ClientIPHeaders)
+	}
+	if cfg.JSONFieldCase != "snake" {
+		t.Errorf("expected snake_case JSON fields by default, got %q", cfg.JSONFieldCase)
+	}
+}
+
+func TestLoad_YAMLFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+port: "8080"
+max_batch_size: 50
+db_download_timeout: 90s
+trusted_proxies: [10.0.0.0/8]
+api_keys:
+  ci: secret
+`)
+	t.Setenv("CONFIG_FILE", path)
+	// Environment variables override the file
+	t.Setenv("MAX_BATCH_SIZE", "25")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != "8080" {
+		t.Errorf("expected port from file, got %q", cfg.Port)
+	}
+	if cfg.MaxBatchSize != 25 {
+		t.Errorf("expected MAX_BATCH_SIZE to override the file, got %d", cfg.MaxBatchSize)
+	}
+	if cfg.DownloadTimeout != 90*time.Second {
+		t.Errorf("expected download timeout 90s, got %v", cfg.DownloadTimeout)
+	}
+	if !slices.Equal(cfg.TrustedProxies, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}) {
+		t.Errorf("unexpected trusted proxies: %v", cfg.TrustedProxies)
+	}
+	if cfg.APIKeys["ci"] != "secret" {
+		t.Errorf("expected API key from file, got %v", cfg.APIKeys)
+	}
+	// Unset keys keep their defaults
+	if cfg.Host != DefaultHost {
+		t.Errorf("expected default host, got %q", cfg.Host)
+	}
+}
+
+func TestLoad_JSONFile(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"port": "9090", "db_download_retry_delay": "2s"}`)
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != "9090" || cfg.DownloadRetryDelay != 2*time.Second {
+		t.Errorf("unexpected config from JSON file: port %q, retry delay %v", cfg.Port, cfg.DownloadRetryDelay)
+	}
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"missing", "", "", "failed to read config file"},
+		{"malformed yaml", "config.yaml", "port: [", "invalid config file"},
+		{"malformed json", "config.json", `{"port": `, "invalid config file"},
+		{"unknown key", "config.yaml", "prot: 8080", "invalid config file"},
+		{"wrong type", "config.yaml", "max_batch_size: lots", "invalid config file"},
+		{"unsupported extension", "config.toml", "port = 1", "unsupported config file extension"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing.yaml")
+			if tt.file != "" {
+				path = writeConfigFile(t, tt.file, tt.content)
+			}
+			t.Setenv("CONFIG_FILE", path)
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Validate() on defaults error = %v", err)
+	}
+
+	cfg := Default()
+	cfg.Port = "http"
+	cfg.UpdateIntervalHours = -1
+	cfg.CityDBIPv4Path = ""
+	cfg.CountryDBURL = "not a url"
+	cfg.ASNDBPath = "/data/asn.mmdb"
+	cfg.ASNDBURL = "ftp://example.com/asn.mmdb"
+	cfg.TLSCertFile = "/etc/tls/cert.pem"
+	cfg.GRPCPort = "0"
+	cfg.DefaultLookupMode = "postal"
+	cfg.IPLookupMode = "postal"
+	cfg.JSONFieldCase = "kebab"
+	cfg.DBFormat = "csv"
+	cfg.LogLevel = "verbose"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() succeeded with an invalid config")
+	}
+	// Every problem is reported
+	for _, want := range []string{"PORT", "UPDATE_INTERVAL_HOURS", "CITY_DB_IPV4_PATH", "COUNTRY_DB_URL", "ASN_DB_URL", "TLS_KEY_FILE", "GRPC_PORT", "DEFAULT_LOOKUP_MODE", "IP_LOOKUP_MODE", "JSON_FIELD_CASE", "DB_FORMAT", "LOG_LEVEL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidate_OfflineSkipsURLs(t *testing.T) {
+	cfg := Default()
+	cfg.DBOffline = true
+	cfg.CountryDBURL = "not a url"
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want URLs ignored in offline mode", err)
+	}
+
+	cfg.Port = "70000"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected out-of-range port to be rejected")
+	}
+}
+
+func TestLoad_EmptyDBURL(t *testing.T) {
+	// An empty URL leaves the database to be managed externally
+	t.Setenv("CITY_DB_IPV4_URL", "")
+	t.Setenv("CITY_DB_IPV6_URL", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.CityDBIPv4URL != "" || cfg.CityDBIPv6URL != "" {
+		t.Errorf("expected empty city URLs, got %q and %q", cfg.CityDBIPv4URL, cfg.CityDBIPv6URL)
+	}
+	if cfg.CountryDBURL != DefaultCountryDBURL {
+		t.Errorf("expected the default country URL, got %q", cfg.CountryDBURL)
+	}
+}
+
+func TestLoad_APIKeyScopes(t *testing.T) {
+	t.Setenv("API_KEYS", "partner:key-one,reports:key-two")
+	t.Setenv("API_KEY_SCOPES", "partner:country_code|country_name, reports: city ")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string][]string{
+		"partner": {"country_code", "country_name"},
+		"reports": {"city"},
+	}
+	if len(cfg.APIKeyScopes) != len(want) {
+		t.Fatalf("APIKeyScopes = %v, want %v", cfg.APIKeyScopes, want)
+	}
+	for name, fields := range want {
+		if !slices.Equal(cfg.APIKeyScopes[name], fields) {
+			t.Errorf("APIKeyScopes[%q] = %v, want %v", name, cfg.APIKeyScopes[name], fields)
+		}
+	}
+
+	cfg.APIKeyScopes["unknown"] = []string{"country_code"}
+	cfg.APIKeyScopes["partner"] = []string{"country"}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown API key "unknown"`) || !strings.Contains(err.Error(), `unknown field "country"`) {
+		t.Errorf("Validate() error = %v, want unknown key and field errors", err)
+	}
+}
+
+func TestValidate_DuplicateAPIKeys(t *testing.T) {
+	cfg := Default()
+	cfg.APIKeys["partner"] = "same-key"
+	cfg.APIKeys["reports"] = "same-key"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `API keys "partner" and "reports" must not share a value`) {
+		t.Errorf("Validate() error = %v, want duplicate key error", err)
+	}
+	if strings.Contains(err.Error(), "same-key") {
+		t.Errorf("Validate() error = %v, must not include the key", err)
+	}
+}
+
+func TestLoad_APIKeysMalformed(t *testing.T) {
+	for _, value := range []string{"partner", "partner key-one", "partner:", ":key-one", "reports:key-two,partner"} {
+		t.Setenv("API_KEYS", value)
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), "API_KEYS entry") {
+			t.Errorf("API_KEYS=%q: Load() error = %v, want a malformed entry error", value, err)
+			continue
+		}
+		if strings.Contains(err.Error(), "key-") {
+			t.Errorf("API_KEYS=%q: Load() error = %v, must not include the key", value, err)
+		}
+	}
+}
+
+func TestLoad_APIKeyScopesMalformed(t *testing.T) {
+	t.Setenv("API_KEYS", "partner:key-one")
+	for _, value := range []string{"partner", "partner:", ":country_code", "partner:|"} {
+		t.Setenv("API_KEY_SCOPES", value)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "API_KEY_SCOPES") {
+			t.Errorf("API_KEY_SCOPES=%q: Load() error = %v, want a malformed entry error", value, err)
+		}
+	}
+}
+
+func TestLoad_SelfTestProbes(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// A database that doesn't cover the probe would fail startup, so
+	// there are none unless configured
+	if len(cfg.SelfTestProbes) != 0 {
+		t.Errorf("unexpected default probes: %v", cfg.SelfTestProbes)
+	}
+
+	// Set but empty overrides the config file
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "config.yaml", "self_test_probes: [\"8.8.8.8=US\"]\n"))
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !slices.Equal(cfg.SelfTestProbes, []string{"8.8.8.8=US"}) {
+		t.Errorf("expected probes from the config file, got %v", cfg.SelfTestProbes)
+	}
+	t.Setenv("SELF_TEST_PROBES", "")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.SelfTestProbes) != 0 {
+		t.Errorf("expected no probes, got %v", cfg.SelfTestProbes)
+	}
+
+	t.Setenv("SELF_TEST_PROBES", "1.1.1.1, 2001:4860:4860::8888=us,example.com=US,8.8.8.8=USA")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `"example.com=US"`) || !strings.Contains(err.Error(), `"8.8.8.8=USA"`) {
+		t.Errorf("Validate() error = %v, want the two invalid probes reported", err)
+	}
+	if strings.Contains(err.Error(), "1.1.1.1") || strings.Contains(err.Error(), "2001:") {
+		t.Errorf("Validate() rejected a valid probe: %v", err)
+	}
+
+	ip, country, err := ParseProbe("2001:4860:4860::8888=us")
+	if err != nil || ip != netip.MustParseAddr("2001:4860:4860::8888") || country != "US" {
+		t.Errorf("ParseProbe() = %v, %q, %v", ip, country, err)
+	}
+}
+
+func TestLoad_APIKeyFile(t *testing.T) {
+	path := writeConfigFile(t, "api-key", "s3cret\n")
+	t.Setenv("API_KEY_FILE", path)
+	t.Setenv("API_KEY", "from-env")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.APIKeys[DefaultAPIKeyName]; got != "s3cret" {
+		t.Errorf("expected key from file without the trailing newline, got %q", got)
+	}
+}
+
+func TestLoad_APIKeyFileErrors(t *testing.T) {
+	t.Setenv("API_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "API_KEY_FILE") {
+		t.Errorf("Load() error = %v, want API_KEY_FILE read error", err)
+	}
+
+	t.Setenv("API_KEY_FILE", writeConfigFile(t, "empty", "\n"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("Load() error = %v, want empty file error", err)
+	}
+}
+
+func TestValidate_AdminAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"127.0.0.1:9090", ""},
+		{":9090", ""},
+		{"[::1]:9090", ""},
+		{"9090", "ADMIN_ADDR must be host:port"},
+		{"localhost:admin", "ADMIN_ADDR must be host:port"},
+		{"127.0.0.1:" + DefaultPort, "ADMIN_ADDR must use a port other than PORT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			cfg := Default()
+			cfg.AdminAddr = tt.addr
+			err := cfg.Validate()
+			if tt.want == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// With HTTP on a socket PORT is free, but gRPC still listens on TCP
+	cfg := Default()
+	cfg.ListenSocket = "/run/ipburack.sock"
+	cfg.GRPCPort = "9090"
+	cfg.AdminAddr = "127.0.0.1:" + DefaultPort
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want PORT ignored with LISTEN_SOCKET", err)
+	}
+	cfg.AdminAddr = "127.0.0.1:9090"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "GRPC_PORT") {
+		t.Errorf("Validate() error = %v, want a GRPC_PORT clash", err)
+	}
+}
+
+func TestValidate_PprofRequiresAPIKey(t *testing.T) {
+	cfg := Default()
+	cfg.PprofEnabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "PPROF_ENABLED") {
+		t.Errorf("Validate() error = %v, want pprof API key error", err)
+	}
+
+	cfg.APIKeys["ops"] = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
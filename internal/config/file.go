@@ -0,0 +1,47 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile overlays the settings in a YAML or JSON file, chosen by extension,
+// onto c. Keys that aren't Config fields are rejected so typos don't go
+// unnoticed.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+	case ".json":
+		// JSON is a subset of YAML, so both share the decoder below (and its
+		// duration parsing); this only makes syntax errors JSON-specific
+		var syntax any
+		if err := json.Unmarshal(data, &syntax); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q (want .yaml, .yml or .json)", ext)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if c.APIKeys == nil {
+		c.APIKeys = make(map[string]string)
+	}
+	return nil
+}
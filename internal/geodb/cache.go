@@ -0,0 +1,124 @@
+package geodb
+
+import (
+	"container/list"
+	"net/netip"
+	"sync"
+)
+
+type cacheKey struct {
+	ip   netip.Addr
+	opts LookupOptions
+}
+
+type cacheEntry struct {
+	key    cacheKey
+	result *LookupResult
+	err    error
+}
+
+// cacheStats is a snapshot of the lookup cache counters.
+type cacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+// lookupCache is a bounded LRU cache of lookup results. A nil *lookupCache is
+// valid and caches nothing, which is how a cache size of 0 disables it.
+type lookupCache struct {
+	mu      sync.Mutex
+	maxSize int
+	ll      *list.List
+	items   map[cacheKey]*list.Element
+	// gen is bumped on every purge so results computed against a database
+	// that has since been swapped out are not inserted afterwards.
+	gen    uint64
+	hits   uint64
+	misses uint64
+}
+
+func newLookupCache(maxSize int) *lookupCache {
+	if maxSize <= 0 {
+		return nil
+	}
+	return &lookupCache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		items:   make(map[cacheKey]*list.Element),
+	}
+}
+
+// get returns the cached entry for key along with the current generation,
+// which must be passed back to add.
+func (c *lookupCache) get(key cacheKey) (*cacheEntry, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		c.hits++
+		return el.Value.(*cacheEntry), c.gen, true
+	}
+	c.misses++
+	return nil, c.gen, false
+}
+
+func (c *lookupCache) add(entry *cacheEntry, gen uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if el, ok := c.items[entry.key]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[entry.key] = c.ll.PushFront(entry)
+	if c.ll.Len() > c.maxSize {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// purge drops all entries. Called whenever a database is swapped.
+func (c *lookupCache) purge() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	clear(c.items)
+	c.gen++
+}
+
+func (c *lookupCache) stats() cacheStats {
+	if c == nil {
+		return cacheStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return cacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+		Size:   c.ll.Len(),
+	}
+}
@@ -0,0 +1,95 @@
+package geodb
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func testKey(ip string) cacheKey {
+	return cacheKey{ip: netip.MustParseAddr(ip)}
+}
+
+func TestLookupCache_Eviction(t *testing.T) {
+	c := newLookupCache(2)
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		_, gen, _ := c.get(testKey(ip))
+		c.add(&cacheEntry{key: testKey(ip), result: &LookupResult{CountryCode: "US"}}, gen)
+	}
+
+	// Touch the first entry so the second becomes least recently used
+	if _, _, ok := c.get(testKey("192.0.2.1")); !ok {
+		t.Fatal("expected first entry to be cached")
+	}
+
+	_, gen, _ := c.get(testKey("192.0.2.3"))
+	c.add(&cacheEntry{key: testKey("192.0.2.3"), result: &LookupResult{CountryCode: "DE"}}, gen)
+
+	if _, _, ok := c.get(testKey("192.0.2.2")); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if _, _, ok := c.get(testKey("192.0.2.1")); !ok {
+		t.Error("expected recently used entry to survive eviction")
+	}
+	if got := c.stats().Size; got != 2 {
+		t.Errorf("expected size 2, got %d", got)
+	}
+}
+
+func TestLookupCache_PurgeDropsStaleAdds(t *testing.T) {
+	c := newLookupCache(10)
+	key := testKey("192.0.2.1")
+
+	// A lookup that started before a reload must not repopulate the cache
+	_, gen, _ := c.get(key)
+	c.purge()
+	c.add(&cacheEntry{key: key, result: &LookupResult{CountryCode: "US"}}, gen)
+
+	if _, _, ok := c.get(key); ok {
+		t.Error("expected entry computed before purge to be discarded")
+	}
+}
+
+func TestLookupCache_Stats(t *testing.T) {
+	c := newLookupCache(10)
+	key := testKey("192.0.2.1")
+
+	_, gen, _ := c.get(key)
+	c.add(&cacheEntry{key: key, result: &LookupResult{CountryCode: "US"}}, gen)
+	c.get(key)
+	c.get(key)
+
+	stats := c.stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("expected 2 hits and 1 miss, got %+v", stats)
+	}
+}
+
+func TestLookupCache_Disabled(t *testing.T) {
+	c := newLookupCache(0)
+	if c != nil {
+		t.Fatal("expected size 0 to disable the cache")
+	}
+
+	key := testKey("192.0.2.1")
+	c.add(&cacheEntry{key: key}, 0)
+	if _, _, ok := c.get(key); ok {
+		t.Error("expected disabled cache to never hit")
+	}
+}
+
+func TestGeoDB_ErrorsNotCached(t *testing.T) {
+	// No databases loaded, so every lookup fails with an internal error
+	g := New(Options{CacheSize: 10}, nil)
+
+	for range 2 {
+		if _, err := g.Lookup("192.0.2.1", LookupOptions{}); err == nil {
+			t.Fatal("expected lookup to fail without databases")
+		}
+	}
+
+	stats := g.cacheStats()
+	if stats.Hits != 0 || stats.Misses != 2 {
+		t.Errorf("expected internal errors to bypass the cache, got %+v", stats)
+	}
+}
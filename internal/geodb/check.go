@@ -0,0 +1,51 @@
+package geodb
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// errNotLoaded is reported by Check for a database that has no reader.
+var errNotLoaded = errors.New("database not loaded")
+
+// Fixed addresses Check looks up. Whether they are found doesn't matter,
+// only that the database can be searched for them.
+var (
+	checkIPv4 = netip.MustParseAddr("1.1.1.1")
+	checkIPv6 = netip.MustParseAddr("2606:4700:4700::1111")
+)
+
+// Check looks up a fixed address in every configured database, bypassing
+// the cache, and reports per database whether it could be read: nil when
+// the lookup answered, found or not, otherwise why it failed.
+func (g *GeoDB) Check() map[string]error {
+	errs := make(map[string]error)
+	for _, inst := range g.instances() {
+		ip := checkIPv4
+		if inst == g.cityIPv6 {
+			ip = checkIPv6
+		}
+		errs[inst.name] = inst.check(ip)
+	}
+	return errs
+}
+
+func (inst *dbInstance) check(ip netip.Addr) (err error) {
+	db := inst.acquire()
+	if db == nil {
+		return errNotLoaded
+	}
+	defer db.release()
+	// A corrupt file can make a reader panic rather than return an error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("lookup panicked: %v", r)
+		}
+	}()
+
+	if _, err := db.Lookup(ip); err != nil && !errors.Is(err, ErrIPNotFound) {
+		return err
+	}
+	return nil
+}
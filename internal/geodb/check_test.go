@@ -0,0 +1,45 @@
+package geodb
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+// brokenReader fails every lookup, like a truncated database file.
+type brokenReader struct {
+	Reader
+}
+
+func (brokenReader) Lookup(netip.Addr) (*LookupResult, error) {
+	return nil, errors.New("invalid node in search tree")
+}
+
+func TestCheck(t *testing.T) {
+	g := New(Options{}, testLogger{})
+	r, err := openMMDB(writeTestDB(t, "Test-Country", 6, map[string]map[string]any{
+		"8.8.8.0/24": {"country_code": "US"},
+	}), "Country")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.country.db.Store(newDBHandle(r))
+	t.Cleanup(func() { g.country.db.Load().release() })
+	g.cityIPv4.db.Store(newDBHandle(brokenReader{}))
+
+	errs := g.Check()
+	// The probe address isn't in the country database; a miss still
+	// proves the file is readable
+	if err := errs["country"]; err != nil {
+		t.Errorf("expected the country database to pass, got %v", err)
+	}
+	if err := errs["city-ipv4"]; err == nil || err.Error() != "invalid node in search tree" {
+		t.Errorf("expected the city-ipv4 lookup error, got %v", err)
+	}
+	if err := errs["city-ipv6"]; !errors.Is(err, errNotLoaded) {
+		t.Errorf("expected city-ipv6 to be reported as not loaded, got %v", err)
+	}
+	if _, ok := errs["asn"]; ok {
+		t.Error("expected the unconfigured ASN database to be left out")
+	}
+}
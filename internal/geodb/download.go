@@ -0,0 +1,424 @@
+package geodb
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/burakcan/ipburack/internal/geodb")
+
+// ErrChecksumMismatch is returned when a download doesn't match its published SHA256.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrWrongDatabaseType is returned when a download is a valid database of
+// the wrong kind, e.g. a city database served from the country URL.
+var ErrWrongDatabaseType = errors.New("wrong database type")
+
+// ErrBackingOff is returned instead of downloading while a database host's
+// Retry-After is in effect.
+var ErrBackingOff = errors.New("download backing off")
+
+// errNotModified means the server reported the database unchanged since the
+// last download, so there is nothing to reload.
+var errNotModified = errors.New("database not modified")
+
+// maxChecksumBytes bounds how much of a checksum file is read.
+const maxChecksumBytes = 1024
+
+// maxRetryAfter caps how long a host's Retry-After can hold off downloads.
+const maxRetryAfter = 24 * time.Hour
+
+// DefaultDownloadRetryDelay is used when Options.DownloadRetryDelay is not set.
+const DefaultDownloadRetryDelay = time.Second
+
+// DefaultDownloadTimeout bounds a whole download, including reading the body,
+// when Options.DownloadTimeout is not set.
+const DefaultDownloadTimeout = 5 * time.Minute
+
+// DefaultUserAgent identifies the service to database hosts.
+const DefaultUserAgent = "ipburack (+https://github.com/burakcan/ipburack)"
+
+// download is a validated database file waiting in its temp path to be
+// installed.
+type download struct {
+	tmpPath      string
+	etag         string
+	lastModified string
+	// size is the number of bytes written, after any decompression
+	size     int64
+	duration time.Duration
+}
+
+// retryAfterError is a 429 or 503 response that said when to try again.
+type retryAfterError struct {
+	status int
+	wait   time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("download failed with status: %d, retry after %s", e.status, e.wait)
+}
+
+// parseRetryAfter reads a Retry-After header, either delay-seconds or an
+// HTTP date, as a wait from now capped at maxRetryAfter. ok is false when
+// the header is missing or malformed.
+func parseRetryAfter(value string, now time.Time) (wait time.Duration, ok bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		wait = time.Duration(min(secs, int64(maxRetryAfter/time.Second))) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = max(date.Sub(now), 0)
+	} else {
+		return 0, false
+	}
+	return min(wait, maxRetryAfter), true
+}
+
+// discard removes a download that won't be installed.
+func (d *download) discard() {
+	_ = os.Remove(d.tmpPath)
+}
+
+// downloadDB downloads the database and installs it in place. It's only used
+// before the first load, where a file that fails to load fails startup, so
+// the validators are recorded straight away.
+func (g *GeoDB) downloadDB(ctx context.Context, inst *dbInstance) error {
+	d, err := g.fetchDB(ctx, inst)
+	if err != nil {
+		return err
+	}
+	if err := g.install(inst, d); err != nil {
+		return err
+	}
+	inst.keepValidators(d)
+	return nil
+}
+
+// fetchDB downloads the database to a temp file, retrying failed attempts with
+// exponential backoff. Cancelling ctx aborts the transfer in flight, removing
+// its temp file, as well as the wait between attempts.
+//
+// A 429 or 503 with a Retry-After stops the retries and, like a circuit
+// breaker, fails every download of the database with ErrBackingOff until
+// the time given has passed. The loaded database keeps serving meanwhile.
+func (g *GeoDB) fetchDB(ctx context.Context, inst *dbInstance) (_ *download, err error) {
+	if !inst.backoffUntil.IsZero() {
+		if time.Now().Before(inst.backoffUntil) {
+			return nil, fmt.Errorf("%w until %s", ErrBackingOff, inst.backoffUntil.Format(time.RFC3339))
+		}
+		inst.backoffUntil = time.Time{}
+		g.logger.Info(inst.name+" database download backoff ended", map[string]any{
+			"url": inst.url,
+		})
+	}
+
+	ctx, span := tracer.Start(ctx, "geodb.download", trace.WithAttributes(
+		attribute.String("geodb.database", inst.name),
+		attribute.String("url.full", inst.url),
+	))
+	defer func() {
+		switch {
+		case errors.Is(err, errNotModified):
+			span.SetAttributes(attribute.Bool("geodb.unchanged", true))
+		case err != nil:
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	delay := g.retryDelay
+	for attempt := 1; ; attempt++ {
+		d, err := g.fetchOnce(ctx, inst)
+		if err == nil || errors.Is(err, errNotModified) {
+			return d, err
+		}
+		var retryAfter *retryAfterError
+		if errors.As(err, &retryAfter) {
+			inst.backoffUntil = time.Now().Add(retryAfter.wait)
+			g.logger.Warn(inst.name+" database host asked to back off, pausing downloads", map[string]any{
+				"url":    inst.url,
+				"status": retryAfter.status,
+				"until":  inst.backoffUntil.Format(time.RFC3339),
+			})
+			return nil, err
+		}
+		// A cancelled transfer isn't worth a retry warning
+		if attempt > g.maxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt), attribute.String("error", err.Error())))
+		g.logger.Warn(inst.name+" database download failed, retrying", map[string]any{
+			"attempt": attempt,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (g *GeoDB) fetchOnce(ctx context.Context, inst *dbInstance) (*download, error) {
+	tmpPath := inst.path + ".tmp"
+
+	// Fetch the expected digest first so a bad checksum URL fails fast
+	var expected string
+	if inst.sha256URL != "" {
+		var err error
+		if expected, err = g.fetchChecksum(ctx, inst.sha256URL); err != nil {
+			return nil, fmt.Errorf("failed to fetch checksum: %w", err)
+		}
+	}
+
+	req, err := g.newRequest(ctx, inst.url)
+	if err != nil {
+		return nil, err
+	}
+	// Validators are only useful while the file they describe is on disk
+	if _, err := os.Stat(inst.path); err == nil {
+		if inst.etag != "" {
+			req.Header.Set("If-None-Match", inst.etag)
+		}
+		if inst.lastModified != "" {
+			req.Header.Set("If-Modified-Since", inst.lastModified)
+		}
+	}
+
+	start := time.Now()
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified {
+		g.logger.Info(inst.name+" database unchanged", map[string]any{
+			"url": inst.url,
+		})
+		return nil, errNotModified
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		// Retry-After: 0 is no reason to back off
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok && wait > 0 {
+			return nil, &retryAfterError{status: resp.StatusCode, wait: wait}
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	}
+
+	// Large databases take a while, so say how much is coming before the
+	// copy rather than looking hung until it's done
+	fields := map[string]any{"url": inst.url}
+	if resp.ContentLength >= 0 {
+		fields["content_length"] = resp.ContentLength
+	}
+	g.logger.Info(inst.name+" database download started", fields)
+
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The checksum covers the bytes as served, so it is computed before
+	// any decompression
+	hash := sha256.New()
+	body := io.TeeReader(resp.Body, hash)
+	if isGzip(inst.url, resp) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmpPath)
+			return nil, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		body = gz
+	}
+
+	size, err := io.Copy(out, body)
+	_ = out.Close()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+
+	if expected != "" {
+		actual := hex.EncodeToString(hash.Sum(nil))
+		if actual != expected {
+			_ = os.Remove(tmpPath)
+			g.logger.Error(inst.name+" database checksum mismatch", map[string]any{
+				"url":      inst.url,
+				"expected": expected,
+				"actual":   actual,
+			})
+			return nil, ErrChecksumMismatch
+		}
+	}
+
+	// Validate the downloaded file
+	testDB, err := openReader(inst.format, tmpPath, inst.dbType)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("downloaded file is invalid: %w", err)
+	}
+	err = inst.checkType(testDB)
+	_ = testDB.Close()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+
+	return &download{
+		tmpPath:      tmpPath,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		size:         size,
+		duration:     time.Since(start),
+	}, nil
+}
+
+// checkType rejects a database that isn't the kind the instance expects, e.g.
+// a city database configured as the country one. The kind must be one of
+// the words in the database type, so GeoLite2-Country matches Country but
+// not City. Geolocation databases may name their ASN source, as in
+// geolite2-geo-whois-asn-country, so one naming a country or city is never
+// taken as an ASN database.
+func (inst *dbInstance) checkType(db Reader) error {
+	if inst.dbType == "" {
+		return nil
+	}
+	dbType := db.Metadata().DatabaseType
+	words := strings.FieldsFunc(strings.ToLower(dbType), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	ok := slices.Contains(words, strings.ToLower(inst.dbType))
+	if ok && strings.EqualFold(inst.dbType, "ASN") {
+		ok = !slices.Contains(words, "country") && !slices.Contains(words, "city")
+	}
+	if !ok {
+		return fmt.Errorf("%w: expected a %s database, got %q", ErrWrongDatabaseType, inst.dbType, dbType)
+	}
+	return nil
+}
+
+// install moves a download into place. Its validators aren't recorded until
+// the database is serving, so a download that never gets swapped in is
+// fetched again next time rather than answered with a 304.
+func (g *GeoDB) install(inst *dbInstance, d *download) error {
+	if err := os.Rename(d.tmpPath, inst.path); err != nil {
+		d.discard()
+		return err
+	}
+
+	g.logger.Info(inst.name+" database downloaded", map[string]any{
+		"path":     inst.path,
+		"url":      inst.url,
+		"bytes":    d.size,
+		"duration": d.duration.String(),
+	})
+
+	return nil
+}
+
+// keepValidators records a download's ETag and Last-Modified for the next
+// conditional request.
+func (inst *dbInstance) keepValidators(d *download) {
+	inst.etag = d.etag
+	inst.lastModified = d.lastModified
+}
+
+// isGzip reports whether a download is gzip-compressed, either by a .gz URL
+// suffix or a Content-Encoding the HTTP client didn't already decode.
+func isGzip(rawURL string, resp *http.Response) bool {
+	if u, err := url.Parse(rawURL); err == nil && strings.HasSuffix(u.Path, ".gz") {
+		return true
+	}
+	return !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip")
+}
+
+// newRequest builds a GET carrying the configured User-Agent, cancelled
+// along with ctx.
+func (g *GeoDB) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", g.userAgent)
+	return req, nil
+}
+
+// get issues a GET with the configured client and User-Agent.
+func (g *GeoDB) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := g.newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return g.client.Do(req)
+}
+
+// fetchChecksum downloads a checksum file and returns the hex digest.
+func (g *GeoDB) fetchChecksum(ctx context.Context, url string) (string, error) {
+	resp, err := g.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("checksum download failed with status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumBytes))
+	if err != nil {
+		return "", err
+	}
+
+	return parseChecksum(string(body))
+}
+
+// parseChecksum accepts either a bare hex digest or sha256sum output
+// ("<digest>  <filename>") and returns the lowercase digest.
+func parseChecksum(s string) (string, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return "", errors.New("empty checksum file")
+	}
+
+	digest := strings.ToLower(fields[0])
+	if len(digest) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid SHA256 digest length: %d", len(digest))
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", fmt.Errorf("invalid SHA256 digest: %w", err)
+	}
+
+	return digest, nil
+}
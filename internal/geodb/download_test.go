@@ -0,0 +1,638 @@
+package geodb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/burakcan/ipburack/internal/logger/logtest"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (testLogger) Info(string, map[string]any)  {}
+func (testLogger) Warn(string, map[string]any)  {}
+func (testLogger) Error(string, map[string]any) {}
+
+func TestParseChecksum(t *testing.T) {
+	const digest = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare digest", input: digest, want: digest},
+		{name: "trailing newline", input: digest + "\n", want: digest},
+		{name: "sha256sum output", input: digest + "  country.mmdb\n", want: digest},
+		{name: "uppercase", input: "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08", want: digest},
+		{name: "empty", input: "", wantErr: true},
+		{name: "too short", input: "abc123", wantErr: true},
+		{name: "not hex", input: "zz86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChecksum(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseChecksum() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadDB_ChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/db.mmdb":
+			_, _ = w.Write([]byte("truncated database"))
+		case "/db.mmdb.sha256":
+			// SHA256 of "test", not of the served body
+			_, _ = w.Write([]byte("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08  db.mmdb\n"))
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	if err := os.WriteFile(path, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(Options{}, testLogger{})
+	inst := &dbInstance{
+		name:      "country",
+		path:      path,
+		url:       srv.URL + "/db.mmdb",
+		sha256URL: srv.URL + "/db.mmdb.sha256",
+	}
+
+	err := g.downloadDB(context.Background(), inst)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("expected temp file to be removed after checksum mismatch")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "existing" {
+		t.Errorf("expected existing database to be untouched, got %q", data)
+	}
+}
+
+func TestDownloadDB_RetriesWithBackoff(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	g := New(Options{DownloadRetries: 2, DownloadRetryDelay: time.Millisecond}, testLogger{})
+	inst := &dbInstance{
+		name: "country",
+		path: filepath.Join(t.TempDir(), "country.mmdb"),
+		url:  srv.URL,
+	}
+
+	if err := g.downloadDB(context.Background(), inst); err == nil {
+		t.Fatal("expected download to fail")
+	}
+
+	// One initial attempt plus two retries
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 download attempts, got %d", got)
+	}
+}
+
+func TestDownloadDB_RetryCancelled(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	g := New(Options{DownloadRetries: 5, DownloadRetryDelay: time.Hour}, testLogger{})
+	inst := &dbInstance{
+		name: "country",
+		path: filepath.Join(t.TempDir(), "country.mmdb"),
+		url:  srv.URL,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- g.downloadDB(ctx, inst) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("downloadDB did not return after context cancellation")
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected a single attempt before cancellation, got %d", got)
+	}
+}
+
+func TestDownloadDB_CancelledMidTransfer(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Send part of the body, then stall until the test is over
+		w.Header().Set("Content-Length", "1048576")
+		_, _ = w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		close(started)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	// Retries would only be cut short by the cancellation too
+	g := New(Options{DownloadRetries: 5, DownloadTimeout: time.Hour}, testLogger{})
+	inst := &dbInstance{
+		name: "country",
+		path: filepath.Join(t.TempDir(), "country.mmdb"),
+		url:  srv.URL,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- g.downloadDB(ctx, inst) }()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("download never started")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("downloadDB did not return after context cancellation")
+	}
+
+	if _, err := os.Stat(inst.path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temp file to be removed, stat error = %v", err)
+	}
+	if _, err := os.Stat(inst.path); !os.IsNotExist(err) {
+		t.Errorf("expected nothing installed, stat error = %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{"Fri, 02 Jan 2026 12:30:00 GMT", 30 * time.Minute, true},
+		{"Fri, 02 Jan 2026 11:00:00 GMT", 0, true},
+		{"999999999", maxRetryAfter, true},
+		{"", 0, false},
+		{"-5", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDownloadDB_RetryAfter(t *testing.T) {
+	db := buildTestDB(t, "Test-Country", 4, map[string]map[string]any{
+		"203.0.113.0/24": {"country_code": "US"},
+	})
+	var requests atomic.Int32
+	var throttled atomic.Bool
+	throttled.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if throttled.Load() {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write(db)
+	}))
+	defer srv.Close()
+
+	log := &logtest.Recorder{}
+	g := New(Options{DownloadRetries: 3, DownloadRetryDelay: time.Millisecond}, log)
+	inst := &dbInstance{
+		name: "country",
+		path: filepath.Join(t.TempDir(), "country.mmdb"),
+		url:  srv.URL,
+	}
+
+	// The host's answer stops the retries and opens the breaker
+	if err := g.downloadDB(context.Background(), inst); err == nil {
+		t.Fatal("expected a throttled download to fail")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected no retries after a Retry-After, got %d requests", got)
+	}
+	if wait := time.Until(inst.backoffUntil); wait < 59*time.Minute || wait > time.Hour {
+		t.Errorf("expected to back off for an hour, got %v", wait)
+	}
+
+	// Within the window the host isn't contacted at all
+	throttled.Store(false)
+	if err := g.downloadDB(context.Background(), inst); !errors.Is(err, ErrBackingOff) {
+		t.Errorf("expected ErrBackingOff, got %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected no request while backing off, got %d requests", got)
+	}
+
+	// Once it has passed, downloads resume
+	inst.backoffUntil = time.Now().Add(-time.Second)
+	if err := g.downloadDB(context.Background(), inst); err != nil {
+		t.Fatalf("downloadDB() error = %v", err)
+	}
+	if _, ok := log.Find("country database download backoff ended"); !ok {
+		t.Error("expected the end of the backoff to be logged")
+	}
+	if !inst.backoffUntil.IsZero() {
+		t.Error("expected the backoff to be cleared")
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadDB_Gzip(t *testing.T) {
+	db := buildTestDB(t, "Test-Country", 4, map[string]map[string]any{
+		"8.8.8.0/24": {"country_code": "US"},
+	})
+	compressed := gzipBytes(t, db)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/encoded.mmdb" {
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		_, _ = w.Write(compressed)
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/country.mmdb.gz", "/encoded.mmdb"} {
+		t.Run(path, func(t *testing.T) {
+			g := New(Options{}, testLogger{})
+			inst := &dbInstance{
+				name: "country",
+				path: filepath.Join(t.TempDir(), "country.mmdb"),
+				url:  srv.URL + path,
+			}
+
+			if err := g.downloadDB(context.Background(), inst); err != nil {
+				t.Fatalf("downloadDB() error = %v", err)
+			}
+
+			data, err := os.ReadFile(inst.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, db) {
+				t.Error("expected the decompressed database on disk")
+			}
+		})
+	}
+}
+
+func TestDownloadDB_GzipCorrupt(t *testing.T) {
+	compressed := gzipBytes(t, bytes.Repeat([]byte("x"), 1024))
+	// Truncate the stream so decompression fails mid-copy
+	compressed = compressed[:len(compressed)/2]
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(compressed)
+	}))
+	defer srv.Close()
+
+	g := New(Options{}, testLogger{})
+	inst := &dbInstance{
+		name: "country",
+		path: filepath.Join(t.TempDir(), "country.mmdb"),
+		url:  srv.URL + "/country.mmdb.gz",
+	}
+
+	if err := g.downloadDB(context.Background(), inst); err == nil {
+		t.Fatal("expected download of a corrupt gzip stream to fail")
+	}
+
+	if _, err := os.Stat(inst.path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("expected temp file to be removed after a decompression error")
+	}
+}
+
+func TestDownloadDB_UserAgent(t *testing.T) {
+	var gotUA atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA.Store(r.UserAgent())
+		_, _ = w.Write(buildTestDB(t, "Test-Country", 6, nil))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{name: "default", want: DefaultUserAgent},
+		{name: "custom", userAgent: "custom-agent/1.0", want: "custom-agent/1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(Options{UserAgent: tt.userAgent}, testLogger{})
+			inst := &dbInstance{
+				name: "country",
+				path: filepath.Join(t.TempDir(), "country.mmdb"),
+				url:  srv.URL,
+			}
+
+			if err := g.downloadDB(context.Background(), inst); err != nil {
+				t.Fatalf("downloadDB() error = %v", err)
+			}
+			if got := gotUA.Load(); got != tt.want {
+				t.Errorf("expected User-Agent %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDownloadDB_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	g := New(Options{DownloadTimeout: 50 * time.Millisecond}, testLogger{})
+	inst := &dbInstance{
+		name: "country",
+		path: filepath.Join(t.TempDir(), "country.mmdb"),
+		url:  srv.URL,
+	}
+
+	start := time.Now()
+	if err := g.downloadDB(context.Background(), inst); err == nil {
+		t.Fatal("expected download to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hung download took %v to fail", elapsed)
+	}
+}
+
+func TestDownloadDB_ConditionalRequests(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2026 00:00:00 GMT"
+	data := buildTestDB(t, "Test-Country", 6, nil)
+
+	var downloads atomic.Int32
+	var gotIfNoneMatch, gotIfModifiedSince atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIfNoneMatch.Store(r.Header.Get("If-None-Match"))
+		gotIfModifiedSince.Store(r.Header.Get("If-Modified-Since"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	g := New(Options{}, testLogger{})
+	inst := &dbInstance{
+		name: "country",
+		path: filepath.Join(t.TempDir(), "country.mmdb"),
+		url:  srv.URL,
+	}
+
+	if err := g.downloadDB(context.Background(), inst); err != nil {
+		t.Fatalf("first downloadDB() error = %v", err)
+	}
+	if got := gotIfNoneMatch.Load(); got != "" {
+		t.Errorf("expected no If-None-Match on first download, got %q", got)
+	}
+
+	err := g.downloadDB(context.Background(), inst)
+	if !errors.Is(err, errNotModified) {
+		t.Fatalf("second downloadDB() error = %v, want errNotModified", err)
+	}
+	if got := gotIfNoneMatch.Load(); got != etag {
+		t.Errorf("expected If-None-Match %q, got %q", etag, got)
+	}
+	if got := gotIfModifiedSince.Load(); got != lastModified {
+		t.Errorf("expected If-Modified-Since %q, got %q", lastModified, got)
+	}
+	if got := downloads.Load(); got != 1 {
+		t.Errorf("expected 1 full download, got %d", got)
+	}
+
+	// Without the file on disk the validators are useless
+	if err := os.Remove(inst.path); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.downloadDB(context.Background(), inst); err != nil {
+		t.Fatalf("downloadDB() after removal error = %v", err)
+	}
+	if got := downloads.Load(); got != 2 {
+		t.Errorf("expected a full download after the file was removed, got %d downloads", got)
+	}
+}
+
+func TestDownloadDB_LogsSize(t *testing.T) {
+	db := buildTestDB(t, "Test-Country", 4, map[string]map[string]any{
+		"203.0.113.0/24": {"country_code": "US"},
+	})
+
+	tests := []struct {
+		name          string
+		chunked       bool
+		contentLength any
+	}{
+		{name: "with Content-Length", contentLength: int64(len(db))},
+		{name: "chunked", chunked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.chunked {
+					w.(http.Flusher).Flush()
+				}
+				_, _ = w.Write(db)
+			}))
+			defer srv.Close()
+
+			log := &logtest.Recorder{}
+			g := New(Options{}, log)
+			inst := &dbInstance{
+				name: "country",
+				path: filepath.Join(t.TempDir(), "country.mmdb"),
+				url:  srv.URL,
+			}
+
+			if err := g.downloadDB(context.Background(), inst); err != nil {
+				t.Fatalf("downloadDB() error = %v", err)
+			}
+
+			started, ok := log.Find("country database download started")
+			if !ok {
+				t.Fatal("expected a log line before the copy")
+			}
+			if got := started.Data["content_length"]; got != tt.contentLength {
+				t.Errorf("expected content_length %v, got %v", tt.contentLength, got)
+			}
+
+			done, ok := log.Find("country database downloaded")
+			if !ok {
+				t.Fatal("expected a log line after the download")
+			}
+			if got := done.Data["bytes"]; got != int64(len(db)) {
+				t.Errorf("expected bytes %d, got %v", len(db), got)
+			}
+			if _, ok := done.Data["duration"].(string); !ok {
+				t.Errorf("expected a duration, got %v", done.Data["duration"])
+			}
+		})
+	}
+}
+
+func TestDownloadDB_DatabaseType(t *testing.T) {
+	tests := []struct {
+		name    string
+		served  string
+		wantErr error
+	}{
+		{name: "matching type", served: "GeoLite2-Country"},
+		{name: "case-insensitive", served: "geolite2-geo-whois-asn-country"},
+		{name: "city file at the country URL", served: "GeoLite2-City", wantErr: ErrWrongDatabaseType},
+		{name: "type word must match whole", served: "GeoLite2-Countryish", wantErr: ErrWrongDatabaseType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := buildTestDB(t, tt.served, 4, nil)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(db)
+			}))
+			defer srv.Close()
+
+			g := New(Options{}, testLogger{})
+			inst := &dbInstance{
+				name:   "country",
+				path:   filepath.Join(t.TempDir(), "country.mmdb"),
+				url:    srv.URL,
+				dbType: "Country",
+			}
+
+			err := g.downloadDB(context.Background(), inst)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("downloadDB() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				return
+			}
+			if _, err := os.Stat(inst.path + ".tmp"); !os.IsNotExist(err) {
+				t.Error("expected temp file to be removed after a type mismatch")
+			}
+			if _, err := os.Stat(inst.path); !os.IsNotExist(err) {
+				t.Error("expected the mismatched database not to be installed")
+			}
+		})
+	}
+}
+
+// typedReader reports a fixed database type.
+type typedReader struct {
+	Reader
+	dbType string
+}
+
+func (r typedReader) Metadata() Metadata {
+	return Metadata{DatabaseType: r.dbType}
+}
+
+func TestCheckType(t *testing.T) {
+	tests := []struct {
+		want   string
+		served string
+		ok     bool
+	}{
+		{"Country", "GeoLite2-Country", true},
+		{"Country", "geolite2-geo-whois-asn-country", true},
+		{"Country", "IP2Location-DB3-Country-City", true},
+		{"City", "IP2Location-DB3-Country-City", true},
+		{"City", "DBIP-City-Lite", true},
+		{"City", "IP2Location-DB1-Country", false},
+		{"ASN", "GeoLite2-ASN", true},
+		{"ASN", "geolite2-geo-whois-asn-country", false},
+		{"ASN", "DBIP-ASN-Lite", true},
+		{"ASN", "GeoLite2-ASNish", false},
+	}
+
+	for _, tt := range tests {
+		inst := &dbInstance{name: "test", dbType: tt.want}
+		err := inst.checkType(typedReader{dbType: tt.served})
+		if tt.ok && err != nil {
+			t.Errorf("checkType(%s) for %s: unexpected error %v", tt.served, tt.want, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrWrongDatabaseType) {
+			t.Errorf("checkType(%s) for %s: error = %v, want ErrWrongDatabaseType", tt.served, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package geodb
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// fallbackLogInterval is the least time between two fallback warnings.
+const fallbackLogInterval = time.Minute
+
+// fallbackLog rate-limits the warning logged when a lookup misses its
+// primary database and falls back to the other one. A city database that
+// keeps missing is usually misconfigured or stale, but logging every
+// fallback would flood the logs.
+type fallbackLog struct {
+	// next is the time, in Unix nanoseconds, before which warnings are
+	// suppressed
+	next atomic.Int64
+	// suppressed counts the fallbacks since the last warning
+	suppressed atomic.Int64
+}
+
+// logFallback records a lookup that fell back from the primary database to
+// the fallback one after err, warning at most once per fallbackLogInterval
+// with the number of fallbacks not logged in between.
+func (g *GeoDB) logFallback(primary, fallback string, err error) {
+	now := time.Now().UnixNano()
+	next := g.fallbacks.next.Load()
+	if now < next || !g.fallbacks.next.CompareAndSwap(next, now+int64(fallbackLogInterval)) {
+		g.fallbacks.suppressed.Add(1)
+		return
+	}
+	g.logger.Warn("lookup fell back to the "+fallback+" database", map[string]any{
+		"primary":    primary,
+		"error":      err.Error(),
+		"suppressed": g.fallbacks.suppressed.Swap(0),
+	})
+}
@@ -0,0 +1,71 @@
+package geodb
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/logger/logtest"
+)
+
+func TestLookup_LogsFallback(t *testing.T) {
+	log := &logtest.Recorder{}
+	g := New(Options{}, log)
+	r, err := openMMDB(writeTestDB(t, "Test-Country", 6, map[string]map[string]any{
+		"8.8.8.0/24": {"country_code": "US"},
+	}), "Country")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.country.db.Store(newDBHandle(r))
+	t.Cleanup(func() { g.country.db.Load().release() })
+
+	// Without a city database there's nothing to fall back from
+	if _, err := g.lookup(t.Context(), netip.MustParseAddr("8.8.8.8"), LookupOptions{UseCity: true}); err != nil {
+		t.Fatalf("lookup() error = %v", err)
+	}
+	if warns := log.Level("warn"); len(warns) != 0 {
+		t.Fatalf("expected no warning without a city database, got %+v", warns)
+	}
+
+	city, err := openMMDB(writeTestDB(t, "Test-City", 4, map[string]map[string]any{
+		"1.1.1.0/24": {"country_code": "AU", "city": "Sydney"},
+	}), "City")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.cityIPv4.db.Store(newDBHandle(city))
+	t.Cleanup(func() { g.cityIPv4.db.Load().release() })
+
+	// Addresses the city database misses fall back to country
+	for range 3 {
+		if _, err := g.lookup(t.Context(), netip.MustParseAddr("8.8.8.8"), LookupOptions{UseCity: true}); err != nil {
+			t.Fatalf("lookup() error = %v", err)
+		}
+	}
+	warns := log.Level("warn")
+	if len(warns) != 1 {
+		t.Fatalf("expected one fallback warning within the interval, got %d", len(warns))
+	}
+	if w := warns[0]; w.Message != "lookup fell back to the country database" || w.Data["primary"] != "city" {
+		t.Errorf("unexpected warning: %+v", w)
+	}
+
+	// Once the interval has passed, the next one reports the ones skipped
+	g.fallbacks.next.Store(0)
+	if _, err := g.lookup(t.Context(), netip.MustParseAddr("8.8.8.8"), LookupOptions{UseCity: true}); err != nil {
+		t.Fatalf("lookup() error = %v", err)
+	}
+	warns = log.Level("warn")
+	if len(warns) != 2 || warns[1].Data["suppressed"] != int64(2) {
+		t.Errorf("expected a second warning with 2 suppressed, got %+v", warns)
+	}
+
+	// Lookups the primary database answers don't warn
+	g.fallbacks.next.Store(0)
+	if _, err := g.lookup(t.Context(), netip.MustParseAddr("8.8.8.8"), LookupOptions{}); err != nil {
+		t.Fatalf("lookup() error = %v", err)
+	}
+	if warns = log.Level("warn"); len(warns) != 2 {
+		t.Errorf("expected no warning for a primary hit, got %+v", warns[2:])
+	}
+}
@@ -4,20 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/oschwald/maxminddb-golang/v2"
 )
 
 var (
 	ErrInvalidIP  = errors.New("invalid IP address")
 	ErrIPNotFound = errors.New("IP not found in database")
+	// ErrCityUnavailable means the city database for the address family
+	// isn't loaded; lookups fall back to the country database
+	ErrCityUnavailable = errors.New("city database unavailable")
+	// ErrPrivateIP is returned, when detection is enabled, for private and
+	// reserved addresses that no database can locate
+	ErrPrivateIP = errors.New("private or reserved IP address")
 )
 
 // CountryRecord matches the structure in geolite2-geo-whois-asn-country MMDB
@@ -27,61 +32,318 @@ type CountryRecord struct {
 
 // CityRecord matches the structure in geolite2-city MMDB
 type CityRecord struct {
-	CountryCode string  `maxminddb:"country_code"`
-	City        string  `maxminddb:"city"`
-	PostCode    string  `maxminddb:"postcode"`
-	Latitude    float64 `maxminddb:"latitude"`
-	Longitude   float64 `maxminddb:"longitude"`
+	CountryCode string `maxminddb:"country_code"`
+	// Region is the first-level subdivision (state/province) name. The
+	// ip-location-db builds carry names, not ISO 3166-2 codes, and some
+	// variants omit the field, which leaves it empty.
+	Region    string  `maxminddb:"state1"`
+	City      string  `maxminddb:"city"`
+	PostCode  string  `maxminddb:"postcode"`
+	Latitude  float64 `maxminddb:"latitude"`
+	Longitude float64 `maxminddb:"longitude"`
+	// AccuracyRadius is in km. The ip-location-db builds don't carry it, so
+	// it is only set with databases that do.
+	AccuracyRadius uint16 `maxminddb:"accuracy_radius"`
+}
+
+// ASNRecord matches the structure in the ip-location-db ASN MMDB
+type ASNRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// LookupOptions controls which databases a lookup consults.
+type LookupOptions struct {
+	// UseCity tries the city database first, falling back to country
+	UseCity bool
+	// Strict consults only the database UseCity selects, so an address it
+	// doesn't have is not found rather than resolved by the other one
+	Strict bool
+	// ASN also resolves ASN data; a no-op when no ASN database is configured
+	ASN bool
+	// Full consults every configured database and merges what they know;
+	// it implies UseCity and ASN and overrides Strict
+	Full bool
 }
 
 type LookupResult struct {
 	CountryCode string `json:"country_code"`
 	PostalCode  string `json:"postal_code,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	// Coordinates are pointers so a genuine (0,0) match isn't dropped by omitempty.
+	// They are only set for city matches.
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// AccuracyRadius is in km; 0 when unknown
+	AccuracyRadius uint16 `json:"accuracy_radius,omitempty"`
+	ASN            uint   `json:"asn,omitempty"`
+	ASOrg          string `json:"as_org,omitempty"`
+	// IPVersion is "v4" or "v6"; IPv4-mapped addresses count as v4
+	IPVersion string `json:"ip_version,omitempty"`
+	// Network is the database network the address matched, in CIDR
+	// notation; empty when the reader can't tell
+	Network string `json:"network,omitempty"`
 }
 
 type Logger interface {
 	Info(message string, data map[string]any)
+	Warn(message string, data map[string]any)
 	Error(message string, data map[string]any)
 }
 
+// Metrics receives database lifecycle events.
+type Metrics interface {
+	DatabaseUpdated(name string, t time.Time)
+	// DatabaseStale reports whether a database is older than the max age
+	DatabaseStale(name string, stale bool)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) DatabaseUpdated(string, time.Time) {}
+func (nopMetrics) DatabaseStale(string, bool)        {}
+
 type dbInstance struct {
-	db   *maxminddb.Reader
+	// db is the loaded reader, nil until the first load. Lookups load it
+	// without locking; see dbHandle.
+	db atomic.Pointer[dbHandle]
+	// mu guards the timestamps below
 	mu   sync.RWMutex
+	name string
 	path string
-	url  string
+	// url is where updates are downloaded from; empty when the file is
+	// managed externally (see external)
+	url string
+	// format is the file format; empty means FormatMMDB
+	format Format
+	// sha256URL optionally points to a checksum file for the download
+	sha256URL string
+	// dbType selects the fields lookups return, and must appear in a
+	// download's database type, ignoring case, so a URL pointing at the
+	// wrong kind of database is rejected. Empty skips the check.
+	dbType string
+	// lastUpdated is when db was last successfully loaded
+	lastUpdated time.Time
+	// lastCurrent is when db was last confirmed current, by downloading it
+	// or by the server reporting it unchanged. A file loaded from disk counts
+	// from when it was written.
+	lastCurrent time.Time
+	// Validators from the last download, sent on the next one so an
+	// unchanged database isn't fetched again. Only touched by downloads and
+	// LoadFromBytes, which never run concurrently for the same instance.
+	etag         string
+	lastModified string
+	// backoffUntil is set when the host answered with a Retry-After, and
+	// holds off downloads until then. Only touched by downloads too.
+	backoffUntil time.Time
+}
+
+// DatabaseInfo describes the state of a configured database.
+type DatabaseInfo struct {
+	Loaded bool `json:"loaded"`
+	// BuildTime comes from the database metadata
+	BuildTime time.Time `json:"build_time,omitzero"`
+	// LastUpdated is when the server last loaded this database
+	LastUpdated time.Time `json:"last_updated,omitzero"`
+	// Stale is set when the database hasn't been confirmed current for
+	// longer than the configured max age
+	Stale bool `json:"stale"`
+}
+
+// DatabaseVersion identifies the build of a loaded database, from its
+// metadata, along with where it is loaded from.
+type DatabaseVersion struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	URL          string    `json:"url,omitempty"`
+	DatabaseType string    `json:"database_type"`
+	BuildEpoch   uint      `json:"build_epoch"`
+	BuildTime    time.Time `json:"build_time"`
+	// NodeCount is 0 for formats other than MMDB
+	NodeCount uint `json:"node_count"`
+	// IPVersion is 4 for IPv4-only databases and 6 for IPv4 and IPv6
+	IPVersion uint `json:"ip_version"`
+}
+
+// Options configures a GeoDB.
+type Options struct {
+	// Format is the file format of the country and city databases; empty
+	// means FormatMMDB. The ASN database is always MMDB.
+	Format       Format
+	CountryPath  string
+	CountryURL   string
+	CityIPv4Path string
+	CityIPv4URL  string
+	CityIPv6Path string
+	CityIPv6URL  string
+	// ASNPath enables the optional ASN database when non-empty
+	ASNPath string
+	ASNURL  string
+	// Optional SHA256 checksum URLs; downloads are verified when set
+	CountrySHA256URL  string
+	CityIPv4SHA256URL string
+	CityIPv6SHA256URL string
+	ASNSHA256URL      string
+	UpdateInterval    time.Duration
+	// DownloadRetries is the number of retries after a failed download (0 disables retries)
+	DownloadRetries int
+	// DownloadRetryDelay is the initial backoff, doubled after each retry
+	DownloadRetryDelay time.Duration
+	// HTTPClient is used for downloads; nil builds one with DownloadTimeout
+	// and the default transport, which honors HTTP_PROXY/HTTPS_PROXY
+	HTTPClient *http.Client
+	// DownloadTimeout bounds each download when HTTPClient is nil
+	DownloadTimeout time.Duration
+	// UserAgent is sent with downloads; empty uses DefaultUserAgent
+	UserAgent string
+	// CacheSize is the maximum number of cached lookup results (0 disables the cache)
+	CacheSize int
+	// Metrics is optional; nil disables instrumentation
+	Metrics Metrics
+	// Offline disables all downloads: missing files are an error and there
+	// are no scheduled updates
+	Offline bool
+	// DetectPrivate makes Lookup return ErrPrivateIP for private, loopback,
+	// link-local and other reserved addresses instead of not-found
+	DetectPrivate bool
+	// MaxAge flags a loaded database as stale once it hasn't been confirmed
+	// current for this long (0 disables the check)
+	MaxAge time.Duration
+	// SelfTest are known-answer lookups Start runs once the databases are
+	// loaded; a failed one fails Start. Empty skips the self-test.
+	SelfTest []Probe
 }
 
 type GeoDB struct {
 	country        *dbInstance
 	cityIPv4       *dbInstance
 	cityIPv6       *dbInstance
+	asn            *dbInstance // nil when not configured
+	cache          *lookupCache
+	metrics        Metrics
 	updateInterval time.Duration
+	maxRetries     int
+	retryDelay     time.Duration
+	client         *http.Client
+	userAgent      string
+	offline        bool
+	detectPrivate  bool
+	maxAge         time.Duration
+	probes         []Probe
 	logger         Logger
-	cancel         context.CancelFunc
-	wg             sync.WaitGroup
+	fallbacks      fallbackLog
+	// updateMu serializes the initial load and scheduled and manual updates
+	// so they don't clobber each other's temp files
+	updateMu sync.Mutex
+	// swapMu serializes swaps. Lookups don't take it: they pin their
+	// readers through swapGen, so they never mix old and new data and
+	// never wait on a swap.
+	swapMu  sync.Mutex
+	swapGen atomic.Uint64
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
 }
 
-func New(countryPath, countryURL, cityIPv4Path, cityIPv4URL, cityIPv6Path, cityIPv6URL string, updateInterval time.Duration, logger Logger) *GeoDB {
-	return &GeoDB{
-		country:        &dbInstance{path: countryPath, url: countryURL},
-		cityIPv4:       &dbInstance{path: cityIPv4Path, url: cityIPv4URL},
-		cityIPv6:       &dbInstance{path: cityIPv6Path, url: cityIPv6URL},
-		updateInterval: updateInterval,
+func New(opts Options, logger Logger) *GeoDB {
+	g := &GeoDB{
+		country:        &dbInstance{name: "country", path: opts.CountryPath, url: opts.CountryURL, sha256URL: opts.CountrySHA256URL, format: opts.Format, dbType: "Country"},
+		cityIPv4:       &dbInstance{name: "city-ipv4", path: opts.CityIPv4Path, url: opts.CityIPv4URL, sha256URL: opts.CityIPv4SHA256URL, format: opts.Format, dbType: "City"},
+		cityIPv6:       &dbInstance{name: "city-ipv6", path: opts.CityIPv6Path, url: opts.CityIPv6URL, sha256URL: opts.CityIPv6SHA256URL, format: opts.Format, dbType: "City"},
+		cache:          newLookupCache(opts.CacheSize),
+		metrics:        opts.Metrics,
+		updateInterval: opts.UpdateInterval,
+		maxRetries:     opts.DownloadRetries,
+		retryDelay:     opts.DownloadRetryDelay,
+		client:         opts.HTTPClient,
+		userAgent:      opts.UserAgent,
+		offline:        opts.Offline,
+		detectPrivate:  opts.DetectPrivate,
+		maxAge:         opts.MaxAge,
+		probes:         opts.SelfTest,
 		logger:         logger,
 	}
+	if g.metrics == nil {
+		g.metrics = nopMetrics{}
+	}
+	if g.retryDelay <= 0 {
+		g.retryDelay = DefaultDownloadRetryDelay
+	}
+	if g.client == nil {
+		timeout := opts.DownloadTimeout
+		if timeout <= 0 {
+			timeout = DefaultDownloadTimeout
+		}
+		g.client = &http.Client{Timeout: timeout}
+	}
+	if g.userAgent == "" {
+		g.userAgent = DefaultUserAgent
+	}
+	if opts.ASNPath != "" {
+		g.asn = &dbInstance{name: "asn", path: opts.ASNPath, url: opts.ASNURL, sha256URL: opts.ASNSHA256URL, dbType: "ASN"}
+	}
+	return g
+}
+
+// external reports whether the database file is managed outside the
+// server: with no URL it is only ever loaded from disk, never downloaded.
+func (inst *dbInstance) external() bool {
+	return inst.url == ""
+}
+
+// instances returns all configured databases.
+func (g *GeoDB) instances() []*dbInstance {
+	insts := []*dbInstance{g.country, g.cityIPv4, g.cityIPv6}
+	if g.asn != nil {
+		insts = append(insts, g.asn)
+	}
+	return insts
 }
 
+// Start loads the databases, downloading any that are missing, and starts
+// background updates. Only the country database is required; the others are
+// logged and retried on the next update so the server can run degraded. The
+// self-test probes, if any, must pass before Start returns successfully.
 func (g *GeoDB) Start(ctx context.Context) error {
-	// Initialize all databases
-	if err := g.initDB(g.country, "country"); err != nil {
-		return err
+	// Initialize all databases concurrently so a cold start takes as long as
+	// the slowest download rather than all of them
+	insts := g.instances()
+	errs := make([]error, len(insts))
+	// The server is already up, so a refresh may be asked for meanwhile;
+	// it waits until the databases are loaded
+	g.updateMu.Lock()
+	var wg sync.WaitGroup
+	for i, inst := range insts {
+		wg.Go(func() {
+			errs[i] = g.initDB(ctx, inst)
+		})
+	}
+	wg.Wait()
+	g.updateMu.Unlock()
+
+	for i, inst := range insts {
+		if errs[i] == nil {
+			continue
+		}
+		if inst == g.country {
+			return errs[i]
+		}
+		g.logger.Warn(inst.name+" database unavailable, continuing without it", map[string]any{
+			"error": errs[i].Error(),
+		})
 	}
-	if err := g.initDB(g.cityIPv4, "city-ipv4"); err != nil {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	if err := g.initDB(g.cityIPv6, "city-ipv6"); err != nil {
+	if err := g.selfTest(ctx); err != nil {
 		return err
 	}
+	g.checkStale(time.Now())
+
+	if g.offline {
+		g.logger.Info("offline mode, automatic database updates disabled", nil)
+		return nil
+	}
 
 	// Start background update goroutine
 	updateCtx, cancel := context.WithCancel(ctx)
@@ -92,24 +354,36 @@ func (g *GeoDB) Start(ctx context.Context) error {
 	return nil
 }
 
-func (g *GeoDB) initDB(inst *dbInstance, name string) error {
+func (g *GeoDB) initDB(ctx context.Context, inst *dbInstance) error {
 	dir := filepath.Dir(inst.path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
+	if inst.external() && !g.offline {
+		g.logger.Info(inst.name+" database has no URL, loading it from disk only (external-file mode)", map[string]any{
+			"path": inst.path,
+		})
+	}
+
 	if _, err := os.Stat(inst.path); os.IsNotExist(err) {
-		g.logger.Info(name+" database not found, downloading", map[string]any{
+		if inst.external() && !g.offline {
+			return fmt.Errorf("%s database not found at %s and no URL is configured to download it", inst.name, inst.path)
+		}
+		if g.offline {
+			return fmt.Errorf("%s database not found at %s and downloads are disabled (offline mode)", inst.name, inst.path)
+		}
+		g.logger.Info(inst.name+" database not found, downloading", map[string]any{
 			"path": inst.path,
 			"url":  inst.url,
 		})
-		if err := g.downloadDB(inst, name); err != nil {
-			return fmt.Errorf("failed to download %s database: %w", name, err)
+		if err := g.downloadDB(ctx, inst); err != nil {
+			return fmt.Errorf("failed to download %s database: %w", inst.name, err)
 		}
 	}
 
-	if err := g.loadDB(inst, name); err != nil {
-		return fmt.Errorf("failed to load %s database: %w", name, err)
+	if err := g.loadDB(inst); err != nil {
+		return fmt.Errorf("failed to load %s database: %w", inst.name, err)
 	}
 
 	return nil
@@ -121,160 +395,379 @@ func (g *GeoDB) Stop() {
 	}
 	g.wg.Wait()
 
-	for _, inst := range []*dbInstance{g.country, g.cityIPv4, g.cityIPv6} {
-		inst.mu.Lock()
-		if inst.db != nil {
-			_ = inst.db.Close()
-		}
-		inst.mu.Unlock()
+	// Readers still pinned by lookups close once those finish
+	g.swapMu.Lock()
+	defer g.swapMu.Unlock()
+	g.swapGen.Add(1)
+	for _, inst := range g.instances() {
+		inst.db.Swap(nil).release()
 	}
+	g.swapGen.Add(1)
+}
+
+// Lookup is LookupCtx without cancellation.
+func (g *GeoDB) Lookup(ipStr string, opts LookupOptions) (*LookupResult, error) {
+	return g.LookupCtx(context.Background(), ipStr, opts)
 }
 
-// Lookup performs a lookup. If useCity is true, tries city DB first with country fallback.
-func (g *GeoDB) Lookup(ipStr string, useCity bool) (*LookupResult, error) {
+// LookupCtx performs a lookup. If opts.UseCity is true, tries city DB first with country fallback;
+// opts.Strict disables the fallback in either direction.
+// Results (including not-found) are served from the LRU cache when enabled.
+// Once ctx is done the database is no longer consulted and ctx.Err() is returned.
+func (g *GeoDB) LookupCtx(ctx context.Context, ipStr string, opts LookupOptions) (*LookupResult, error) {
+	// Parsed once here; the cache and every database fallback use the netip.Addr
 	ip, err := netip.ParseAddr(ipStr)
 	if err != nil {
 		return nil, ErrInvalidIP
 	}
+	// IPv4-mapped addresses (::ffff:a.b.c.d) belong to the IPv4 databases
+	ip = ip.Unmap()
+	if g.detectPrivate && isPrivate(ip) {
+		return nil, ErrPrivateIP
+	}
 
-	if useCity {
-		// Try city first, fallback to country
-		if result, err := g.lookupCity(ip); err == nil {
-			return result, nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key := cacheKey{ip: ip, opts: opts}
+	entry, gen, ok := g.cache.get(key)
+	if ok {
+		return entry.result, entry.err
+	}
+
+	result, err := g.lookup(ctx, ip, opts)
+	if err == nil || errors.Is(err, ErrIPNotFound) {
+		g.cache.add(&cacheEntry{key: key, result: result, err: err}, gen)
+	}
+	return result, err
+}
+
+// Databases reports the state of every configured database, keyed by name.
+func (g *GeoDB) Databases() map[string]DatabaseInfo {
+	infos := make(map[string]DatabaseInfo)
+	for _, inst := range g.instances() {
+		db := inst.acquire()
+		inst.mu.RLock()
+		info := DatabaseInfo{
+			Loaded:      db != nil,
+			LastUpdated: inst.lastUpdated,
+			Stale:       g.isStale(inst, time.Now()),
+		}
+		inst.mu.RUnlock()
+		if db != nil {
+			info.BuildTime = db.Metadata().BuildTime
+			db.release()
 		}
-		return g.lookupCountry(ip)
+		infos[inst.name] = info
 	}
+	return infos
+}
 
-	// Try country first, fallback to city
-	if result, err := g.lookupCountry(ip); err == nil {
-		return result, nil
+// Versions reports the build metadata of every loaded database, in a fixed
+// order: country, city-ipv4, city-ipv6, then asn.
+func (g *GeoDB) Versions() []DatabaseVersion {
+	versions := []DatabaseVersion{}
+	for _, inst := range g.instances() {
+		if db := inst.acquire(); db != nil {
+			meta := db.Metadata()
+			db.release()
+			versions = append(versions, DatabaseVersion{
+				Name:         inst.name,
+				Path:         inst.path,
+				URL:          inst.url,
+				DatabaseType: meta.DatabaseType,
+				BuildEpoch:   uint(meta.BuildTime.Unix()),
+				BuildTime:    meta.BuildTime,
+				NodeCount:    meta.NodeCount,
+				IPVersion:    meta.IPVersion,
+			})
+		}
 	}
-	return g.lookupCity(ip)
+	return versions
 }
 
-func (g *GeoDB) lookupCountry(ip netip.Addr) (*LookupResult, error) {
-	g.country.mu.RLock()
-	db := g.country.db
-	g.country.mu.RUnlock()
+// Ready reports whether each configured database is loaded, keyed by name.
+func (g *GeoDB) Ready() map[string]bool {
+	ready := make(map[string]bool)
+	for _, inst := range g.instances() {
+		ready[inst.name] = inst.db.Load() != nil
+	}
+	return ready
+}
 
-	if db == nil {
-		return nil, errors.New("country database not loaded")
+// cacheStats reports lookup cache hits/misses.
+func (g *GeoDB) cacheStats() cacheStats {
+	return g.cache.stats()
+}
+
+func (g *GeoDB) lookup(ctx context.Context, ip netip.Addr, opts LookupOptions) (*LookupResult, error) {
+	// Select IPv4 or IPv6 city database based on IP type
+	city := g.cityIPv4
+	if !ip.Is4() {
+		city = g.cityIPv6
+	}
+	if opts.Full {
+		// The city database has the most detail; the country one covers
+		// the addresses it lacks, and ASN data is merged in below
+		opts.UseCity, opts.Strict, opts.ASN = true, false, true
+	}
+	var asn *dbInstance
+	if opts.ASN {
+		asn = g.asn
 	}
+	dbs := g.pin(g.country, city, asn)
+	defer dbs.release()
+	dbs.fallback = g.logFallback
 
-	var record CountryRecord
-	if err := db.Lookup(ip).Decode(&record); err != nil {
-		return nil, fmt.Errorf("lookup failed: %w", err)
+	result, err := dbs.lookupLocation(ip, opts)
+	if err != nil {
+		return nil, err
 	}
+	result.IPVersion = ipVersion(ip)
 
-	if record.CountryCode == "" {
-		return nil, ErrIPNotFound
+	// ASN data is best-effort: a miss leaves the location result intact
+	if asn != nil {
+		// A result without the ASN it was asked for would be cached
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if record, err := dbs.lookupASN(ip); err == nil {
+			result.ASN = record.ASN
+			result.ASOrg = record.ASOrg
+		}
 	}
 
-	return &LookupResult{
-		CountryCode: record.CountryCode,
-	}, nil
+	return result, nil
 }
 
-func (g *GeoDB) lookupCity(ip netip.Addr) (*LookupResult, error) {
-	// Select IPv4 or IPv6 database based on IP type
-	var inst *dbInstance
+// ipVersion names the address family of an unmapped address.
+func ipVersion(ip netip.Addr) string {
 	if ip.Is4() {
-		inst = g.cityIPv4
-	} else {
-		inst = g.cityIPv6
+		return "v4"
 	}
+	return "v6"
+}
 
-	inst.mu.RLock()
-	db := inst.db
-	inst.mu.RUnlock()
-
-	if db == nil {
-		return nil, errors.New("city database not loaded")
+func (s dbSet) lookupLocation(ip netip.Addr, opts LookupOptions) (*LookupResult, error) {
+	if opts.Strict {
+		if !opts.UseCity {
+			return s.lookupCountry(ip)
+		}
+		result, err := s.lookupCity(ip)
+		if errors.Is(err, ErrCityUnavailable) {
+			// Without a city database no address is in it
+			return nil, fmt.Errorf("%w: %w", ErrIPNotFound, err)
+		}
+		return result, err
 	}
 
-	var record CityRecord
-	if err := db.Lookup(ip).Decode(&record); err != nil {
-		return nil, fmt.Errorf("lookup failed: %w", err)
+	if opts.UseCity {
+		// Try city first, fallback to country
+		result, err := s.lookupCity(ip)
+		if err == nil {
+			return result, nil
+		}
+		// Without a city database every lookup lands here, which isn't
+		// worth a warning
+		if !errors.Is(err, ErrCityUnavailable) {
+			s.fellBack("city", "country", err)
+		}
+		return s.lookupCountry(ip)
 	}
 
-	if record.CountryCode == "" {
-		return nil, ErrIPNotFound
+	// Try country first, fallback to city
+	result, err := s.lookupCountry(ip)
+	if err == nil {
+		return result, nil
 	}
-
-	return &LookupResult{
-		CountryCode: record.CountryCode,
-		PostalCode:  record.PostCode,
-	}, nil
+	result, cityErr := s.lookupCity(ip)
+	if errors.Is(cityErr, ErrCityUnavailable) {
+		// No fallback available, so the country outcome stands
+		return nil, err
+	}
+	s.fellBack("country", "city", err)
+	return result, cityErr
 }
 
-func (g *GeoDB) loadDB(inst *dbInstance, name string) error {
-	db, err := maxminddb.Open(inst.path)
-	if err != nil {
-		return err
+// fellBack reports a lookup that missed the primary database to the
+// fallback hook, if any.
+func (s dbSet) fellBack(primary, fallback string, err error) {
+	if s.fallback != nil {
+		s.fallback(primary, fallback, err)
 	}
+}
 
-	inst.mu.Lock()
-	old := inst.db
-	inst.db = db
-	inst.mu.Unlock()
-
-	if old != nil {
-		_ = old.Close()
+func (s dbSet) lookupCountry(ip netip.Addr) (*LookupResult, error) {
+	if s.country == nil {
+		return nil, errors.New("country database not loaded")
 	}
+	return s.country.Lookup(ip)
+}
 
-	g.logger.Info(name+" database loaded", map[string]any{
-		"path": inst.path,
-	})
-
-	return nil
+func (s dbSet) lookupCity(ip netip.Addr) (*LookupResult, error) {
+	if s.city == nil {
+		return nil, ErrCityUnavailable
+	}
+	return s.city.Lookup(ip)
 }
 
-func (g *GeoDB) downloadDB(inst *dbInstance, name string) error {
-	tmpPath := inst.path + ".tmp"
+func (s dbSet) lookupASN(ip netip.Addr) (*LookupResult, error) {
+	if s.asn == nil {
+		return nil, errors.New("asn database not loaded")
+	}
+	return s.asn.Lookup(ip)
+}
 
-	resp, err := http.Get(inst.url)
+func (g *GeoDB) loadDB(inst *dbInstance) error {
+	db, err := openReader(inst.format, inst.path, inst.dbType)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = resp.Body.Close() }()
+	return g.loadAll([]pendingDB{{inst: inst, db: db, current: modTime(inst.path)}})
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
+// modTime returns when the file at path was last written, or the zero time
+// if it can't be read.
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
 	}
+	return fi.ModTime()
+}
 
-	out, err := os.Create(tmpPath)
+// openBytes opens data as the instance's database, checking it is the
+// expected kind as a download would be. Only MMDB data is supported.
+func (inst *dbInstance) openBytes(data []byte) (Reader, error) {
+	if inst.format != FormatMMDB && inst.format != "" {
+		return nil, fmt.Errorf("loading %s databases from memory is not supported", inst.format)
+	}
+	db, err := openMMDBBytes(data, inst.dbType)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := inst.checkType(db); err != nil {
+		_ = db.Close()
+		return nil, err
 	}
+	return db, nil
+}
+
+// LoadFromBytes swaps in an MMDB database held in memory, such as a small
+// one embedded in the binary, in place of the named database ("country",
+// "city-ipv4", "city-ipv6" or "asn"). Nothing is written to its path, so a
+// later update or reload replaces it as usual; the next update downloads the
+// database in full even if the server's copy hasn't changed. With no URL
+// configured only a reload does. data must not be modified afterwards.
+func (g *GeoDB) LoadFromBytes(name string, data []byte) error {
+	i := slices.IndexFunc(g.instances(), func(inst *dbInstance) bool { return inst.name == name })
+	if i < 0 {
+		return fmt.Errorf("unknown database %q", name)
+	}
+	inst := g.instances()[i]
 
-	_, err = io.Copy(out, resp.Body)
-	_ = out.Close()
+	g.updateMu.Lock()
+	defer g.updateMu.Unlock()
+
+	db, err := inst.openBytes(data)
 	if err != nil {
-		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to load %s database: %w", name, err)
+	}
+	if err := g.loadAll([]pendingDB{{inst: inst, db: db, current: db.Metadata().BuildTime}}); err != nil {
 		return err
 	}
+	// The validators describe the last download, which is no longer what's
+	// served, so a 304 mustn't keep the data from memory in place
+	inst.etag, inst.lastModified = "", ""
+	return nil
+}
 
-	// Validate the downloaded file
-	testDB, err := maxminddb.Open(tmpPath)
-	if err != nil {
-		_ = os.Remove(tmpPath)
-		return fmt.Errorf("downloaded file is invalid: %w", err)
+// pendingDB is a validated database waiting to be swapped in.
+type pendingDB struct {
+	inst *dbInstance
+	db   Reader
+	// dl is the download to install first; nil when reloading from disk
+	dl *download
+	// current is when the data was last known current, such as a file's
+	// modification time, so loading an old file doesn't hide that it's
+	// stale. Zero, as for downloads, means now.
+	current time.Time
+}
+
+// errUpdateAborted is reported for databases that were ready but held back
+// because another database in the set failed.
+var errUpdateAborted = errors.New("update aborted: another database failed")
+
+// loadAll installs the pending downloads and swaps every reader in at once,
+// so lookups see either the previous set or the new one. On failure nothing
+// is swapped and the pending readers are closed.
+func (g *GeoDB) loadAll(pending []pendingDB) error {
+	for i, p := range pending {
+		if p.dl == nil {
+			continue
+		}
+		if err := g.install(p.inst, p.dl); err != nil {
+			discardPending(pending[i+1:])
+			for _, p := range pending[:i+1] {
+				_ = p.db.Close()
+			}
+			return fmt.Errorf("failed to install %s database: %w", p.inst.name, err)
+		}
 	}
-	_ = testDB.Close()
 
-	if err := os.Rename(tmpPath, inst.path); err != nil {
-		_ = os.Remove(tmpPath)
-		return err
+	now := time.Now()
+	old := make([]*dbHandle, 0, len(pending))
+
+	g.swapMu.Lock()
+	g.swapGen.Add(1)
+	for _, p := range pending {
+		old = append(old, p.inst.db.Swap(newDBHandle(p.db)))
+	}
+	g.swapGen.Add(1)
+	g.swapMu.Unlock()
+
+	for _, p := range pending {
+		if p.dl != nil {
+			p.inst.keepValidators(p.dl)
+		}
+		current := now
+		if !p.current.IsZero() && p.current.Before(now) {
+			current = p.current
+		}
+		p.inst.mu.Lock()
+		p.inst.lastUpdated = now
+		p.inst.lastCurrent = current
+		p.inst.mu.Unlock()
 	}
 
-	g.logger.Info(name+" database downloaded", map[string]any{
-		"path": inst.path,
-		"url":  inst.url,
-	})
+	// Old readers close once the lookups still using them finish
+	for _, db := range old {
+		db.release()
+	}
+
+	// Cached results may be stale now that the data changed
+	g.cache.purge()
+	for _, p := range pending {
+		g.metrics.DatabaseUpdated(p.inst.name, now)
+		g.logger.Info(p.inst.name+" database loaded", map[string]any{
+			"path": p.inst.path,
+		})
+	}
 
 	return nil
 }
 
+// discardPending closes readers and removes downloads that won't be swapped in.
+func discardPending(pending []pendingDB) {
+	for _, p := range pending {
+		_ = p.db.Close()
+		if p.dl != nil {
+			p.dl.discard()
+		}
+	}
+}
+
 func (g *GeoDB) updateLoop(ctx context.Context) {
 	defer g.wg.Done()
 
@@ -287,25 +780,162 @@ func (g *GeoDB) updateLoop(ctx context.Context) {
 			return
 		case <-ticker.C:
 			g.logger.Info("starting scheduled database update", nil)
+			g.Refresh(ctx)
+			g.checkStale(time.Now())
+		}
+	}
+}
 
-			dbs := []struct {
-				inst *dbInstance
-				name string
-			}{
-				{g.country, "country"},
-				{g.cityIPv4, "city-ipv4"},
-				{g.cityIPv6, "city-ipv6"},
-			}
+// isStale reports whether inst is loaded but hasn't been confirmed current
+// within the max age. Callers must hold inst.mu.
+func (g *GeoDB) isStale(inst *dbInstance, now time.Time) bool {
+	return g.maxAge > 0 && inst.db.Load() != nil && now.Sub(inst.lastCurrent) > g.maxAge
+}
+
+// checkStale warns about, and reports to metrics, every database whose data
+// is older than the max age, so updates that keep failing don't go unnoticed.
+func (g *GeoDB) checkStale(now time.Time) {
+	if g.maxAge <= 0 {
+		return
+	}
+	for _, inst := range g.instances() {
+		inst.mu.RLock()
+		stale := g.isStale(inst, now)
+		age := now.Sub(inst.lastCurrent)
+		inst.mu.RUnlock()
+
+		g.metrics.DatabaseStale(inst.name, stale)
+		if stale {
+			g.logger.Warn(inst.name+" database is stale", map[string]any{
+				"age":     age.Round(time.Second).String(),
+				"max_age": g.maxAge.String(),
+			})
+		}
+	}
+}
+
+// RefreshStatus reports the outcome of refreshing a single database.
+type RefreshStatus struct {
+	Database string `json:"database"`
+	Updated  bool   `json:"updated"`
+	// Unchanged is set when the server reported no newer version
+	Unchanged bool   `json:"unchanged,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
 
-			for _, d := range dbs {
-				if err := g.downloadDB(d.inst, d.name); err != nil {
-					g.logger.Error(d.name+" database update failed", map[string]any{"error": err.Error()})
-				} else if err := g.loadDB(d.inst, d.name); err != nil {
-					g.logger.Error(d.name+" database reload failed", map[string]any{"error": err.Error()})
-				}
+// Refresh downloads and reloads every database, the same as a scheduled
+// update. In offline mode the files are only reloaded from disk. Databases
+// without a URL are managed externally and left to Reload. Concurrent calls
+// (including the update loop) run one at a time.
+//
+// The databases are replaced as a set: everything is downloaded and
+// validated first, and if any loaded database fails, none are swapped. A
+// database that isn't loaded yet can't be left inconsistent, so its failure
+// doesn't hold back the others.
+func (g *GeoDB) Refresh(ctx context.Context) []RefreshStatus {
+	g.updateMu.Lock()
+	defer g.updateMu.Unlock()
+
+	insts := g.instances()
+	if !g.offline {
+		insts = slices.DeleteFunc(insts, (*dbInstance).external)
+	}
+	statuses := make([]RefreshStatus, len(insts))
+	var pending []pendingDB
+	var pendingIdx []int
+	aborted := false
+	for i, inst := range insts {
+		statuses[i].Database = inst.name
+		p, err := g.stageDB(ctx, inst)
+		switch {
+		case errors.Is(err, errNotModified):
+			statuses[i].Unchanged = true
+			inst.mu.Lock()
+			inst.lastCurrent = time.Now()
+			inst.mu.Unlock()
+		case err != nil:
+			statuses[i].Error = err.Error()
+			aborted = aborted || inst.db.Load() != nil
+		default:
+			pending = append(pending, p)
+			pendingIdx = append(pendingIdx, i)
+		}
+	}
+
+	var err error
+	if aborted {
+		discardPending(pending)
+		err = errUpdateAborted
+	} else if len(pending) > 0 {
+		err = g.loadAll(pending)
+	}
+	for _, i := range pendingIdx {
+		if err != nil {
+			statuses[i].Error = err.Error()
+		} else {
+			statuses[i].Updated = true
+		}
+	}
+	if err != nil {
+		g.logger.Error("database update failed, keeping the current set", map[string]any{"error": err.Error()})
+	}
+
+	g.logger.Info("database update completed", nil)
+	return statuses
+}
+
+// Reload re-opens every database from its existing path without
+// downloading, for files replaced on disk out-of-band. Each database is
+// reloaded on its own; one that fails to open keeps serving its previous
+// data.
+func (g *GeoDB) Reload() []RefreshStatus {
+	g.updateMu.Lock()
+	defer g.updateMu.Unlock()
+
+	var statuses []RefreshStatus
+	for _, inst := range g.instances() {
+		status := RefreshStatus{Database: inst.name}
+		if err := g.loadDB(inst); err != nil {
+			g.logger.Error(inst.name+" database reload failed", map[string]any{"error": err.Error()})
+			status.Error = err.Error()
+		} else {
+			status.Updated = true
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// stageDB downloads (unless offline) and opens a single database without
+// swapping it in. It returns errNotModified when the download was skipped.
+func (g *GeoDB) stageDB(ctx context.Context, inst *dbInstance) (pendingDB, error) {
+	path := inst.path
+	var dl *download
+	if !g.offline {
+		var err error
+		if dl, err = g.fetchDB(ctx, inst); errors.Is(err, errNotModified) {
+			return pendingDB{}, err
+		} else if err != nil {
+			// fetchDB logs when a backoff starts and ends, not every skip
+			if !errors.Is(err, ErrBackingOff) {
+				g.logger.Error(inst.name+" database update failed", map[string]any{"error": err.Error()})
 			}
+			return pendingDB{}, err
+		}
+		path = dl.tmpPath
+	}
 
-			g.logger.Info("database update completed", nil)
+	db, err := openReader(inst.format, path, inst.dbType)
+	if err != nil {
+		if dl != nil {
+			dl.discard()
 		}
+		g.logger.Error(inst.name+" database reload failed", map[string]any{"error": err.Error()})
+		return pendingDB{}, err
+	}
+	p := pendingDB{inst: inst, db: db, dl: dl}
+	if dl == nil {
+		p.current = modTime(path)
 	}
+	return p, nil
 }
@@ -0,0 +1,1216 @@
+package geodb
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dbServer serves test databases by URL path; files can be swapped between
+// requests. Each file gets an ETag so conditional requests see 304s.
+type dbServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newDBServer(t *testing.T) *dbServer {
+	t.Helper()
+
+	s := &dbServer{files: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		data, ok := s.files[r.URL.Path]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(data))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *dbServer) set(path string, data []byte) {
+	s.mu.Lock()
+	s.files[path] = data
+	s.mu.Unlock()
+}
+
+// newServedGeoDB returns a GeoDB whose three databases download from srv.
+func newServedGeoDB(t *testing.T, srv *dbServer) *GeoDB {
+	t.Helper()
+
+	dir := t.TempDir()
+	g := New(Options{
+		CountryPath:  filepath.Join(dir, "country.mmdb"),
+		CountryURL:   srv.URL + "/country.mmdb",
+		CityIPv4Path: filepath.Join(dir, "city-ipv4.mmdb"),
+		CityIPv4URL:  srv.URL + "/city-ipv4.mmdb",
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		CityIPv6URL:  srv.URL + "/city-ipv6.mmdb",
+
+		UpdateInterval: time.Hour,
+	}, testLogger{})
+	t.Cleanup(g.Stop)
+	return g
+}
+
+func countryDB(t *testing.T, code string) []byte {
+	return buildTestDB(t, "Test-Country", 6, map[string]map[string]any{
+		"8.8.8.0/24": {"country_code": code},
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+
+	for _, s := range g.Refresh(context.Background()) {
+		if !s.Updated {
+			t.Fatalf("expected %s to update, got error %q", s.Database, s.Error)
+		}
+	}
+
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "US" {
+		t.Errorf("expected country code 'US', got %q", result.CountryCode)
+	}
+
+	// A new release is picked up by the next refresh
+	srv.set("/country.mmdb", countryDB(t, "CA"))
+	g.Refresh(context.Background())
+
+	result, err = g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "CA" {
+		t.Errorf("expected refreshed country code 'CA', got %q", result.CountryCode)
+	}
+}
+
+func TestRefresh_ReportsFailures(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+
+	g := newServedGeoDB(t, srv)
+
+	statuses := g.Refresh(context.Background())
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 statuses, got %d", len(statuses))
+	}
+
+	if !statuses[0].Updated {
+		t.Errorf("expected country to update, got error %q", statuses[0].Error)
+	}
+	for _, s := range statuses[1:] {
+		if s.Updated || s.Error == "" {
+			t.Errorf("expected %s to report a failure, got %+v", s.Database, s)
+		}
+	}
+}
+
+func TestDatabases(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+
+	g := newServedGeoDB(t, srv)
+
+	if info := g.Databases()["country"]; info.Loaded {
+		t.Fatal("expected country database to be unloaded before refresh")
+	}
+
+	g.Refresh(context.Background())
+	infos := g.Databases()
+
+	country := infos["country"]
+	if !country.Loaded {
+		t.Fatal("expected country database to be loaded")
+	}
+	if country.BuildTime.IsZero() {
+		t.Error("expected build time from MMDB metadata")
+	}
+	if country.LastUpdated.IsZero() {
+		t.Error("expected last updated time to be tracked")
+	}
+
+	if infos["city-ipv4"].Loaded {
+		t.Error("expected city-ipv4 to be unloaded after a failed download")
+	}
+}
+
+func TestStart_DegradedWithoutCity(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+
+	g := newServedGeoDB(t, srv)
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v, want degraded start", err)
+	}
+
+	want := map[string]bool{"country": true, "city-ipv4": false, "city-ipv6": false}
+	ready := g.Ready()
+	for name, loaded := range want {
+		if ready[name] != loaded {
+			t.Errorf("Ready()[%q] = %v, want %v", name, ready[name], loaded)
+		}
+	}
+
+	// City lookups fall back to the country database
+	result, err := g.Lookup("8.8.8.8", LookupOptions{UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "US" {
+		t.Errorf("expected country code 'US', got %q", result.CountryCode)
+	}
+
+	// A miss is still reported as not found rather than an unavailable city DB
+	if _, err := g.Lookup("1.1.1.1", LookupOptions{}); !errors.Is(err, ErrIPNotFound) {
+		t.Errorf("Lookup() error = %v, want ErrIPNotFound", err)
+	}
+
+	// The missing databases are picked up by a later update
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+	g.Refresh(context.Background())
+	for name, loaded := range g.Ready() {
+		if !loaded {
+			t.Errorf("expected %s to be loaded after refresh", name)
+		}
+	}
+}
+
+func TestStart_FailsWithoutCountry(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	if err := g.Start(context.Background()); err == nil {
+		t.Fatal("Start() succeeded without the country database")
+	}
+}
+
+func TestStart_DownloadsConcurrently(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	// Hold every download until all three have been requested, which only
+	// happens if they run at the same time
+	var mu sync.Mutex
+	arrived := 0
+	all := make(chan struct{})
+	barrier := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrived++
+		if arrived == 3 {
+			close(all)
+		}
+		mu.Unlock()
+
+		select {
+		case <-all:
+			srv.Config.Handler.ServeHTTP(w, r)
+		case <-time.After(5 * time.Second):
+			http.Error(w, "downloads were not concurrent", http.StatusServiceUnavailable)
+		}
+	}))
+	defer barrier.Close()
+
+	g := newServedGeoDB(t, &dbServer{Server: barrier})
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	for name, loaded := range g.Ready() {
+		if !loaded {
+			t.Errorf("expected %s to be loaded", name)
+		}
+	}
+}
+
+func TestStart_RefreshWaits(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	// Hold the first country download, and count downloads of it in flight
+	var inFlight, maxInFlight atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	gate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/country.mmdb" {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			if n > maxInFlight.Load() {
+				maxInFlight.Store(n)
+			}
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer gate.Close()
+
+	g := newServedGeoDB(t, &dbServer{Server: gate})
+	startErr := make(chan error, 1)
+	go func() { startErr <- g.Start(context.Background()) }()
+	<-started
+
+	refreshed := make(chan struct{})
+	go func() {
+		g.Refresh(context.Background())
+		close(refreshed)
+	}()
+	select {
+	case <-refreshed:
+		t.Fatal("Refresh() ran while Start was still downloading")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-startErr; err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-refreshed
+	if n := maxInFlight.Load(); n != 1 {
+		t.Errorf("expected one country download at a time, got %d", n)
+	}
+}
+
+func TestLookupCity_Unavailable(t *testing.T) {
+	g := New(Options{}, testLogger{})
+	dbs := g.pin(g.country, g.cityIPv4, nil)
+	if _, err := dbs.lookupCity(netip.MustParseAddr("8.8.8.8")); !errors.Is(err, ErrCityUnavailable) {
+		t.Errorf("lookupCity() error = %v, want ErrCityUnavailable", err)
+	}
+}
+
+func TestStart_Offline(t *testing.T) {
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	if err := os.WriteFile(countryPath, countryDB(t, "US"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Any download attempt is a failure
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected download of %s in offline mode", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	g := New(Options{
+		CountryPath:  countryPath,
+		CountryURL:   srv.URL + "/country.mmdb",
+		CityIPv4Path: filepath.Join(dir, "city-ipv4.mmdb"),
+		CityIPv4URL:  srv.URL + "/city-ipv4.mmdb",
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		CityIPv6URL:  srv.URL + "/city-ipv6.mmdb",
+		Offline:      true,
+	}, testLogger{})
+	defer g.Stop()
+
+	// UpdateInterval is unset, so starting the update loop would panic
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if g.cancel != nil {
+		t.Error("expected no update loop in offline mode")
+	}
+
+	ready := g.Ready()
+	if !ready["country"] || ready["city-ipv4"] || ready["city-ipv6"] {
+		t.Errorf("unexpected readiness: %v", ready)
+	}
+
+	// Refresh reloads from disk without downloading
+	if err := os.WriteFile(countryPath, countryDB(t, "CA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g.Refresh(context.Background())
+
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "CA" {
+		t.Errorf("expected reloaded country code 'CA', got %q", result.CountryCode)
+	}
+}
+
+func TestStart_OfflineMissingCountry(t *testing.T) {
+	dir := t.TempDir()
+	g := New(Options{
+		CountryPath:  filepath.Join(dir, "country.mmdb"),
+		CityIPv4Path: filepath.Join(dir, "city-ipv4.mmdb"),
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		Offline:      true,
+	}, testLogger{})
+	defer g.Stop()
+
+	err := g.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "offline mode") {
+		t.Errorf("Start() error = %v, want offline mode error", err)
+	}
+}
+
+func TestStart_ExternalDB(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+
+	dir := t.TempDir()
+	cityPath := filepath.Join(dir, "city-ipv4.mmdb")
+	if err := os.WriteFile(cityPath, buildTestDB(t, "Test-City", 4, map[string]map[string]any{
+		"203.0.113.0/24": {"country_code": "NL", "city": "Amsterdam"},
+	}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The city databases have no URL, so they are never downloaded
+	g := New(Options{
+		CountryPath:    filepath.Join(dir, "country.mmdb"),
+		CountryURL:     srv.URL + "/country.mmdb",
+		CityIPv4Path:   cityPath,
+		CityIPv6Path:   filepath.Join(dir, "city-ipv6.mmdb"),
+		UpdateInterval: time.Hour,
+	}, testLogger{})
+	t.Cleanup(g.Stop)
+
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	ready := g.Ready()
+	if !ready["country"] || !ready["city-ipv4"] || ready["city-ipv6"] {
+		t.Errorf("unexpected readiness: %v", ready)
+	}
+	result, err := g.Lookup("203.0.113.5", LookupOptions{UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.City != "Amsterdam" {
+		t.Errorf("expected the external city database to be loaded, got %+v", result)
+	}
+
+	// Updates skip them
+	for _, s := range g.Refresh(context.Background()) {
+		if s.Database != "country" {
+			t.Errorf("expected only the country database to be refreshed, got %+v", s)
+		}
+	}
+}
+
+func TestRefresh_Unchanged(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+
+	for _, s := range g.Refresh(context.Background()) {
+		if !s.Updated {
+			t.Fatalf("expected %s to update, got %+v", s.Database, s)
+		}
+	}
+	before := g.Databases()["country"].LastUpdated
+
+	for _, s := range g.Refresh(context.Background()) {
+		if s.Updated || !s.Unchanged || s.Error != "" {
+			t.Errorf("expected %s to be unchanged, got %+v", s.Database, s)
+		}
+	}
+	if after := g.Databases()["country"].LastUpdated; !after.Equal(before) {
+		t.Error("expected unchanged database not to be reloaded")
+	}
+
+	// A new release is still picked up
+	srv.set("/country.mmdb", countryDB(t, "CA"))
+	statuses := g.Refresh(context.Background())
+	if !statuses[0].Updated {
+		t.Errorf("expected changed country database to update, got %+v", statuses[0])
+	}
+}
+
+func TestRefresh_AllOrNothing(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	g.Refresh(context.Background())
+
+	// A new country release alongside a broken city release must not be
+	// swapped in on its own
+	srv.set("/country.mmdb", countryDB(t, "CA"))
+	srv.set("/city-ipv6.mmdb", []byte("not a database"))
+
+	statuses := g.Refresh(context.Background())
+	for _, s := range statuses {
+		if s.Updated {
+			t.Errorf("expected %s not to be swapped in, got %+v", s.Database, s)
+		}
+	}
+	if statuses[0].Error != errUpdateAborted.Error() {
+		t.Errorf("expected country to report the aborted update, got %+v", statuses[0])
+	}
+
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "US" {
+		t.Errorf("expected previous country code 'US', got %q", result.CountryCode)
+	}
+
+	// Held-back downloads are fetched again once the set is consistent
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+	for _, s := range g.Refresh(context.Background()) {
+		if s.Database == "country" && !s.Updated {
+			t.Errorf("expected country to update, got %+v", s)
+		}
+	}
+	result, err = g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "CA" {
+		t.Errorf("expected new country code 'CA', got %q", result.CountryCode)
+	}
+}
+
+// errorSignal reports each error logged, so tests can wait for background
+// failures.
+type errorSignal struct {
+	testLogger
+	errors chan string
+}
+
+func (l errorSignal) Error(msg string, _ map[string]any) {
+	select {
+	case l.errors <- msg:
+	default:
+	}
+}
+
+func TestUpdateLoop_KeepsServingAfterFailedUpdate(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	dir := t.TempDir()
+	log := errorSignal{errors: make(chan string, 16)}
+	g := New(Options{
+		CountryPath:  filepath.Join(dir, "country.mmdb"),
+		CountryURL:   srv.URL + "/country.mmdb",
+		CityIPv4Path: filepath.Join(dir, "city-ipv4.mmdb"),
+		CityIPv4URL:  srv.URL + "/city-ipv4.mmdb",
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		CityIPv6URL:  srv.URL + "/city-ipv6.mmdb",
+
+		UpdateInterval: 10 * time.Millisecond,
+	}, log)
+	t.Cleanup(g.Stop)
+	if err := g.Start(t.Context()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	loaded := g.country.db.Load()
+
+	// The CDN starts serving a corrupt file
+	srv.set("/country.mmdb", []byte("not a database"))
+	timeout := time.After(5 * time.Second)
+	for msg := ""; msg != "database update failed, keeping the current set"; {
+		select {
+		case msg = <-log.errors:
+		case <-timeout:
+			t.Fatal("timed out waiting for a scheduled update to fail")
+		}
+	}
+
+	// The previous reader is still in place and open
+	if h := g.country.db.Load(); h != loaded || h.refs.Load() < 1 {
+		t.Fatalf("expected the previous country reader to stay loaded, got %p (was %p)", h, loaded)
+	}
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "US" {
+		t.Errorf("expected previous country code 'US', got %q", result.CountryCode)
+	}
+
+	// The file on disk isn't replaced either, so a restart or SIGHUP reload
+	// still finds a valid database
+	db, err := openMMDB(filepath.Join(dir, "country.mmdb"), "Country")
+	if err != nil {
+		t.Fatalf("expected the country file on disk to stay valid, got %v", err)
+	}
+	db.Close()
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	if err := os.WriteFile(countryPath, countryDB(t, "US"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(Options{
+		CountryPath:  countryPath,
+		CityIPv4Path: filepath.Join(dir, "city-ipv4.mmdb"),
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		Offline:      true,
+	}, testLogger{})
+	defer g.Stop()
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := os.WriteFile(countryPath, countryDB(t, "CA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	statuses := g.Reload()
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 statuses, got %d", len(statuses))
+	}
+	if !statuses[0].Updated {
+		t.Errorf("expected country to reload, got %+v", statuses[0])
+	}
+	// Missing files are reported without affecting the others
+	for _, s := range statuses[1:] {
+		if s.Updated || s.Error == "" {
+			t.Errorf("expected %s to report a failure, got %+v", s.Database, s)
+		}
+	}
+
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "CA" {
+		t.Errorf("expected reloaded country code 'CA', got %q", result.CountryCode)
+	}
+}
+
+// trackingReader fails the test if it is closed while a lookup is using it,
+// or used after it was closed.
+type trackingReader struct {
+	Reader
+	t        *testing.T
+	inFlight atomic.Int32
+	closed   atomic.Bool
+}
+
+func (r *trackingReader) Lookup(ip netip.Addr) (*LookupResult, error) {
+	r.inFlight.Add(1)
+	defer r.inFlight.Add(-1)
+	if r.closed.Load() {
+		r.t.Error("lookup on a closed reader")
+	}
+	return r.Reader.Lookup(ip)
+}
+
+func (r *trackingReader) Close() error {
+	r.closed.Store(true)
+	if n := r.inFlight.Load(); n != 0 {
+		r.t.Errorf("reader closed with %d lookups in flight", n)
+	}
+	return r.Reader.Close()
+}
+
+// TestLoadAll_ConcurrentLookups swaps databases over and over while lookups
+// run, checking that no lookup fails or sees a closed reader and that every
+// replaced reader gets closed. Run it with -race.
+func TestLoadAll_ConcurrentLookups(t *testing.T) {
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	cityPath := filepath.Join(dir, "city-ipv4.mmdb")
+	if err := os.WriteFile(countryPath, countryDB(t, "US"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cityPath, buildTestDB(t, "Test-City", 4, map[string]map[string]any{
+		"8.8.8.0/24": {"country_code": "US", "city": "Mountain View"},
+	}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(Options{
+		CountryPath:  countryPath,
+		CityIPv4Path: cityPath,
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		Offline:      true,
+	}, testLogger{})
+
+	var readers []*trackingReader
+	open := func(inst *dbInstance) pendingDB {
+		r, err := openReader(inst.format, inst.path, inst.dbType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := &trackingReader{Reader: r, t: t}
+		readers = append(readers, tr)
+		return pendingDB{inst: inst, db: tr}
+	}
+	swap := func() {
+		if err := g.loadAll([]pendingDB{open(g.country), open(g.cityIPv4)}); err != nil {
+			t.Fatalf("loadAll() error = %v", err)
+		}
+	}
+	swap()
+
+	stop := make(chan struct{})
+	var lookups atomic.Int64
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			opts := LookupOptions{UseCity: i%2 == 0}
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				result, err := g.Lookup("8.8.8.8", opts)
+				if err != nil {
+					t.Errorf("Lookup() error = %v", err)
+					return
+				}
+				if result.CountryCode != "US" {
+					t.Errorf("expected country code 'US', got %q", result.CountryCode)
+					return
+				}
+				lookups.Add(1)
+			}
+		})
+	}
+
+	// Swap only once lookups are under way, and keep going until plenty
+	// of them overlapped the swaps
+	for lookups.Load() == 0 {
+		runtime.Gosched()
+	}
+	for i := 0; i < 200 || lookups.Load() < 10000; i++ {
+		swap()
+	}
+	close(stop)
+	wg.Wait()
+	g.Stop()
+
+	for i, r := range readers {
+		if !r.closed.Load() {
+			t.Errorf("reader %d was never closed", i)
+		}
+	}
+}
+
+// TestLoadAll_FailedInstallKeepsValidators checks that a set that never gets
+// swapped in leaves the previous validators, so the next refresh downloads
+// the new release again instead of getting a 304.
+func TestLoadAll_FailedInstallKeepsValidators(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	g.Refresh(context.Background())
+	before := g.country.etag
+
+	srv.set("/country.mmdb", countryDB(t, "CA"))
+	dl, err := g.fetchDB(context.Background(), g.country)
+	if err != nil {
+		t.Fatalf("fetchDB() error = %v", err)
+	}
+	db, err := openReader(g.country.format, dl.tmpPath, g.country.dbType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := &download{tmpPath: filepath.Join(t.TempDir(), "missing.mmdb"), etag: `"missing"`}
+	cityDB, err := openReader(g.cityIPv4.format, g.cityIPv4.path, g.cityIPv4.dbType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = g.loadAll([]pendingDB{
+		{inst: g.country, db: db, dl: dl},
+		{inst: g.cityIPv4, db: cityDB, dl: missing},
+	})
+	if err == nil {
+		t.Fatal("expected loadAll() to fail")
+	}
+	if g.country.etag != before {
+		t.Errorf("expected country ETag to stay %q, got %q", before, g.country.etag)
+	}
+
+	statuses := g.Refresh(context.Background())
+	if !statuses[0].Updated {
+		t.Errorf("expected country to be downloaded again, got %+v", statuses[0])
+	}
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "CA" {
+		t.Errorf("expected new country code 'CA', got %q", result.CountryCode)
+	}
+}
+
+func TestLookup_IPv4Mapped(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, map[string]map[string]any{
+		"203.0.113.0/24": {"country_code": "NL", "city": "Amsterdam"},
+	}))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	g.Refresh(context.Background())
+
+	result, err := g.Lookup("::ffff:203.0.113.5", LookupOptions{UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.City != "Amsterdam" {
+		t.Errorf("expected mapped address to resolve via the IPv4 city database, got %+v", result)
+	}
+	if result.IPVersion != "v4" {
+		t.Errorf("expected mapped address to report ip_version v4, got %q", result.IPVersion)
+	}
+	if result.Network != "203.0.113.0/24" {
+		t.Errorf("expected mapped address to report its IPv4 network, got %q", result.Network)
+	}
+}
+
+// TestLookup_Region also covers the accuracy radius, another field only some
+// city database builds carry.
+func TestLookup_Region(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, map[string]map[string]any{
+		"203.0.113.0/24":  {"country_code": "US", "state1": "California", "city": "Mountain View", "accuracy_radius": uint16(20)},
+		"198.51.100.0/24": {"country_code": "US", "city": "Springfield"},
+	}))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	g.Refresh(context.Background())
+
+	result, err := g.Lookup("203.0.113.5", LookupOptions{UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.Region != "California" {
+		t.Errorf("expected region California, got %q", result.Region)
+	}
+	if result.AccuracyRadius != 20 {
+		t.Errorf("expected accuracy radius 20, got %d", result.AccuracyRadius)
+	}
+
+	// Records without the field decode with an empty region
+	result, err = g.Lookup("198.51.100.5", LookupOptions{UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.Region != "" || result.AccuracyRadius != 0 || result.City != "Springfield" {
+		t.Errorf("unexpected result for record without region: %+v", result)
+	}
+}
+
+func TestLookup_Strict(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, map[string]map[string]any{
+		"203.0.113.0/24": {"country_code": "NL", "city": "Amsterdam"},
+	}))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	g.Refresh(context.Background())
+
+	tests := []struct {
+		name    string
+		ip      string
+		useCity bool
+		strict  bool
+		want    string
+	}{
+		{"city falls back to country", "8.8.8.8", true, false, "US"},
+		{"country falls back to city", "203.0.113.5", false, false, "NL"},
+		{"strict city", "8.8.8.8", true, true, ""},
+		{"strict country", "203.0.113.5", false, true, ""},
+		{"strict city hit", "203.0.113.5", true, true, "NL"},
+		{"strict country hit", "8.8.8.8", false, true, "US"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := g.Lookup(tt.ip, LookupOptions{UseCity: tt.useCity, Strict: tt.strict})
+			if tt.want == "" {
+				if !errors.Is(err, ErrIPNotFound) {
+					t.Errorf("Lookup() = %+v, %v, want ErrIPNotFound", result, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if result.CountryCode != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, result.CountryCode)
+			}
+		})
+	}
+
+	// A strict city lookup without a city database finds nothing
+	dbs := g.pin(g.country, nil, nil)
+	defer dbs.release()
+	_, err := dbs.lookupLocation(netip.MustParseAddr("8.8.8.8"), LookupOptions{UseCity: true, Strict: true})
+	if !errors.Is(err, ErrIPNotFound) {
+		t.Errorf("lookupLocation() error = %v, want ErrIPNotFound", err)
+	}
+}
+
+func TestLookup_Full(t *testing.T) {
+	g := New(Options{ASNPath: "asn.mmdb"}, testLogger{})
+	for inst, path := range map[*dbInstance]string{
+		g.country: writeTestDB(t, "Test-Country", 6, map[string]map[string]any{
+			"8.8.8.0/24":     {"country_code": "US"},
+			"203.0.113.0/24": {"country_code": "NL"},
+		}),
+		g.cityIPv4: writeTestDB(t, "Test-City", 4, map[string]map[string]any{
+			"203.0.113.0/24": {"country_code": "NL", "city": "Amsterdam", "postcode": "1012"},
+		}),
+		g.asn: writeTestDB(t, "Test-ASN", 4, map[string]map[string]any{
+			"203.0.113.0/24": {"autonomous_system_number": uint32(64496), "autonomous_system_organization": "Example"},
+		}),
+	} {
+		r, err := openMMDB(path, inst.dbType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inst.db.Store(newDBHandle(r))
+	}
+
+	// Strict is overridden, so country and city both answer
+	result, err := g.Lookup("203.0.113.5", LookupOptions{Full: true, Strict: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "NL" || result.City != "Amsterdam" || result.PostalCode != "1012" {
+		t.Errorf("expected the city record, got %+v", result)
+	}
+	if result.ASN != 64496 || result.ASOrg != "Example" {
+		t.Errorf("expected ASN data to be merged in, got %+v", result)
+	}
+
+	// Databases without a record are left out
+	result, err = g.Lookup("8.8.8.8", LookupOptions{Full: true, Strict: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "US" || result.City != "" || result.ASN != 0 {
+		t.Errorf("expected a country-only result, got %+v", result)
+	}
+}
+
+// lateCancelCtx reports cancellation from its second Err check on, as if
+// the request was cancelled while the lookup was under way.
+type lateCancelCtx struct {
+	context.Context
+	checks atomic.Int32
+}
+
+func (c *lateCancelCtx) Err() error {
+	if c.checks.Add(1) > 1 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestLookupCtx_CanceledBeforeASN(t *testing.T) {
+	g := New(Options{ASNPath: "asn.mmdb", CacheSize: 10}, testLogger{})
+	for inst, path := range map[*dbInstance]string{
+		g.country: writeTestDB(t, "Test-Country", 6, map[string]map[string]any{
+			"8.8.8.0/24": {"country_code": "US"},
+		}),
+		g.asn: writeTestDB(t, "Test-ASN", 4, map[string]map[string]any{
+			"8.8.8.0/24": {"autonomous_system_number": uint32(15169), "autonomous_system_organization": "Google"},
+		}),
+	} {
+		r, err := openMMDB(path, inst.dbType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inst.db.Store(newDBHandle(r))
+	}
+
+	ctx := &lateCancelCtx{Context: context.Background()}
+	if _, err := g.LookupCtx(ctx, "8.8.8.8", LookupOptions{ASN: true}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LookupCtx() error = %v, want context.Canceled", err)
+	}
+
+	// The result missing its ASN must not have been cached
+	result, err := g.Lookup("8.8.8.8", LookupOptions{ASN: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.ASN != 15169 {
+		t.Errorf("expected ASN 15169, got %+v", result)
+	}
+}
+
+func TestLoadFromBytes(t *testing.T) {
+	g := New(Options{}, testLogger{})
+	t.Cleanup(func() { g.country.db.Load().release() })
+
+	if err := g.LoadFromBytes("country", countryDB(t, "US")); err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "US" {
+		t.Errorf("expected country code 'US', got %q", result.CountryCode)
+	}
+	if !g.Ready()["country"] {
+		t.Error("expected the country database to be ready")
+	}
+
+	// Like downloads, the data must be the expected kind of database
+	err = g.LoadFromBytes("country", buildTestDB(t, "Test-City", 4, nil))
+	if !errors.Is(err, ErrWrongDatabaseType) {
+		t.Errorf("LoadFromBytes() error = %v, want ErrWrongDatabaseType", err)
+	}
+	if err := g.LoadFromBytes("country", []byte("not a database")); err == nil {
+		t.Error("expected invalid data to be rejected")
+	}
+	if err := g.LoadFromBytes("region", countryDB(t, "US")); err == nil {
+		t.Error("expected an unknown database name to be rejected")
+	}
+	// Failures leave the loaded database in place
+	if result, err := g.Lookup("8.8.8.8", LookupOptions{}); err != nil || result.CountryCode != "US" {
+		t.Errorf("Lookup() = %+v, %v after failed loads", result, err)
+	}
+
+	g = New(Options{Format: FormatIP2Location}, testLogger{})
+	if err := g.LoadFromBytes("country", countryDB(t, "US")); err == nil {
+		t.Error("expected IP2Location databases not to load from memory")
+	}
+}
+
+func TestLoadFromBytes_NextRefreshDownloads(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	g.Refresh(context.Background())
+
+	if err := g.LoadFromBytes("country", countryDB(t, "CA")); err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+	if result, err := g.Lookup("8.8.8.8", LookupOptions{}); err != nil || result.CountryCode != "CA" {
+		t.Fatalf("Lookup() = %+v, %v, want the database from memory", result, err)
+	}
+
+	// The server's copy hasn't changed, but it isn't what's served either
+	g.Refresh(context.Background())
+	if result, err := g.Lookup("8.8.8.8", LookupOptions{}); err != nil || result.CountryCode != "US" {
+		t.Errorf("Lookup() = %+v, %v, want the downloaded database back", result, err)
+	}
+}
+
+// staleMetrics records DatabaseStale reports.
+type staleMetrics struct {
+	nopMetrics
+	stale map[string]bool
+}
+
+func (m *staleMetrics) DatabaseStale(name string, stale bool) {
+	m.stale[name] = stale
+}
+
+func TestStaleness(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	m := &staleMetrics{stale: make(map[string]bool)}
+	g.metrics = m
+	g.maxAge = time.Hour
+	g.Refresh(context.Background())
+
+	if g.Databases()["country"].Stale {
+		t.Fatal("expected freshly loaded database not to be stale")
+	}
+
+	// Updates keep failing while the data ages past the max age
+	later := time.Now().Add(2 * time.Hour)
+	g.checkStale(later)
+	for _, name := range []string{"country", "city-ipv4", "city-ipv6"} {
+		if !m.stale[name] {
+			t.Errorf("expected %s to be reported stale", name)
+		}
+	}
+
+	// A server confirming the data is unchanged makes it current again
+	for _, s := range g.Refresh(context.Background()) {
+		if !s.Unchanged {
+			t.Fatalf("expected %s to be unchanged, got %+v", s.Database, s)
+		}
+	}
+	g.checkStale(time.Now())
+	if m.stale["country"] {
+		t.Error("expected unchanged database to no longer be stale")
+	}
+}
+
+func TestStaleness_OldFileAtStartup(t *testing.T) {
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	if err := os.WriteFile(countryPath, countryDB(t, "US"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(countryPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(Options{
+		CountryPath:  countryPath,
+		CityIPv4Path: filepath.Join(dir, "city-ipv4.mmdb"),
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		Offline:      true,
+		MaxAge:       24 * time.Hour,
+	}, testLogger{})
+	m := &staleMetrics{stale: make(map[string]bool)}
+	g.metrics = m
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer g.Stop()
+
+	if !m.stale["country"] {
+		t.Error("expected a database file older than the max age to be reported stale at startup")
+	}
+	if !g.Databases()["country"].Stale {
+		t.Error("expected country database to be stale")
+	}
+}
+
+func TestVersions(t *testing.T) {
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", countryDB(t, "US"))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+
+	g := newServedGeoDB(t, srv)
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	versions := g.Versions()
+	if len(versions) != 2 {
+		t.Fatalf("expected only the 2 loaded databases, got %+v", versions)
+	}
+
+	country := versions[0]
+	if country.Name != "country" || country.DatabaseType != "Test-Country" || country.IPVersion != 6 {
+		t.Errorf("unexpected country version: %+v", country)
+	}
+	if country.URL != srv.URL+"/country.mmdb" || country.NodeCount == 0 || country.BuildEpoch == 0 {
+		t.Errorf("unexpected country version: %+v", country)
+	}
+	if city := versions[1]; city.Name != "city-ipv4" || city.IPVersion != 4 {
+		t.Errorf("unexpected city version: %+v", city)
+	}
+}
+
+// BenchmarkLookup_Fallback measures lookups that miss their first database
+// and fall back to the other, with the result cache disabled.
+func BenchmarkLookup_Fallback(b *testing.B) {
+	dir := b.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	cityPath := filepath.Join(dir, "city-ipv4.mmdb")
+	files := map[string][]byte{
+		countryPath: buildTestDB(b, "Test-Country", 6, map[string]map[string]any{
+			"8.8.8.0/24": {"country_code": "US"},
+		}),
+		cityPath: buildTestDB(b, "Test-City", 4, map[string]map[string]any{
+			"203.0.113.0/24": {"country_code": "NL", "city": "Amsterdam"},
+		}),
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	g := New(Options{
+		CountryPath:  countryPath,
+		CityIPv4Path: cityPath,
+		CityIPv6Path: filepath.Join(dir, "missing.mmdb"),
+		Offline:      true,
+	}, testLogger{})
+	if err := g.Start(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	defer g.Stop()
+
+	benchmarks := []struct {
+		name string
+		ip   string
+		opts LookupOptions
+	}{
+		// City miss, answered by the country database
+		{"city to country", "8.8.8.8", LookupOptions{UseCity: true}},
+		// Country miss, answered by the city database
+		{"country to city", "203.0.113.5", LookupOptions{}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			if _, err := g.Lookup(bm.ip, bm.opts); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				_, _ = g.Lookup(bm.ip, bm.opts)
+			}
+		})
+	}
+}
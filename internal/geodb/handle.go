@@ -0,0 +1,97 @@
+package geodb
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// dbHandle is a loaded reader with a count of its users. The dbInstance
+// holding it counts as one; each lookup pinning it counts as another. A
+// replaced reader is closed when the last user lets go, so lookups never
+// block on a swap and never see a closed reader.
+type dbHandle struct {
+	Reader
+	refs atomic.Int64
+}
+
+func newDBHandle(r Reader) *dbHandle {
+	h := &dbHandle{Reader: r}
+	h.refs.Store(1)
+	return h
+}
+
+// acquire adds a user, failing once the handle has been released for good.
+func (h *dbHandle) acquire() bool {
+	for {
+		n := h.refs.Load()
+		if n == 0 {
+			return false
+		}
+		if h.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// release drops a user, closing the reader after the last one.
+func (h *dbHandle) release() {
+	if h != nil && h.refs.Add(-1) == 0 {
+		_ = h.Reader.Close()
+	}
+}
+
+// acquire pins the instance's current reader; nil when none is loaded.
+// Callers must release the handle.
+func (inst *dbInstance) acquire() *dbHandle {
+	for {
+		h := inst.db.Load()
+		if h == nil || h.acquire() {
+			return h
+		}
+		// Replaced and closed since the load; the new one is in place
+	}
+}
+
+// dbSet is the readers a single lookup uses, pinned together so they come
+// from the same generation. Any of them may be nil.
+type dbSet struct {
+	country, city, asn *dbHandle
+	// fallback is told about lookups that fall back from one location
+	// database to the other; nil ignores them
+	fallback func(primary, fallback string, err error)
+}
+
+// pin acquires the readers of the given instances (any may be nil) as one
+// set. swapGen is odd while loadAll is swapping readers, and changes with
+// every swap; a set pinned across a change is dropped and pinned again.
+func (g *GeoDB) pin(country, city, asn *dbInstance) dbSet {
+	for {
+		gen := g.swapGen.Load()
+		if gen%2 == 0 {
+			set := dbSet{
+				country: pinInstance(country),
+				city:    pinInstance(city),
+				asn:     pinInstance(asn),
+			}
+			if g.swapGen.Load() == gen {
+				return set
+			}
+			set.release()
+		}
+		// A swap only stores a few pointers
+		runtime.Gosched()
+	}
+}
+
+func pinInstance(inst *dbInstance) *dbHandle {
+	if inst == nil {
+		return nil
+	}
+	return inst.acquire()
+}
+
+func (s dbSet) release() {
+	s.country.release()
+	s.city.release()
+	s.asn.release()
+}
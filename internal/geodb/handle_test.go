@@ -0,0 +1,95 @@
+package geodb
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// closeCounter counts Close calls on the reader it wraps.
+type closeCounter struct {
+	Reader
+	closed int
+}
+
+func (r *closeCounter) Close() error {
+	r.closed++
+	return r.Reader.Close()
+}
+
+func TestDBHandle_ClosesAfterLastUser(t *testing.T) {
+	r, err := openMMDB(writeTestDB(t, "Test-Country", 4, nil), "Country")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &closeCounter{Reader: r}
+	h := newDBHandle(counter)
+
+	if !h.acquire() {
+		t.Fatal("expected a live handle to be acquired")
+	}
+	// The owner lets go while a lookup still holds it
+	h.release()
+	if counter.closed != 0 {
+		t.Fatal("reader closed while still in use")
+	}
+	h.release()
+	if counter.closed != 1 {
+		t.Fatalf("expected the reader to be closed once, got %d", counter.closed)
+	}
+	if h.acquire() {
+		t.Error("expected a released handle not to be acquired")
+	}
+}
+
+// BenchmarkPin compares pinning a reader for a lookup with the RWMutexes
+// used before, under parallel load. The rwmutex case replays the old
+// scheme: a GeoDB-wide read lock across the lookup plus a per-instance read
+// lock to load the pointer.
+func BenchmarkPin(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "country.mmdb")
+	data := buildTestDB(b, "Test-Country", 4, map[string]map[string]any{
+		"8.8.8.0/24": {"country_code": "US"},
+	})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		b.Fatal(err)
+	}
+	r, err := openMMDB(path, "Country")
+	if err != nil {
+		b.Fatal(err)
+	}
+	ip := netip.MustParseAddr("8.8.8.8")
+
+	b.Run("rwmutex", func(b *testing.B) {
+		var swapMu, instMu sync.RWMutex
+		var db Reader = r
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				swapMu.RLock()
+				instMu.RLock()
+				d := db
+				instMu.RUnlock()
+				_, _ = d.Lookup(ip)
+				swapMu.RUnlock()
+			}
+		})
+	})
+
+	b.Run("atomic", func(b *testing.B) {
+		g := New(Options{}, testLogger{})
+		g.country.db.Store(newDBHandle(r))
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				dbs := g.pin(g.country, nil, nil)
+				_, _ = dbs.country.Lookup(ip)
+				dbs.release()
+			}
+		})
+	})
+
+	_ = r.Close()
+}
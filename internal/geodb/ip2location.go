@@ -0,0 +1,361 @@
+package geodb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// errInvalidIP2Location is returned for files that aren't IP2Location BIN
+// databases, or are truncated.
+var errInvalidIP2Location = errors.New("invalid IP2Location database")
+
+// ip2lHeaderSize is the size of the BIN header; the first section follows it.
+const ip2lHeaderSize = 64
+
+// Column positions of each field by IP2Location product (DB1 to DB26),
+// indexed by product number. Column 1 is ip_from; 0 means the product
+// doesn't carry the field.
+var (
+	ip2lCountryPos   = [27]uint8{0, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+	ip2lRegionPos    = [27]uint8{0, 0, 0, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3}
+	ip2lCityPos      = [27]uint8{0, 0, 0, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4}
+	ip2lLatitudePos  = [27]uint8{0, 0, 0, 0, 0, 5, 5, 0, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5}
+	ip2lLongitudePos = [27]uint8{0, 0, 0, 0, 0, 6, 6, 0, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6}
+	ip2lZipCodePos   = [27]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 7, 7, 7, 7, 0, 7, 7, 7, 0, 7, 0, 7, 7, 7, 0, 7, 7, 7}
+)
+
+// ip2lNum is an address as the 128-bit integer the BIN format compares.
+// IPv4 addresses only use lo.
+type ip2lNum struct{ hi, lo uint64 }
+
+func ip2lNumOf(ip netip.Addr) ip2lNum {
+	if ip.Is4() {
+		b := ip.As4()
+		return ip2lNum{lo: uint64(binary.BigEndian.Uint32(b[:]))}
+	}
+	b := ip.As16()
+	return ip2lNum{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+func (n ip2lNum) less(m ip2lNum) bool {
+	return n.hi < m.hi || (n.hi == m.hi && n.lo < m.lo)
+}
+
+// ip2lSection is the IPv4 or IPv6 half of a BIN file: count rows sorted by
+// ip_from, each range running up to the next row's ip_from.
+type ip2lSection struct {
+	count uint32
+	// base and index are 1-based file offsets; index is 0 without an index
+	base    uint32
+	index   uint32
+	ipSize  uint32
+	rowSize uint32
+	// max is the last address in the family
+	max ip2lNum
+}
+
+// ip2lReader reads IP2Location BIN files. Rows are read from disk on
+// demand; os.File.ReadAt is safe for concurrent use.
+type ip2lReader struct {
+	f       *os.File
+	dbType  string
+	product uint8
+	meta    Metadata
+	v4, v6  ip2lSection
+}
+
+func openIP2Location(path, dbType string) (*ip2lReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newIP2LReader(f, dbType)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func newIP2LReader(f *os.File, dbType string) (*ip2lReader, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var h [ip2lHeaderSize]byte
+	if _, err := f.ReadAt(h[:], 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidIP2Location, err)
+	}
+
+	product, columns := h[0], uint32(h[1])
+	if product == 0 || int(product) >= len(ip2lCountryPos) || columns < 2 {
+		return nil, fmt.Errorf("%w: unknown product DB%d with %d columns", errInvalidIP2Location, product, columns)
+	}
+	// Newer files carry a product code, 1 for IP2Location; older ones leave it 0
+	if code := h[29]; code != 0 && code != 1 {
+		return nil, fmt.Errorf("%w: product code %d", errInvalidIP2Location, code)
+	}
+
+	r := &ip2lReader{
+		f:       f,
+		dbType:  dbType,
+		product: product,
+		v4: ip2lSection{
+			count:   binary.LittleEndian.Uint32(h[5:]),
+			base:    binary.LittleEndian.Uint32(h[9:]),
+			index:   binary.LittleEndian.Uint32(h[21:]),
+			ipSize:  4,
+			rowSize: columns * 4,
+			max:     ip2lNum{lo: math.MaxUint32},
+		},
+		v6: ip2lSection{
+			count:   binary.LittleEndian.Uint32(h[13:]),
+			base:    binary.LittleEndian.Uint32(h[17:]),
+			index:   binary.LittleEndian.Uint32(h[25:]),
+			ipSize:  16,
+			rowSize: 16 + (columns-1)*4,
+			max:     ip2lNum{hi: math.MaxUint64, lo: math.MaxUint64},
+		},
+	}
+	if r.v4.count == 0 {
+		return nil, fmt.Errorf("%w: no IPv4 data", errInvalidIP2Location)
+	}
+	for _, s := range []ip2lSection{r.v4, r.v6} {
+		if s.count == 0 {
+			continue
+		}
+		if s.base == 0 || int64(s.base-1)+int64(s.count)*int64(s.rowSize) > info.Size() {
+			return nil, fmt.Errorf("%w: truncated file", errInvalidIP2Location)
+		}
+	}
+
+	r.meta = Metadata{
+		DatabaseType: r.databaseType(),
+		BuildTime:    time.Date(2000+int(h[2]), time.Month(h[3]), int(h[4]), 0, 0, 0, 0, time.UTC),
+		IPVersion:    4,
+	}
+	if r.v6.count > 0 {
+		r.meta.IPVersion = 6
+	}
+	return r, nil
+}
+
+// databaseType names the product along with the kinds of lookup it can
+// serve, so the download type check works as it does for MMDB files.
+func (r *ip2lReader) databaseType() string {
+	name := fmt.Sprintf("IP2Location-DB%d-Country", r.product)
+	if ip2lCityPos[r.product] != 0 {
+		name += "-City"
+	}
+	return name
+}
+
+func (r *ip2lReader) section(ip netip.Addr) *ip2lSection {
+	if ip.Is4() {
+		return &r.v4
+	}
+	return &r.v6
+}
+
+func (r *ip2lReader) Lookup(ip netip.Addr) (*LookupResult, error) {
+	s := r.section(ip)
+	n := ip2lNumOf(ip)
+	// The last address is where the final row ends, not a row of its own
+	if n == s.max {
+		n.lo--
+	}
+	row, ok, err := r.find(s, n)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrIPNotFound
+	}
+	return r.record(s, row)
+}
+
+// find returns the row whose range contains n; ok is false when n comes
+// before the first row or the section is empty.
+func (r *ip2lReader) find(s *ip2lSection, n ip2lNum) (row uint32, ok bool, err error) {
+	if s.count == 0 {
+		return 0, false, nil
+	}
+	lo, hi := uint32(0), s.count-1
+	if s.index != 0 {
+		// The index narrows the search to the rows sharing the top 16 bits
+		block := n.lo >> 16
+		if s.ipSize == 16 {
+			block = n.hi >> 48
+		}
+		var e [8]byte
+		if err := r.readAt(e[:], int64(s.index-1)+int64(block)*8); err != nil {
+			return 0, false, err
+		}
+		lo, hi = binary.LittleEndian.Uint32(e[:]), min(binary.LittleEndian.Uint32(e[4:]), s.count-1)
+	}
+
+	for lo <= hi {
+		mid := lo + (hi-lo)/2
+		from, err := r.ipFrom(s, mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if n.less(from) {
+			if mid == 0 {
+				break
+			}
+			hi = mid - 1
+		} else {
+			row, ok = mid, true
+			lo = mid + 1
+		}
+	}
+	return row, ok, nil
+}
+
+func (r *ip2lReader) rowOffset(s *ip2lSection, row uint32) int64 {
+	return int64(s.base-1) + int64(row)*int64(s.rowSize)
+}
+
+func (r *ip2lReader) ipFrom(s *ip2lSection, row uint32) (ip2lNum, error) {
+	var b [16]byte
+	if err := r.readAt(b[:s.ipSize], r.rowOffset(s, row)); err != nil {
+		return ip2lNum{}, err
+	}
+	if s.ipSize == 4 {
+		return ip2lNum{lo: uint64(binary.LittleEndian.Uint32(b[:]))}, nil
+	}
+	return ip2lNum{hi: binary.LittleEndian.Uint64(b[8:]), lo: binary.LittleEndian.Uint64(b[:8])}, nil
+}
+
+// record decodes the fields of a row that the database type asks for.
+func (r *ip2lReader) record(s *ip2lSection, row uint32) (*LookupResult, error) {
+	buf := make([]byte, s.rowSize)
+	if err := r.readAt(buf, r.rowOffset(s, row)); err != nil {
+		return nil, err
+	}
+	field := func(pos uint8) uint32 {
+		return binary.LittleEndian.Uint32(buf[s.ipSize+uint32(pos-2)*4:])
+	}
+	str := func(pos uint8) (string, error) {
+		if pos == 0 {
+			return "", nil
+		}
+		v, err := r.readString(field(pos))
+		if v == "-" {
+			v = ""
+		}
+		return v, err
+	}
+
+	country, err := str(ip2lCountryPos[r.product])
+	if err != nil {
+		return nil, err
+	}
+	if country == "" {
+		return nil, ErrIPNotFound
+	}
+	result := &LookupResult{CountryCode: country}
+	if r.dbType != "City" {
+		return result, nil
+	}
+
+	if result.Region, err = str(ip2lRegionPos[r.product]); err != nil {
+		return nil, err
+	}
+	if result.City, err = str(ip2lCityPos[r.product]); err != nil {
+		return nil, err
+	}
+	if result.PostalCode, err = str(ip2lZipCodePos[r.product]); err != nil {
+		return nil, err
+	}
+	if pos := ip2lLatitudePos[r.product]; pos != 0 {
+		lat := float64(math.Float32frombits(field(pos)))
+		lon := float64(math.Float32frombits(field(ip2lLongitudePos[r.product])))
+		result.Latitude, result.Longitude = &lat, &lon
+	}
+	return result, nil
+}
+
+// readString reads a length-prefixed string at a 0-based file offset.
+func (r *ip2lReader) readString(offset uint32) (string, error) {
+	var n [1]byte
+	if err := r.readAt(n[:], int64(offset)); err != nil {
+		return "", err
+	}
+	b := make([]byte, n[0])
+	if err := r.readAt(b, int64(offset)+1); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *ip2lReader) readAt(b []byte, offset int64) error {
+	if _, err := r.f.ReadAt(b, offset); err != nil {
+		return fmt.Errorf("lookup failed: %w: %v", errInvalidIP2Location, err)
+	}
+	return nil
+}
+
+func (r *ip2lReader) Metadata() Metadata {
+	return r.meta
+}
+
+func (r *ip2lReader) Close() error {
+	return r.f.Close()
+}
+
+// countriesWithin counts the rows overlapping prefix, each row being one
+// network.
+func (r *ip2lReader) countriesWithin(ctx context.Context, prefix netip.Prefix) (map[string]int, error) {
+	s := r.section(prefix.Addr())
+	first := ip2lNumOf(prefix.Addr())
+	last := ip2lNumOf(lastAddr(prefix))
+
+	row, ok, err := r.find(s, first)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		row = 0
+	}
+
+	counts := make(map[string]int)
+	for ; row < s.count; row++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		from, err := r.ipFrom(s, row)
+		if err != nil {
+			return nil, err
+		}
+		if last.less(from) {
+			break
+		}
+		result, err := r.record(s, row)
+		if errors.Is(err, ErrIPNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		counts[result.CountryCode]++
+	}
+	return counts, nil
+}
+
+// lastAddr returns the highest address in a masked prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - uint(i%8))
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
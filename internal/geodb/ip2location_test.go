@@ -0,0 +1,318 @@
+package geodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// This file contains a minimal IP2Location BIN writer, the counterpart of
+// the MMDB writer in testdb_test.go. It lays out the header, the strings,
+// the IPv4 and IPv6 rows and, optionally, the IPv4 index.
+
+// ip2lTestRow is one range of a test BIN file, starting at from and running
+// up to the next row. A country of "-" marks a gap, as in real files.
+type ip2lTestRow struct {
+	from                  string
+	country, region, city string
+	zip                   string
+	latitude, longitude   float32
+}
+
+// buildIP2LDB returns a BIN file for product DB<n>. The first row of each
+// section must start at the lowest address of its family; an empty v6
+// builds an IPv4-only file.
+func buildIP2LDB(t testing.TB, product uint8, v4, v6 []ip2lTestRow, indexed bool) []byte {
+	t.Helper()
+
+	var columns uint8
+	for _, pos := range [][27]uint8{ip2lCountryPos, ip2lRegionPos, ip2lCityPos, ip2lLatitudePos, ip2lLongitudePos, ip2lZipCodePos} {
+		columns = max(columns, pos[product])
+	}
+
+	// Strings are placed right after the header
+	var strs bytes.Buffer
+	offsets := make(map[string]uint32)
+	str := func(s string) uint32 {
+		if off, ok := offsets[s]; ok {
+			return off
+		}
+		off := uint32(ip2lHeaderSize + strs.Len())
+		strs.WriteByte(byte(len(s)))
+		strs.WriteString(s)
+		offsets[s] = off
+		return off
+	}
+
+	encodeRows := func(rows []ip2lTestRow, ipSize int) []byte {
+		var out bytes.Buffer
+		for _, row := range rows {
+			b := make([]byte, ipSize+int(columns-1)*4)
+			n := ip2lNumOf(netip.MustParseAddr(row.from))
+			if ipSize == 4 {
+				binary.LittleEndian.PutUint32(b, uint32(n.lo))
+			} else {
+				binary.LittleEndian.PutUint64(b, n.lo)
+				binary.LittleEndian.PutUint64(b[8:], n.hi)
+			}
+			put := func(pos uint8, v uint32) {
+				if pos != 0 {
+					binary.LittleEndian.PutUint32(b[ipSize+int(pos-2)*4:], v)
+				}
+			}
+			put(ip2lCountryPos[product], str(row.country))
+			put(ip2lRegionPos[product], str(row.region))
+			put(ip2lCityPos[product], str(row.city))
+			put(ip2lZipCodePos[product], str(row.zip))
+			put(ip2lLatitudePos[product], math.Float32bits(row.latitude))
+			put(ip2lLongitudePos[product], math.Float32bits(row.longitude))
+			out.Write(b)
+		}
+		return out.Bytes()
+	}
+	v4Rows := encodeRows(v4, 4)
+	v6Rows := encodeRows(v6, 16)
+
+	v4Base := ip2lHeaderSize + strs.Len() + 1
+	v6Base := v4Base + len(v4Rows)
+	indexBase := 0
+	var index []byte
+	if indexed {
+		indexBase = v6Base + len(v6Rows)
+		// Each block of 65536 addresses maps to the rows it overlaps
+		rowOf := func(ip uint32) uint32 {
+			i := sort.Search(len(v4), func(i int) bool {
+				return netip.MustParseAddr(v4[i].from).Compare(netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)})) > 0
+			})
+			return uint32(i - 1)
+		}
+		for block := range uint32(65536) {
+			index = binary.LittleEndian.AppendUint32(index, rowOf(block<<16))
+			index = binary.LittleEndian.AppendUint32(index, rowOf(block<<16|0xffff))
+		}
+	}
+
+	h := make([]byte, ip2lHeaderSize)
+	h[0], h[1] = product, columns
+	h[2], h[3], h[4] = 25, 6, 1
+	binary.LittleEndian.PutUint32(h[5:], uint32(len(v4)))
+	binary.LittleEndian.PutUint32(h[9:], uint32(v4Base))
+	binary.LittleEndian.PutUint32(h[13:], uint32(len(v6)))
+	if len(v6) > 0 {
+		binary.LittleEndian.PutUint32(h[17:], uint32(v6Base))
+	}
+	binary.LittleEndian.PutUint32(h[21:], uint32(indexBase))
+	h[29] = 1
+
+	return slices.Concat(h, strs.Bytes(), v4Rows, v6Rows, index)
+}
+
+// writeIP2LDB builds a BIN file and writes it to a temp file, returning its path.
+func writeIP2LDB(t testing.TB, product uint8, v4, v6 []ip2lTestRow, indexed bool) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.bin")
+	if err := os.WriteFile(path, buildIP2LDB(t, product, v4, v6, indexed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var (
+	testIP2LRowsV4 = []ip2lTestRow{
+		{from: "0.0.0.0", country: "-", region: "-", city: "-", zip: "-"},
+		{from: "1.0.0.0", country: "AU", region: "Queensland", city: "Brisbane", zip: "4000", latitude: -27.5, longitude: 153},
+		{from: "1.0.1.0", country: "CN", region: "Fujian", city: "Fuzhou", zip: "-", latitude: 26, longitude: 119.25},
+		{from: "1.0.4.0", country: "-", region: "-", city: "-", zip: "-"},
+		{from: "8.8.8.0", country: "US", region: "California", city: "Mountain View", zip: "94043", latitude: 37.5, longitude: -122},
+		{from: "8.8.9.0", country: "-", region: "-", city: "-", zip: "-"},
+	}
+	testIP2LRowsV6 = []ip2lTestRow{
+		{from: "::", country: "-", region: "-", city: "-", zip: "-"},
+		{from: "2001:4860::", country: "US", region: "California", city: "Mountain View", zip: "94043", latitude: 37.5, longitude: -122},
+		{from: "2001:4861::", country: "-", region: "-", city: "-", zip: "-"},
+	}
+)
+
+func TestIP2Location_Lookup(t *testing.T) {
+	tests := []struct {
+		ip      string
+		country string
+		city    string
+		zip     string
+		wantErr error
+	}{
+		{ip: "1.0.0.1", country: "AU", city: "Brisbane", zip: "4000"},
+		{ip: "1.0.3.255", country: "CN", city: "Fuzhou"},
+		{ip: "8.8.8.8", country: "US", city: "Mountain View", zip: "94043"},
+		{ip: "8.8.9.1", wantErr: ErrIPNotFound},
+		{ip: "0.0.0.0", wantErr: ErrIPNotFound},
+		{ip: "255.255.255.255", wantErr: ErrIPNotFound},
+		{ip: "2001:4860:4860::8888", country: "US", city: "Mountain View", zip: "94043"},
+		{ip: "2001:db8::1", wantErr: ErrIPNotFound},
+	}
+
+	for _, indexed := range []bool{false, true} {
+		path := writeIP2LDB(t, 9, testIP2LRowsV4, testIP2LRowsV6, indexed)
+		r, err := openIP2Location(path, "City")
+		if err != nil {
+			t.Fatalf("openIP2Location() error = %v", err)
+		}
+		defer r.Close()
+
+		for _, tt := range tests {
+			result, err := r.Lookup(netip.MustParseAddr(tt.ip))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("indexed=%v: Lookup(%s) error = %v, want %v", indexed, tt.ip, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				continue
+			}
+			if result.CountryCode != tt.country || result.City != tt.city || result.PostalCode != tt.zip {
+				t.Errorf("indexed=%v: Lookup(%s) = %+v", indexed, tt.ip, result)
+			}
+			if result.Latitude == nil || result.Longitude == nil {
+				t.Errorf("indexed=%v: Lookup(%s) has no coordinates", indexed, tt.ip)
+			}
+		}
+	}
+}
+
+func TestIP2Location_FieldsByType(t *testing.T) {
+	// DB3 carries region and city but no coordinates
+	path := writeIP2LDB(t, 3, testIP2LRowsV4, nil, false)
+
+	city, err := openIP2Location(path, "City")
+	if err != nil {
+		t.Fatalf("openIP2Location() error = %v", err)
+	}
+	defer city.Close()
+	result, err := city.Lookup(netip.MustParseAddr("8.8.8.8"))
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.Region != "California" || result.City != "Mountain View" || result.PostalCode != "" || result.Latitude != nil {
+		t.Errorf("unexpected DB3 city result: %+v", result)
+	}
+
+	// A country lookup only reports the country, however rich the file
+	country, err := openIP2Location(path, "Country")
+	if err != nil {
+		t.Fatalf("openIP2Location() error = %v", err)
+	}
+	defer country.Close()
+	result, err = country.Lookup(netip.MustParseAddr("8.8.8.8"))
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if *result != (LookupResult{CountryCode: "US"}) {
+		t.Errorf("unexpected country result: %+v", result)
+	}
+
+	// An IPv4-only file has no IPv6 data
+	if _, err := country.Lookup(netip.MustParseAddr("2001:4860::1")); !errors.Is(err, ErrIPNotFound) {
+		t.Errorf("expected ErrIPNotFound for IPv6 in an IPv4-only file, got %v", err)
+	}
+	if meta := country.Metadata(); meta.DatabaseType != "IP2Location-DB3-Country-City" || meta.IPVersion != 4 || meta.BuildTime.Year() != 2025 {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestIP2Location_Invalid(t *testing.T) {
+	tests := map[string][]byte{
+		"mmdb file": buildTestDB(t, "Test-Country", 4, nil),
+		"truncated": buildIP2LDB(t, 1, testIP2LRowsV4, nil, false)[:ip2lHeaderSize+8],
+		"empty":     nil,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.bin")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := openIP2Location(path, "Country"); !errors.Is(err, errInvalidIP2Location) {
+				t.Errorf("openIP2Location() error = %v, want %v", err, errInvalidIP2Location)
+			}
+		})
+	}
+}
+
+// TestIP2Location_GeoDB runs a GeoDB on IP2Location files end to end.
+func TestIP2Location_GeoDB(t *testing.T) {
+	path := writeIP2LDB(t, 5, testIP2LRowsV4, testIP2LRowsV6, true)
+	g := New(Options{
+		Format:       FormatIP2Location,
+		CountryPath:  path,
+		CityIPv4Path: path,
+		CityIPv6Path: path,
+		Offline:      true,
+	}, testLogger{})
+	defer g.Stop()
+
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	result, err := g.Lookup("::ffff:8.8.8.8", LookupOptions{UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if result.CountryCode != "US" || result.City != "Mountain View" || result.IPVersion != "v4" {
+		t.Errorf("unexpected city result: %+v", result)
+	}
+	if result.Latitude == nil || *result.Latitude != 37.5 {
+		t.Errorf("expected latitude 37.5, got %v", result.Latitude)
+	}
+
+	prefix, err := g.LookupPrefix("1.0.0.0/16")
+	if err != nil {
+		t.Fatalf("LookupPrefix() error = %v", err)
+	}
+	if !prefix.Mixed || len(prefix.Countries) != 2 {
+		t.Errorf("expected AU and CN in the prefix, got %+v", prefix)
+	}
+
+	versions := g.Versions()
+	if len(versions) != 3 || versions[0].DatabaseType != "IP2Location-DB5-Country-City" || versions[0].IPVersion != 6 {
+		t.Errorf("unexpected versions: %+v", versions)
+	}
+}
+
+func TestDownloadDB_IP2LocationType(t *testing.T) {
+	// DB1 has no city data, so it can't serve as a city database
+	db := buildIP2LDB(t, 1, testIP2LRowsV4, nil, false)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(db)
+	}))
+	defer srv.Close()
+
+	g := New(Options{}, testLogger{})
+	for _, tt := range []struct {
+		dbType  string
+		wantErr error
+	}{
+		{"Country", nil},
+		{"City", ErrWrongDatabaseType},
+	} {
+		inst := &dbInstance{
+			name:   "test",
+			path:   filepath.Join(t.TempDir(), "test.bin"),
+			url:    srv.URL,
+			format: FormatIP2Location,
+			dbType: tt.dbType,
+		}
+		if err := g.downloadDB(context.Background(), inst); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: downloadDB() error = %v, want %v", tt.dbType, err, tt.wantErr)
+		}
+	}
+}
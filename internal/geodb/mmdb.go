@@ -0,0 +1,122 @@
+package geodb
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// mmdbReader reads MaxMind DB files, decoding the record struct that matches
+// the database type. A maxminddb.Reader is immutable once open, so a single
+// mmdbReader serves any number of goroutines without locking; the file is
+// memory-mapped, so processes opening the same file share its pages through
+// the OS page cache.
+type mmdbReader struct {
+	db     *maxminddb.Reader
+	dbType string
+}
+
+func openMMDB(path, dbType string) (*mmdbReader, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbReader{db: db, dbType: dbType}, nil
+}
+
+// openMMDBBytes reads a MaxMind DB already in memory, e.g. one embedded in
+// the binary. The reader uses data in place, so it must not be modified
+// while the reader is open.
+func openMMDBBytes(data []byte, dbType string) (*mmdbReader, error) {
+	db, err := maxminddb.OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbReader{db: db, dbType: dbType}, nil
+}
+
+func (r *mmdbReader) Lookup(ip netip.Addr) (*LookupResult, error) {
+	result := r.db.Lookup(ip)
+	switch r.dbType {
+	case "City":
+		var record CityRecord
+		if err := result.Decode(&record); err != nil {
+			return nil, fmt.Errorf("lookup failed: %w", err)
+		}
+		if record.CountryCode == "" {
+			return nil, ErrIPNotFound
+		}
+		return &LookupResult{
+			CountryCode:    record.CountryCode,
+			PostalCode:     record.PostCode,
+			Region:         record.Region,
+			City:           record.City,
+			Latitude:       &record.Latitude,
+			Longitude:      &record.Longitude,
+			AccuracyRadius: record.AccuracyRadius,
+			Network:        network(result),
+		}, nil
+	case "ASN":
+		var record ASNRecord
+		if err := result.Decode(&record); err != nil {
+			return nil, fmt.Errorf("lookup failed: %w", err)
+		}
+		if record.AutonomousSystemNumber == 0 {
+			return nil, ErrIPNotFound
+		}
+		return &LookupResult{
+			ASN:   record.AutonomousSystemNumber,
+			ASOrg: record.AutonomousSystemOrganization,
+		}, nil
+	default:
+		var record CountryRecord
+		if err := result.Decode(&record); err != nil {
+			return nil, fmt.Errorf("lookup failed: %w", err)
+		}
+		if record.CountryCode == "" {
+			return nil, ErrIPNotFound
+		}
+		return &LookupResult{CountryCode: record.CountryCode, Network: network(result)}, nil
+	}
+}
+
+// network renders the prefix of the matched record, or "" if it's unknown.
+func network(result maxminddb.Result) string {
+	if prefix := result.Prefix(); prefix.IsValid() {
+		return prefix.String()
+	}
+	return ""
+}
+
+func (r *mmdbReader) Metadata() Metadata {
+	meta := r.db.Metadata
+	return Metadata{
+		DatabaseType: meta.DatabaseType,
+		BuildTime:    meta.BuildTime(),
+		NodeCount:    meta.NodeCount,
+		IPVersion:    meta.IPVersion,
+	}
+}
+
+func (r *mmdbReader) Close() error {
+	return r.db.Close()
+}
+
+func (r *mmdbReader) countriesWithin(ctx context.Context, prefix netip.Prefix) (map[string]int, error) {
+	counts := make(map[string]int)
+	for network := range r.db.NetworksWithin(prefix) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var record CountryRecord
+		if err := network.Decode(&record); err != nil {
+			return nil, fmt.Errorf("lookup failed: %w", err)
+		}
+		if record.CountryCode != "" {
+			counts[record.CountryCode]++
+		}
+	}
+	return counts, nil
+}
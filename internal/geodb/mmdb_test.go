@@ -0,0 +1,68 @@
+package geodb
+
+import (
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+// TestMMDBReader_ConcurrentLookups shares one reader across goroutines, as
+// every lookup does; run with -race.
+func TestMMDBReader_ConcurrentLookups(t *testing.T) {
+	r, err := openMMDBBytes(buildTestDB(t, "Test-City", 4, map[string]map[string]any{
+		"203.0.113.0/24": {"country_code": "NL", "city": "Amsterdam"},
+	}), "City")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	ip := netip.MustParseAddr("203.0.113.5")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				result, err := r.Lookup(ip)
+				if err != nil || result.City != "Amsterdam" {
+					t.Errorf("Lookup() = %+v, %v", result, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// BenchmarkMMDBLookup compares a memory-mapped file with a buffer loaded
+// through LoadFromBytes, both shared by parallel lookups.
+func BenchmarkMMDBLookup(b *testing.B) {
+	records := map[string]map[string]any{
+		"8.8.8.0/24": {"country_code": "US"},
+	}
+	ip := netip.MustParseAddr("8.8.8.8")
+
+	open := map[string]func() (*mmdbReader, error){
+		"mmap": func() (*mmdbReader, error) {
+			return openMMDB(writeTestDB(b, "Test-Country", 4, records), "Country")
+		},
+		"bytes": func() (*mmdbReader, error) {
+			return openMMDBBytes(buildTestDB(b, "Test-Country", 4, records), "Country")
+		},
+	}
+	for _, name := range []string{"mmap", "bytes"} {
+		b.Run(name, func(b *testing.B) {
+			r, err := open[name]()
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer r.Close()
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, _ = r.Lookup(ip)
+				}
+			})
+		})
+	}
+}
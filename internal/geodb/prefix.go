@@ -0,0 +1,122 @@
+package geodb
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+var (
+	ErrInvalidPrefix = errors.New("invalid network prefix")
+	// ErrPrefixTooLarge bounds the work a single prefix lookup can cause
+	ErrPrefixTooLarge = errors.New("network prefix too large")
+)
+
+// Smallest prefix lengths accepted by LookupPrefix, per address family.
+const (
+	MinPrefixBitsIPv4 = 16
+	MinPrefixBitsIPv6 = 32
+)
+
+// ipv4Blocks are the IPv6 ranges IPv4 addresses are found under: mapped
+// addresses, and the ::/96 an IPv6 MMDB tree stores IPv4 in.
+var ipv4Blocks = []netip.Prefix{
+	netip.MustParsePrefix("::ffff:0:0/96"),
+	netip.MustParsePrefix("::/96"),
+}
+
+// CountryCount is the number of database networks within a prefix that map
+// to a country.
+type CountryCount struct {
+	CountryCode string `json:"country_code" xml:"country_code"`
+	Networks    int    `json:"networks" xml:"networks"`
+}
+
+type PrefixResult struct {
+	XMLName xml.Name `json:"-" xml:"network"`
+	Prefix  string   `json:"prefix" xml:"prefix"`
+	// CountryCode is set when every network in the prefix maps to the same country
+	CountryCode string `json:"country_code,omitempty" xml:"country_code,omitempty"`
+	// Mixed is set when the prefix spans several countries
+	Mixed bool `json:"mixed" xml:"mixed"`
+	// Countries breaks the prefix down by country, most networks first
+	Countries []CountryCount `json:"countries" xml:"countries>country"`
+}
+
+// LookupPrefix is LookupPrefixCtx without cancellation.
+func (g *GeoDB) LookupPrefix(prefixStr string) (*PrefixResult, error) {
+	return g.LookupPrefixCtx(context.Background(), prefixStr)
+}
+
+// LookupPrefixCtx aggregates the countries of every country-database network
+// covered by a CIDR prefix. A prefix inside a single database network
+// resolves to that network's country. A prefix within the IPv4 space written
+// in IPv6 form is looked up, and reported, as IPv4. The walk stops with
+// ctx.Err() once ctx is done.
+func (g *GeoDB) LookupPrefixCtx(ctx context.Context, prefixStr string) (*PrefixResult, error) {
+	prefix, err := netip.ParsePrefix(prefixStr)
+	if err != nil {
+		return nil, ErrInvalidPrefix
+	}
+	prefix = prefix.Masked()
+
+	// Otherwise the IPv6 limit would let the whole IPv4 space through
+	for _, block := range ipv4Blocks {
+		if !prefix.Overlaps(block) {
+			continue
+		}
+		if prefix.Bits() < block.Bits() {
+			return nil, fmt.Errorf("%w: must not cover the IPv4 space", ErrPrefixTooLarge)
+		}
+		a := prefix.Addr().As16()
+		prefix = netip.PrefixFrom(netip.AddrFrom4([4]byte(a[12:])), prefix.Bits()-block.Bits())
+		break
+	}
+
+	minBits := MinPrefixBitsIPv6
+	if prefix.Addr().Is4() {
+		minBits = MinPrefixBitsIPv4
+	}
+	if prefix.Bits() < minBits {
+		return nil, fmt.Errorf("%w: must be /%d or smaller", ErrPrefixTooLarge, minBits)
+	}
+
+	db := g.country.acquire()
+	if db == nil {
+		return nil, errors.New("country database not loaded")
+	}
+	defer db.release()
+
+	counter, ok := db.Reader.(prefixCounter)
+	if !ok {
+		return nil, errors.New("prefix lookups are not supported by the country database format")
+	}
+
+	counts, err := counter.countriesWithin(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(counts) == 0 {
+		return nil, ErrIPNotFound
+	}
+
+	result := &PrefixResult{
+		Prefix: prefix.String(),
+		Mixed:  len(counts) > 1,
+	}
+	for code, n := range counts {
+		result.Countries = append(result.Countries, CountryCount{CountryCode: code, Networks: n})
+	}
+	slices.SortFunc(result.Countries, func(a, b CountryCount) int {
+		return cmp.Or(cmp.Compare(b.Networks, a.Networks), cmp.Compare(a.CountryCode, b.CountryCode))
+	})
+	if !result.Mixed {
+		result.CountryCode = result.Countries[0].CountryCode
+	}
+
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package geodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newPrefixGeoDB(t *testing.T) *GeoDB {
+	t.Helper()
+
+	srv := newDBServer(t)
+	srv.set("/country.mmdb", buildTestDB(t, "Test-Country", 6, map[string]map[string]any{
+		"8.8.8.0/24":    {"country_code": "US"},
+		"8.8.9.0/24":    {"country_code": "US"},
+		"8.8.10.0/24":   {"country_code": "CA"},
+		"2001:db8::/48": {"country_code": "DE"},
+	}))
+	srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, nil))
+	srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+	g := newServedGeoDB(t, srv)
+	g.Refresh(context.Background())
+	return g
+}
+
+func TestLookupPrefix(t *testing.T) {
+	g := newPrefixGeoDB(t)
+
+	tests := []struct {
+		prefix  string
+		country string
+		mixed   bool
+		want    []CountryCount
+	}{
+		{"8.8.8.0/23", "US", false, []CountryCount{{"US", 2}}},
+		// A prefix inside a single network resolves to that network
+		{"8.8.8.128/25", "US", false, []CountryCount{{"US", 1}}},
+		{"8.8.0.0/16", "", true, []CountryCount{{"US", 2}, {"CA", 1}}},
+		{"2001:db8::/32", "DE", false, []CountryCount{{"DE", 1}}},
+		// IPv4 in IPv6 form resolves as IPv4
+		{"::ffff:8.8.8.0/119", "US", false, []CountryCount{{"US", 2}}},
+	}
+
+	for _, tt := range tests {
+		result, err := g.LookupPrefix(tt.prefix)
+		if err != nil {
+			t.Errorf("LookupPrefix(%s) error = %v", tt.prefix, err)
+			continue
+		}
+		if result.CountryCode != tt.country || result.Mixed != tt.mixed {
+			t.Errorf("LookupPrefix(%s) = %+v, want country %q mixed %v", tt.prefix, result, tt.country, tt.mixed)
+		}
+		if len(result.Countries) != len(tt.want) {
+			t.Errorf("LookupPrefix(%s) countries = %v, want %v", tt.prefix, result.Countries, tt.want)
+			continue
+		}
+		for i, c := range tt.want {
+			if result.Countries[i] != c {
+				t.Errorf("LookupPrefix(%s) countries = %v, want %v", tt.prefix, result.Countries, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLookupPrefix_Errors(t *testing.T) {
+	g := newPrefixGeoDB(t)
+
+	tests := []struct {
+		prefix string
+		want   error
+	}{
+		{"8.8.8.8", ErrInvalidPrefix},
+		{"not-a-prefix/24", ErrInvalidPrefix},
+		{"8.0.0.0/8", ErrPrefixTooLarge},
+		{"2001::/16", ErrPrefixTooLarge},
+		// IPv4 in IPv6 form gets the IPv4 limit
+		{"::/96", ErrPrefixTooLarge},
+		{"::ffff:8.0.0.0/104", ErrPrefixTooLarge},
+		{"::/32", ErrPrefixTooLarge},
+		{"::/64", ErrPrefixTooLarge},
+		{"1.1.1.0/24", ErrIPNotFound},
+	}
+
+	for _, tt := range tests {
+		if _, err := g.LookupPrefix(tt.prefix); !errors.Is(err, tt.want) {
+			t.Errorf("LookupPrefix(%s) error = %v, want %v", tt.prefix, err, tt.want)
+		}
+	}
+}
+
+func TestLookupCtx_Canceled(t *testing.T) {
+	g := newPrefixGeoDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := g.LookupCtx(ctx, "8.8.8.8", LookupOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := g.LookupPrefixCtx(ctx, "8.8.0.0/16"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for prefix, got %v", err)
+	}
+
+	// The canceled lookup must not have been cached
+	result, err := g.Lookup("8.8.8.8", LookupOptions{})
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if result.CountryCode != "US" {
+		t.Errorf("expected US, got %s", result.CountryCode)
+	}
+}
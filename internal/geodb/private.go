@@ -0,0 +1,34 @@
+package geodb
+
+import "net/netip"
+
+// reservedPrefixes are special-purpose ranges (RFC 6890 and friends) that
+// the netip helpers don't already cover and that never appear in a
+// geolocation database.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved for future use
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// isPrivate reports whether ip is private, loopback, link-local, multicast or
+// otherwise reserved, i.e. not a routable public address.
+func isPrivate(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsPrivate() || !ip.IsGlobalUnicast() {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
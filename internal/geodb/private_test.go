@@ -0,0 +1,53 @@
+package geodb
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPrivate(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.0", true},
+		{"172.16.5.4", true},
+		{"192.168.1.1", true},
+		{"127.0.0.1", true},
+		{"169.254.1.1", true},
+		{"100.64.0.1", true},
+		{"192.0.2.1", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"2001:db8::1", true},
+		{"::ffff:192.168.1.1", true},
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"2001:4860:4860::8888", false},
+		{"::ffff:8.8.8.8", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPrivate(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("isPrivate(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestLookup_Private(t *testing.T) {
+	g := New(Options{DetectPrivate: true}, testLogger{})
+	if _, err := g.Lookup("192.168.1.1", LookupOptions{}); !errors.Is(err, ErrPrivateIP) {
+		t.Errorf("Lookup() error = %v, want ErrPrivateIP", err)
+	}
+
+	// Without detection private addresses go to the databases as before
+	g = New(Options{}, testLogger{})
+	if _, err := g.Lookup("192.168.1.1", LookupOptions{}); errors.Is(err, ErrPrivateIP) {
+		t.Error("expected ErrPrivateIP only when detection is enabled")
+	}
+}
@@ -0,0 +1,60 @@
+package geodb
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Format names an on-disk database format.
+type Format string
+
+const (
+	// FormatMMDB is the MaxMind DB format, the default
+	FormatMMDB Format = "mmdb"
+	// FormatIP2Location is the IP2Location BIN format
+	FormatIP2Location Format = "ip2location"
+)
+
+// Reader is an open database file. GeoDB only talks to databases through
+// it, so lookups, reloads and updates don't depend on the file format.
+// Implementations must be safe for concurrent lookups.
+type Reader interface {
+	// Lookup returns the data for ip, or ErrIPNotFound when the database
+	// has none. Fields the database doesn't carry are left zero.
+	Lookup(ip netip.Addr) (*LookupResult, error)
+	Metadata() Metadata
+	Close() error
+}
+
+// prefixCounter is implemented by readers that can enumerate the networks
+// within a prefix, for LookupPrefix.
+type prefixCounter interface {
+	// countriesWithin counts the networks covered by prefix, per country
+	countriesWithin(ctx context.Context, prefix netip.Prefix) (map[string]int, error)
+}
+
+// Metadata describes the build of a database file.
+type Metadata struct {
+	DatabaseType string
+	BuildTime    time.Time
+	// NodeCount is the size of the MMDB search tree; 0 for other formats
+	NodeCount uint
+	// IPVersion is 4 for IPv4-only databases and 6 for IPv4 and IPv6
+	IPVersion uint
+}
+
+// openReader opens the database at path. dbType ("Country", "City" or
+// "ASN") selects which fields lookups return, so a country database that
+// happens to carry city data still answers like a country database.
+func openReader(format Format, path, dbType string) (Reader, error) {
+	switch format {
+	case FormatMMDB, "":
+		return openMMDB(path, dbType)
+	case FormatIP2Location:
+		return openIP2Location(path, dbType)
+	default:
+		return nil, fmt.Errorf("unknown database format %q", format)
+	}
+}
@@ -0,0 +1,44 @@
+package geodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Probe is a known-answer lookup Start runs against the country database,
+// to catch a file that opens fine but has no usable data.
+type Probe struct {
+	IP string
+	// Country is the expected country code; empty accepts any country
+	Country string
+}
+
+// selfTest runs the probes, failing unless every one resolves to a
+// plausible country. Only the country database is consulted, so the city
+// database can't cover for it.
+func (g *GeoDB) selfTest(ctx context.Context) error {
+	if len(g.probes) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, p := range g.probes {
+		result, err := g.LookupCtx(ctx, p.IP, LookupOptions{Strict: true})
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", p.IP, err))
+		case p.Country != "" && !strings.EqualFold(result.CountryCode, p.Country):
+			errs = append(errs, fmt.Errorf("%s: expected %s, got %s", p.IP, p.Country, result.CountryCode))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("database self-test failed: %w", errors.Join(errs...))
+	}
+
+	g.logger.Info("database self-test passed", map[string]any{
+		"probes": len(g.probes),
+	})
+	return nil
+}
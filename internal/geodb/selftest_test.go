@@ -0,0 +1,47 @@
+package geodb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStart_SelfTest(t *testing.T) {
+	tests := []struct {
+		name    string
+		probes  []Probe
+		wantErr string
+	}{
+		{name: "no probes"},
+		{name: "expected country", probes: []Probe{{IP: "8.8.8.8", Country: "US"}}},
+		{name: "any country", probes: []Probe{{IP: "8.8.8.8"}}},
+		{name: "case-insensitive", probes: []Probe{{IP: "8.8.8.8", Country: "us"}}},
+		{name: "wrong country", probes: []Probe{{IP: "8.8.8.8", Country: "DE"}}, wantErr: "expected DE, got US"},
+		{name: "not found", probes: []Probe{{IP: "8.8.8.8"}, {IP: "1.1.1.1"}}, wantErr: "1.1.1.1: IP not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDBServer(t)
+			srv.set("/country.mmdb", countryDB(t, "US"))
+			srv.set("/city-ipv4.mmdb", buildTestDB(t, "Test-City", 4, map[string]map[string]any{
+				"1.1.1.0/24": {"country_code": "AU"},
+			}))
+			srv.set("/city-ipv6.mmdb", buildTestDB(t, "Test-City", 6, nil))
+
+			g := newServedGeoDB(t, srv)
+			g.probes = tt.probes
+
+			err := g.Start(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Start() error = %v", err)
+				}
+				return
+			}
+			// The city database doesn't cover for a country database miss
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Start() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package geodb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// This file contains a minimal MaxMind DB writer so tests can build small
+// databases on the fly instead of depending on fixture files. It supports
+// 24-bit records, non-overlapping networks and the handful of data types our
+// record structs use.
+
+type testNode struct {
+	id       int
+	children [2]*testNode
+	// data holds the data-section offset + 1 for each record (0 = empty)
+	data [2]int
+}
+
+// buildTestDB returns an MMDB file mapping each CIDR in records to its data.
+// ipVersion is 4 or 6; IPv4 networks in an IPv6 database live under ::/96
+// the way MaxMind's IPv4-compatible layout does.
+func buildTestDB(t testing.TB, dbType string, ipVersion int, records map[string]map[string]any) []byte {
+	t.Helper()
+
+	root := &testNode{}
+	var data bytes.Buffer
+
+	// Sorted for deterministic output
+	cidrs := make([]string, 0, len(records))
+	for cidr := range records {
+		cidrs = append(cidrs, cidr)
+	}
+	slices.Sort(cidrs)
+
+	for _, cidr := range cidrs {
+		prefix := netip.MustParsePrefix(cidr)
+		offset := data.Len()
+		encodeTestValue(t, &data, records[cidr])
+
+		addr := prefix.Addr()
+		bits := prefix.Bits()
+		var ipBytes []byte
+		switch {
+		case ipVersion == 4:
+			if !addr.Is4() {
+				t.Fatalf("IPv6 network %s in IPv4 database", cidr)
+			}
+			b := addr.As4()
+			ipBytes = b[:]
+		case addr.Is4():
+			var b [16]byte
+			a4 := addr.As4()
+			copy(b[12:], a4[:])
+			ipBytes = b[:]
+			bits += 96
+		default:
+			b := addr.As16()
+			ipBytes = b[:]
+		}
+
+		node := root
+		for i := 0; i < bits; i++ {
+			bit := (ipBytes[i/8] >> (7 - uint(i%8))) & 1
+			if i == bits-1 {
+				node.data[bit] = offset + 1
+				break
+			}
+			if node.children[bit] == nil {
+				node.children[bit] = &testNode{}
+			}
+			node = node.children[bit]
+		}
+	}
+
+	// Number nodes breadth-first; the root must be node 0
+	var nodes []*testNode
+	queue := []*testNode{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		n.id = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			if c != nil {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	nodeCount := len(nodes)
+	record := func(n *testNode, bit int) uint32 {
+		switch {
+		case n.children[bit] != nil:
+			return uint32(n.children[bit].id)
+		case n.data[bit] != 0:
+			return uint32(nodeCount + 16 + n.data[bit] - 1)
+		default:
+			return uint32(nodeCount)
+		}
+	}
+
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for bit := range 2 {
+			v := record(n, bit)
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+	out.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encodeTestValue(t, &out, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               dbType,
+		"description":                 map[string]any{"en": "test database"},
+		"ip_version":                  uint16(ipVersion),
+		"languages":                   []any{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})
+
+	return out.Bytes()
+}
+
+// writeTestDB builds a database and writes it to a temp file, returning its path.
+func writeTestDB(t testing.TB, dbType string, ipVersion int, records map[string]map[string]any) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), dbType+".mmdb")
+	if err := os.WriteFile(path, buildTestDB(t, dbType, ipVersion, records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func encodeTestControl(buf *bytes.Buffer, typ, size int) {
+	var ctrl byte
+	var ext []byte
+	if typ > 7 {
+		ext = append(ext, byte(typ-7))
+	} else {
+		ctrl = byte(typ << 5)
+	}
+
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 285:
+		ctrl |= 29
+		ext = append(ext, byte(size-29))
+	case size < 65821:
+		ctrl |= 30
+		ext = binary.BigEndian.AppendUint16(ext, uint16(size-285))
+	default:
+		ctrl |= 31
+		s := size - 65821
+		ext = append(ext, byte(s>>16), byte(s>>8), byte(s))
+	}
+
+	buf.WriteByte(ctrl)
+	buf.Write(ext)
+}
+
+func encodeTestUint(buf *bytes.Buffer, typ int, v uint64) {
+	var b []byte
+	for v > 0 {
+		b = append([]byte{byte(v)}, b...)
+		v >>= 8
+	}
+	encodeTestControl(buf, typ, len(b))
+	buf.Write(b)
+}
+
+func encodeTestValue(t testing.TB, buf *bytes.Buffer, v any) {
+	t.Helper()
+
+	switch v := v.(type) {
+	case string:
+		encodeTestControl(buf, 2, len(v))
+		buf.WriteString(v)
+	case float64:
+		encodeTestControl(buf, 3, 8)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	case uint16:
+		encodeTestUint(buf, 5, uint64(v))
+	case uint32:
+		encodeTestUint(buf, 6, uint64(v))
+	case int:
+		encodeTestUint(buf, 6, uint64(v))
+	case uint64:
+		encodeTestUint(buf, 9, v)
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		encodeTestControl(buf, 14, size)
+	case []any:
+		encodeTestControl(buf, 11, len(v))
+		for _, item := range v {
+			encodeTestValue(t, buf, item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		encodeTestControl(buf, 7, len(v))
+		for _, k := range keys {
+			encodeTestValue(t, buf, k)
+			encodeTestValue(t, buf, v[k])
+		}
+	default:
+		t.Fatalf("unsupported test value type %T", v)
+	}
+}
@@ -0,0 +1,61 @@
+package geodb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// WarmCache resolves the addresses listed in the file at path, one per line,
+// so their results are cached before clients ask for them. Blank lines and
+// lines starting with # are skipped, as are entries that fail to resolve.
+// opts should match the requests expected, since cached results are keyed
+// by them. With more addresses than the cache holds, the last ones listed
+// win. Cancelling ctx stops the warmup.
+func (g *GeoDB) WarmCache(ctx context.Context, path string, opts LookupOptions) error {
+	if g.cache == nil {
+		g.logger.Warn("lookup cache disabled, skipping warmup", map[string]any{
+			"path": path,
+		})
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	start := time.Now()
+	warmed, skipped := 0, 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Not-found results are cached too
+		if _, err := g.LookupCtx(ctx, line, opts); err == nil || errors.Is(err, ErrIPNotFound) {
+			warmed++
+		} else {
+			skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	g.logger.Info("lookup cache warmed", map[string]any{
+		"path":     path,
+		"entries":  warmed,
+		"skipped":  skipped,
+		"duration": time.Since(start).String(),
+	})
+	return nil
+}
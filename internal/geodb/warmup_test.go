@@ -0,0 +1,67 @@
+package geodb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/logger/logtest"
+)
+
+func TestWarmCache(t *testing.T) {
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	if err := os.WriteFile(countryPath, countryDB(t, "US"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	listPath := filepath.Join(dir, "hot-ips.txt")
+	list := "# hot addresses\n8.8.8.8\n\n  8.8.8.9  \n1.1.1.1\nnot-an-ip\n"
+	if err := os.WriteFile(listPath, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := &logtest.Recorder{}
+	g := New(Options{
+		CountryPath:  countryPath,
+		CityIPv4Path: filepath.Join(dir, "city-ipv4.mmdb"),
+		CityIPv6Path: filepath.Join(dir, "city-ipv6.mmdb"),
+		CacheSize:    10,
+		Offline:      true,
+	}, log)
+	defer g.Stop()
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := g.WarmCache(context.Background(), listPath, LookupOptions{}); err != nil {
+		t.Fatalf("WarmCache() error = %v", err)
+	}
+
+	// Found and not-found results are cached, the invalid entry isn't
+	warmed, ok := log.Find("lookup cache warmed")
+	if !ok {
+		t.Fatal("expected the warmup to be logged")
+	}
+	if warmed.Data["entries"] != 3 || warmed.Data["skipped"] != 1 {
+		t.Errorf("expected 3 entries warmed and 1 skipped, got %v", warmed.Data)
+	}
+	if size := g.cacheStats().Size; size != 3 {
+		t.Errorf("expected 3 cached results, got %d", size)
+	}
+
+	before := g.cacheStats()
+	if _, err := g.Lookup("8.8.8.8", LookupOptions{}); err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if after := g.cacheStats(); after.Hits != before.Hits+1 {
+		t.Errorf("expected a warmed address to be a cache hit, got %+v", after)
+	}
+}
+
+func TestWarmCache_MissingFile(t *testing.T) {
+	g := New(Options{CacheSize: 10}, testLogger{})
+	if err := g.WarmCache(context.Background(), filepath.Join(t.TempDir(), "missing.txt"), LookupOptions{}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
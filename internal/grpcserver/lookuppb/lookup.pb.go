@@ -0,0 +1,316 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: ipburack/v1/lookup.proto
+
+package lookuppb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LookupRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Ip    string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	// use_city tries the city database first, falling back to country
+	UseCity bool `protobuf:"varint,2,opt,name=use_city,json=useCity,proto3" json:"use_city,omitempty"`
+	// asn also resolves ASN data when an ASN database is configured
+	Asn           bool `protobuf:"varint,3,opt,name=asn,proto3" json:"asn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_ipburack_v1_lookup_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipburack_v1_lookup_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_ipburack_v1_lookup_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LookupRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *LookupRequest) GetUseCity() bool {
+	if x != nil {
+		return x.UseCity
+	}
+	return false
+}
+
+func (x *LookupRequest) GetAsn() bool {
+	if x != nil {
+		return x.Asn
+	}
+	return false
+}
+
+type LookupReply struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Ip          string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	CountryCode string                 `protobuf:"bytes,2,opt,name=country_code,json=countryCode,proto3" json:"country_code,omitempty"`
+	PostalCode  string                 `protobuf:"bytes,3,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	Region      string                 `protobuf:"bytes,4,opt,name=region,proto3" json:"region,omitempty"`
+	City        string                 `protobuf:"bytes,5,opt,name=city,proto3" json:"city,omitempty"`
+	// Coordinates are only set for city matches
+	Latitude  *float64 `protobuf:"fixed64,6,opt,name=latitude,proto3,oneof" json:"latitude,omitempty"`
+	Longitude *float64 `protobuf:"fixed64,7,opt,name=longitude,proto3,oneof" json:"longitude,omitempty"`
+	// accuracy_radius is in km; 0 when unknown
+	AccuracyRadius uint32 `protobuf:"varint,8,opt,name=accuracy_radius,json=accuracyRadius,proto3" json:"accuracy_radius,omitempty"`
+	Asn            uint32 `protobuf:"varint,9,opt,name=asn,proto3" json:"asn,omitempty"`
+	AsOrg          string `protobuf:"bytes,10,opt,name=as_org,json=asOrg,proto3" json:"as_org,omitempty"`
+	// ip_version is "v4" or "v6"
+	IpVersion string `protobuf:"bytes,11,opt,name=ip_version,json=ipVersion,proto3" json:"ip_version,omitempty"`
+	// private is set for private and reserved addresses, which have no location
+	Private bool `protobuf:"varint,12,opt,name=private,proto3" json:"private,omitempty"`
+	// error is only set by BatchLookup, for an IP that couldn't be resolved
+	Error         string `protobuf:"bytes,13,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupReply) Reset() {
+	*x = LookupReply{}
+	mi := &file_ipburack_v1_lookup_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupReply) ProtoMessage() {}
+
+func (x *LookupReply) ProtoReflect() protoreflect.Message {
+	mi := &file_ipburack_v1_lookup_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupReply.ProtoReflect.Descriptor instead.
+func (*LookupReply) Descriptor() ([]byte, []int) {
+	return file_ipburack_v1_lookup_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LookupReply) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *LookupReply) GetCountryCode() string {
+	if x != nil {
+		return x.CountryCode
+	}
+	return ""
+}
+
+func (x *LookupReply) GetPostalCode() string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return ""
+}
+
+func (x *LookupReply) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *LookupReply) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *LookupReply) GetLatitude() float64 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *LookupReply) GetLongitude() float64 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *LookupReply) GetAccuracyRadius() uint32 {
+	if x != nil {
+		return x.AccuracyRadius
+	}
+	return 0
+}
+
+func (x *LookupReply) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *LookupReply) GetAsOrg() string {
+	if x != nil {
+		return x.AsOrg
+	}
+	return ""
+}
+
+func (x *LookupReply) GetIpVersion() string {
+	if x != nil {
+		return x.IpVersion
+	}
+	return ""
+}
+
+func (x *LookupReply) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+func (x *LookupReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_ipburack_v1_lookup_proto protoreflect.FileDescriptor
+
+const file_ipburack_v1_lookup_proto_rawDesc = "" +
+	"\n" +
+	"\x18ipburack/v1/lookup.proto\x12\vipburack.v1\"L\n" +
+	"\rLookupRequest\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x19\n" +
+	"\buse_city\x18\x02 \x01(\bR\auseCity\x12\x10\n" +
+	"\x03asn\x18\x03 \x01(\bR\x03asn\"\x8d\x03\n" +
+	"\vLookupReply\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12!\n" +
+	"\fcountry_code\x18\x02 \x01(\tR\vcountryCode\x12\x1f\n" +
+	"\vpostal_code\x18\x03 \x01(\tR\n" +
+	"postalCode\x12\x16\n" +
+	"\x06region\x18\x04 \x01(\tR\x06region\x12\x12\n" +
+	"\x04city\x18\x05 \x01(\tR\x04city\x12\x1f\n" +
+	"\blatitude\x18\x06 \x01(\x01H\x00R\blatitude\x88\x01\x01\x12!\n" +
+	"\tlongitude\x18\a \x01(\x01H\x01R\tlongitude\x88\x01\x01\x12'\n" +
+	"\x0faccuracy_radius\x18\b \x01(\rR\x0eaccuracyRadius\x12\x10\n" +
+	"\x03asn\x18\t \x01(\rR\x03asn\x12\x15\n" +
+	"\x06as_org\x18\n" +
+	" \x01(\tR\x05asOrg\x12\x1d\n" +
+	"\n" +
+	"ip_version\x18\v \x01(\tR\tipVersion\x12\x18\n" +
+	"\aprivate\x18\f \x01(\bR\aprivate\x12\x14\n" +
+	"\x05error\x18\r \x01(\tR\x05errorB\v\n" +
+	"\t_latitudeB\f\n" +
+	"\n" +
+	"_longitude2\x98\x01\n" +
+	"\rLookupService\x12>\n" +
+	"\x06Lookup\x12\x1a.ipburack.v1.LookupRequest\x1a\x18.ipburack.v1.LookupReply\x12G\n" +
+	"\vBatchLookup\x12\x1a.ipburack.v1.LookupRequest\x1a\x18.ipburack.v1.LookupReply(\x010\x01B;Z9github.com/burakcan/ipburack/internal/grpcserver/lookuppbb\x06proto3"
+
+var (
+	file_ipburack_v1_lookup_proto_rawDescOnce sync.Once
+	file_ipburack_v1_lookup_proto_rawDescData []byte
+)
+
+func file_ipburack_v1_lookup_proto_rawDescGZIP() []byte {
+	file_ipburack_v1_lookup_proto_rawDescOnce.Do(func() {
+		file_ipburack_v1_lookup_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ipburack_v1_lookup_proto_rawDesc), len(file_ipburack_v1_lookup_proto_rawDesc)))
+	})
+	return file_ipburack_v1_lookup_proto_rawDescData
+}
+
+var file_ipburack_v1_lookup_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_ipburack_v1_lookup_proto_goTypes = []any{
+	(*LookupRequest)(nil), // 0: ipburack.v1.LookupRequest
+	(*LookupReply)(nil),   // 1: ipburack.v1.LookupReply
+}
+var file_ipburack_v1_lookup_proto_depIdxs = []int32{
+	0, // 0: ipburack.v1.LookupService.Lookup:input_type -> ipburack.v1.LookupRequest
+	0, // 1: ipburack.v1.LookupService.BatchLookup:input_type -> ipburack.v1.LookupRequest
+	1, // 2: ipburack.v1.LookupService.Lookup:output_type -> ipburack.v1.LookupReply
+	1, // 3: ipburack.v1.LookupService.BatchLookup:output_type -> ipburack.v1.LookupReply
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_ipburack_v1_lookup_proto_init() }
+func file_ipburack_v1_lookup_proto_init() {
+	if File_ipburack_v1_lookup_proto != nil {
+		return
+	}
+	file_ipburack_v1_lookup_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ipburack_v1_lookup_proto_rawDesc), len(file_ipburack_v1_lookup_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ipburack_v1_lookup_proto_goTypes,
+		DependencyIndexes: file_ipburack_v1_lookup_proto_depIdxs,
+		MessageInfos:      file_ipburack_v1_lookup_proto_msgTypes,
+	}.Build()
+	File_ipburack_v1_lookup_proto = out.File
+	file_ipburack_v1_lookup_proto_goTypes = nil
+	file_ipburack_v1_lookup_proto_depIdxs = nil
+}
@@ -0,0 +1,355 @@
+// Package grpcserver serves the lookup API over gRPC, backed by the same
+// databases as the HTTP handlers.
+package grpcserver
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/burakcan/ipburack --go-grpc_out=../.. --go-grpc_opt=module=github.com/burakcan/ipburack ipburack/v1/lookup.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+	"github.com/burakcan/ipburack/internal/grpcserver/lookuppb"
+	"github.com/burakcan/ipburack/internal/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GeoLookup is the subset of GeoDB the service needs.
+type GeoLookup interface {
+	LookupCtx(ctx context.Context, ip string, opts geodb.LookupOptions) (*geodb.LookupResult, error)
+}
+
+// Options configures the gRPC server. Zero values select defaults.
+type Options struct {
+	// Auth checks the API key in request metadata; nil disables auth
+	Auth *middleware.AuthMiddleware
+	// Creds enables TLS; nil serves plaintext
+	Creds credentials.TransportCredentials
+	// RateLimit throttles RPCs per peer address, each batch stream counting
+	// as one request like an HTTP batch; nil disables limiting
+	RateLimit *middleware.RateLimiter
+	// Concurrency caps the RPCs handled at once, a batch stream holding its
+	// slot until it ends; nil disables the cap
+	Concurrency *middleware.ConcurrencyLimiter
+	// MaxBatchSize caps the lookups in one batch stream; <= 0 disables it
+	MaxBatchSize int
+}
+
+// Service implements lookuppb.LookupServiceServer.
+type Service struct {
+	lookuppb.UnimplementedLookupServiceServer
+	geo GeoLookup
+}
+
+func NewService(geo GeoLookup) *Service {
+	return &Service{geo: geo}
+}
+
+// New returns a gRPC server with the lookup service registered. RPCs pass
+// the rate limit, auth and concurrency limit in the same order as HTTP
+// lookups, so key guessing is throttled too.
+func New(geo GeoLookup, opts Options) *grpc.Server {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if opts.RateLimit != nil {
+		unary = append(unary, unaryRateLimit(opts.RateLimit))
+		stream = append(stream, streamRateLimit(opts.RateLimit))
+	}
+	if opts.Auth != nil {
+		unary = append(unary, unaryAuth(opts.Auth))
+		stream = append(stream, streamAuth(opts.Auth))
+	}
+	if opts.Concurrency != nil {
+		unary = append(unary, unaryConcurrency(opts.Concurrency))
+		stream = append(stream, streamConcurrency(opts.Concurrency))
+	}
+	if opts.MaxBatchSize > 0 {
+		stream = append(stream, streamBatchLimit(opts.MaxBatchSize))
+	}
+
+	var serverOpts []grpc.ServerOption
+	if len(unary) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(unary...))
+	}
+	if len(stream) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainStreamInterceptor(stream...))
+	}
+	if opts.Creds != nil {
+		serverOpts = append(serverOpts, grpc.Creds(opts.Creds))
+	}
+
+	s := grpc.NewServer(serverOpts...)
+	lookuppb.RegisterLookupServiceServer(s, NewService(geo))
+	return s
+}
+
+func (s *Service) Lookup(ctx context.Context, req *lookuppb.LookupRequest) (*lookuppb.LookupReply, error) {
+	reply, err := s.resolve(ctx, req)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+	return reply, nil
+}
+
+func (s *Service) BatchLookup(stream lookuppb.LookupService_BatchLookupServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		reply, err := s.resolve(ctx, req)
+		if err != nil {
+			// The stream is over once the client is gone
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return status.FromContextError(ctxErr).Err()
+			}
+			reply = &lookuppb.LookupReply{Ip: req.GetIp(), Error: status.Convert(lookupError(err)).Message()}
+		}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+	}
+}
+
+// resolve looks up a single IP and builds its reply.
+func (s *Service) resolve(ctx context.Context, req *lookuppb.LookupRequest) (*lookuppb.LookupReply, error) {
+	result, err := s.geo.LookupCtx(ctx, req.GetIp(), geodb.LookupOptions{
+		UseCity: req.GetUseCity(),
+		ASN:     req.GetAsn(),
+	})
+	// Private addresses are a valid answer, just one without a location
+	if errors.Is(err, geodb.ErrPrivateIP) {
+		return &lookuppb.LookupReply{Ip: req.GetIp(), Private: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &lookuppb.LookupReply{
+		Ip:             req.GetIp(),
+		CountryCode:    result.CountryCode,
+		PostalCode:     result.PostalCode,
+		Region:         result.Region,
+		City:           result.City,
+		Latitude:       result.Latitude,
+		Longitude:      result.Longitude,
+		AccuracyRadius: uint32(result.AccuracyRadius),
+		Asn:            uint32(result.ASN),
+		AsOrg:          result.ASOrg,
+		IpVersion:      result.IPVersion,
+	}
+	applyScope(ctx, reply)
+	return reply, nil
+}
+
+// scopeFields clears each reply field an API key scope can withhold, keyed
+// by the JSON name the HTTP API uses for it.
+var scopeFields = map[string]func(*lookuppb.LookupReply){
+	"country_code":    func(r *lookuppb.LookupReply) { r.CountryCode = "" },
+	"postal_code":     func(r *lookuppb.LookupReply) { r.PostalCode = "" },
+	"region":          func(r *lookuppb.LookupReply) { r.Region = "" },
+	"city":            func(r *lookuppb.LookupReply) { r.City = "" },
+	"latitude":        func(r *lookuppb.LookupReply) { r.Latitude = nil },
+	"longitude":       func(r *lookuppb.LookupReply) { r.Longitude = nil },
+	"accuracy_radius": func(r *lookuppb.LookupReply) { r.AccuracyRadius = 0 },
+	"asn":             func(r *lookuppb.LookupReply) { r.Asn = 0 },
+	"as_org":          func(r *lookuppb.LookupReply) { r.AsOrg = "" },
+	"ip_version":      func(r *lookuppb.LookupReply) { r.IpVersion = "" },
+}
+
+// applyScope drops the fields the caller's API key isn't entitled to, as the
+// HTTP handlers do.
+func applyScope(ctx context.Context, reply *lookuppb.LookupReply) {
+	allowed, ok := middleware.KeyScope(ctx)
+	if !ok {
+		return
+	}
+	for name, drop := range scopeFields {
+		if !slices.Contains(allowed, name) {
+			drop(reply)
+		}
+	}
+}
+
+// lookupError maps a lookup error to a gRPC status with the same messages as
+// the HTTP API.
+func lookupError(err error) error {
+	switch {
+	case errors.Is(err, geodb.ErrInvalidIP):
+		return status.Error(codes.InvalidArgument, "invalid IP address")
+	case errors.Is(err, geodb.ErrIPNotFound):
+		return status.Error(codes.NotFound, "IP not found in database")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, "lookup failed")
+	}
+}
+
+var errUnauthenticated = status.Error(codes.Unauthenticated, "invalid or missing API key")
+
+// authenticate checks the API key in the incoming metadata, read the same
+// way as the HTTP headers.
+func authenticate(ctx context.Context, auth *middleware.AuthMiddleware) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	ctx, ok := auth.Authenticate(ctx, header)
+	if !ok {
+		return ctx, errUnauthenticated
+	}
+	return ctx, nil
+}
+
+func unaryAuth(auth *middleware.AuthMiddleware) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(auth *middleware.AuthMiddleware) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), auth)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authedStream carries the context with the authenticated key name.
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
+
+// peerKey returns the caller's IP, which rate limit buckets are keyed on.
+// gRPC has no forwarding headers to honor, so it's always the connection's.
+func peerKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// rateLimit takes a token for the caller, failing with ResourceExhausted
+// and a retry-after hint in the trailer metadata when none is left.
+func rateLimit(ctx context.Context, limit *middleware.RateLimiter) error {
+	wait, ok := limit.Allow(peerKey(ctx))
+	if ok {
+		return nil
+	}
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(wait.Seconds())))))
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+func unaryRateLimit(limit *middleware.RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := rateLimit(ctx, limit); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamRateLimit(limit *middleware.RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rateLimit(ss.Context(), limit); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// acquire takes a concurrency slot, mapping a full server to Unavailable.
+func acquire(ctx context.Context, busy *middleware.ConcurrencyLimiter) (func(), error) {
+	release, err := busy.Acquire(ctx)
+	if errors.Is(err, middleware.ErrBusy) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return release, nil
+}
+
+func unaryConcurrency(busy *middleware.ConcurrencyLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		release, err := acquire(ctx, busy)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+func streamConcurrency(busy *middleware.ConcurrencyLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := acquire(ss.Context(), busy)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
+func streamBatchLimit(max int) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &cappedStream{ServerStream: ss, max: max})
+	}
+}
+
+// cappedStream fails the stream once the client sends more than max
+// messages, as an HTTP batch with too many IPs is rejected.
+type cappedStream struct {
+	grpc.ServerStream
+	max      int
+	received int
+}
+
+func (s *cappedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.received++
+	if s.received > s.max {
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("too many IP addresses in batch (max %d)", s.max))
+	}
+	return nil
+}
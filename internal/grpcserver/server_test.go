@@ -0,0 +1,284 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+	"github.com/burakcan/ipburack/internal/grpcserver/lookuppb"
+	"github.com/burakcan/ipburack/internal/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// tableGeoLookup resolves IPs from a fixed table, mimicking GeoDB's errors
+type tableGeoLookup map[string]*geodb.LookupResult
+
+func (m tableGeoLookup) LookupCtx(ctx context.Context, ip string, opts geodb.LookupOptions) (*geodb.LookupResult, error) {
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return nil, geodb.ErrInvalidIP
+	}
+	if ip == "10.0.0.1" {
+		return nil, geodb.ErrPrivateIP
+	}
+	if result, ok := m[ip]; ok {
+		return result, nil
+	}
+	return nil, geodb.ErrIPNotFound
+}
+
+// newTestClient serves the lookup service over an in-memory connection.
+func newTestClient(t *testing.T, opts Options) lookuppb.LookupServiceClient {
+	t.Helper()
+
+	lat, lon := 40.7128, -74.006
+	geo := tableGeoLookup{
+		"8.8.8.8": {CountryCode: "US", City: "New York", Latitude: &lat, Longitude: &lon, IPVersion: "v4"},
+		"1.1.1.1": {CountryCode: "AU", IPVersion: "v4"},
+	}
+
+	ln := bufconn.Listen(1 << 20)
+	s := New(geo, opts)
+	go func() { _ = s.Serve(ln) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return lookuppb.NewLookupServiceClient(conn)
+}
+
+func TestLookup(t *testing.T) {
+	client := newTestClient(t, Options{})
+
+	reply, err := client.Lookup(context.Background(), &lookuppb.LookupRequest{Ip: "8.8.8.8", UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if reply.GetCountryCode() != "US" || reply.GetCity() != "New York" || reply.GetIpVersion() != "v4" {
+		t.Errorf("unexpected reply: %v", reply)
+	}
+	if reply.Latitude == nil || reply.GetLatitude() != 40.7128 {
+		t.Errorf("expected latitude 40.7128, got %v", reply.Latitude)
+	}
+
+	reply, err = client.Lookup(context.Background(), &lookuppb.LookupRequest{Ip: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if !reply.GetPrivate() || reply.GetCountryCode() != "" {
+		t.Errorf("expected a private reply, got %v", reply)
+	}
+}
+
+func TestLookup_Errors(t *testing.T) {
+	client := newTestClient(t, Options{})
+
+	tests := []struct {
+		ip   string
+		code codes.Code
+	}{
+		{"not-an-ip", codes.InvalidArgument},
+		{"9.9.9.9", codes.NotFound},
+	}
+	for _, tt := range tests {
+		_, err := client.Lookup(context.Background(), &lookuppb.LookupRequest{Ip: tt.ip})
+		if got := status.Code(err); got != tt.code {
+			t.Errorf("Lookup(%q) code = %v, want %v", tt.ip, got, tt.code)
+		}
+	}
+}
+
+func TestBatchLookup(t *testing.T) {
+	client := newTestClient(t, Options{})
+
+	stream, err := client.BatchLookup(context.Background())
+	if err != nil {
+		t.Fatalf("BatchLookup() error = %v", err)
+	}
+	ips := []string{"8.8.8.8", "invalid", "1.1.1.1"}
+	for _, ip := range ips {
+		if err := stream.Send(&lookuppb.LookupRequest{Ip: ip}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []*lookuppb.LookupReply
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		replies = append(replies, reply)
+	}
+
+	if len(replies) != len(ips) {
+		t.Fatalf("expected %d replies, got %d", len(ips), len(replies))
+	}
+	if replies[0].GetCountryCode() != "US" || replies[2].GetCountryCode() != "AU" {
+		t.Errorf("unexpected replies: %v", replies)
+	}
+	if replies[1].GetIp() != "invalid" || replies[1].GetError() != "invalid IP address" {
+		t.Errorf("expected a per-IP error, got %v", replies[1])
+	}
+}
+
+func TestAuth(t *testing.T) {
+	auth := middleware.NewAuth(map[string]string{"default": "secret"}, middleware.SchemeBoth)
+	client := newTestClient(t, Options{Auth: auth})
+	req := &lookuppb.LookupRequest{Ip: "8.8.8.8"}
+
+	if _, err := client.Lookup(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without a key, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wrong")
+	if _, err := client.Lookup(ctx, req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated with a wrong key, got %v", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+	if _, err := client.Lookup(ctx, req); err != nil {
+		t.Errorf("expected the API key to be accepted, got %v", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if _, err := client.Lookup(ctx, req); err != nil {
+		t.Errorf("expected the bearer token to be accepted, got %v", err)
+	}
+
+	// Streams are checked too
+	stream, err := client.BatchLookup(context.Background())
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated stream without a key, got %v", err)
+	}
+}
+
+func TestLookup_KeyScope(t *testing.T) {
+	auth := middleware.NewAuth(map[string]string{"partner": "secret"}, middleware.SchemeAPIKey)
+	auth.SetScopes(map[string][]string{"partner": {"country_code"}})
+	client := newTestClient(t, Options{Auth: auth})
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+	reply, err := client.Lookup(ctx, &lookuppb.LookupRequest{Ip: "8.8.8.8", UseCity: true})
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if reply.GetCountryCode() != "US" || reply.GetIp() != "8.8.8.8" {
+		t.Errorf("expected the scoped fields, got %v", reply)
+	}
+	if reply.GetCity() != "" || reply.Latitude != nil || reply.GetIpVersion() != "" {
+		t.Errorf("expected fields outside the scope to be withheld, got %v", reply)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := middleware.NewRateLimiter(0.001, 1, nil)
+	client := newTestClient(t, Options{RateLimit: limit})
+	req := &lookuppb.LookupRequest{Ip: "8.8.8.8"}
+
+	if _, err := client.Lookup(context.Background(), req); err != nil {
+		t.Fatalf("first Lookup() error = %v", err)
+	}
+	var trailer metadata.MD
+	_, err := client.Lookup(context.Background(), req, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted once the burst is used, got %v", err)
+	}
+	if len(trailer.Get("retry-after")) == 0 {
+		t.Error("expected a retry-after trailer")
+	}
+
+	// Streams share the bucket
+	stream, err := client.BatchLookup(context.Background())
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected rate limited stream, got %v", err)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	client := newTestClient(t, Options{Concurrency: middleware.NewConcurrencyLimiter(1)})
+
+	// An open batch stream holds the only slot
+	stream, err := client.BatchLookup(context.Background())
+	if err != nil {
+		t.Fatalf("BatchLookup() error = %v", err)
+	}
+	if err := stream.Send(&lookuppb.LookupRequest{Ip: "8.8.8.8"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+
+	_, err = client.Lookup(context.Background(), &lookuppb.LookupRequest{Ip: "8.8.8.8"})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable while the stream is open, got %v", err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected the stream to end, got %v", err)
+	}
+	if _, err := client.Lookup(context.Background(), &lookuppb.LookupRequest{Ip: "8.8.8.8"}); err != nil {
+		t.Errorf("expected Lookup() to succeed once the stream ended, got %v", err)
+	}
+}
+
+func TestBatchLookup_MaxBatchSize(t *testing.T) {
+	client := newTestClient(t, Options{MaxBatchSize: 2})
+
+	stream, err := client.BatchLookup(context.Background())
+	if err != nil {
+		t.Fatalf("BatchLookup() error = %v", err)
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "8.8.8.8"} {
+		if err := stream.Send(&lookuppb.LookupRequest{Ip: ip}); err != nil {
+			break
+		}
+	}
+	_ = stream.CloseSend()
+
+	var replies int
+	for {
+		_, err = stream.Recv()
+		if err != nil {
+			break
+		}
+		replies++
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted past the batch cap, got %v", err)
+	}
+	if replies != 2 {
+		t.Errorf("expected 2 replies before the cap, got %d", replies)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+// GeoAdmin is the subset of GeoDB used by the admin endpoints.
+type GeoAdmin interface {
+	Refresh(ctx context.Context) []geodb.RefreshStatus
+	Versions() []geodb.DatabaseVersion
+}
+
+// Admin serves the operator endpoints under /admin.
+type Admin struct {
+	geo GeoAdmin
+}
+
+func NewAdmin(geo GeoAdmin) *Admin {
+	return &Admin{geo: geo}
+}
+
+type RefreshResponse struct {
+	Databases []geodb.RefreshStatus `json:"databases"`
+}
+
+// Refresh triggers an immediate download and reload of all databases.
+// Responds 500 if any database failed to update; unchanged databases are
+// not a failure.
+func (a *Admin) Refresh(w http.ResponseWriter, r *http.Request) {
+	// Downloads can outlast the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	statuses := a.geo.Refresh(r.Context())
+
+	status := http.StatusOK
+	for _, s := range statuses {
+		if s.Error != "" {
+			status = http.StatusInternalServerError
+			break
+		}
+	}
+
+	writeJSON(w, status, RefreshResponse{Databases: statuses})
+}
+
+// Databases reports the build metadata of every loaded database, to verify
+// which variant is deployed.
+func (a *Admin) Databases(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.geo.Versions())
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+// mockGeoAdmin implements GeoAdmin for testing
+type mockGeoAdmin struct {
+	statuses []geodb.RefreshStatus
+	versions []geodb.DatabaseVersion
+	calls    int
+}
+
+func (m *mockGeoAdmin) Refresh(ctx context.Context) []geodb.RefreshStatus {
+	m.calls++
+	return m.statuses
+}
+
+func (m *mockGeoAdmin) Versions() []geodb.DatabaseVersion {
+	return m.versions
+}
+
+func TestAdminRefresh_Success(t *testing.T) {
+	mock := &mockGeoAdmin{
+		statuses: []geodb.RefreshStatus{
+			{Database: "country", Updated: true},
+			{Database: "city-ipv4", Updated: true},
+		},
+	}
+	a := NewAdmin(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/refresh", nil)
+	w := httptest.NewRecorder()
+
+	a.Refresh(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if mock.calls != 1 {
+		t.Errorf("expected Refresh to be called once, got %d", mock.calls)
+	}
+
+	var resp RefreshResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Databases) != 2 {
+		t.Errorf("expected 2 database statuses, got %d", len(resp.Databases))
+	}
+}
+
+func TestAdminRefresh_PartialFailure(t *testing.T) {
+	mock := &mockGeoAdmin{
+		statuses: []geodb.RefreshStatus{
+			{Database: "country", Updated: true},
+			{Database: "city-ipv4", Error: "download failed with status: 503"},
+		},
+	}
+	a := NewAdmin(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/refresh", nil)
+	w := httptest.NewRecorder()
+
+	a.Refresh(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	var resp RefreshResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Databases[1].Error == "" {
+		t.Error("expected per-database error in response")
+	}
+}
+
+func TestAdminRefresh_Unchanged(t *testing.T) {
+	mock := &mockGeoAdmin{
+		statuses: []geodb.RefreshStatus{
+			{Database: "country", Updated: true},
+			{Database: "city-ipv4", Unchanged: true},
+		},
+	}
+	a := NewAdmin(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/refresh", nil)
+	w := httptest.NewRecorder()
+
+	a.Refresh(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d for unchanged database, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestAdminDatabases(t *testing.T) {
+	mock := &mockGeoAdmin{
+		versions: []geodb.DatabaseVersion{
+			{Name: "country", Path: "/data/country.mmdb", DatabaseType: "geolite2-country", BuildEpoch: 1700000000, NodeCount: 42, IPVersion: 6},
+			{Name: "city-ipv4", Path: "/data/city-ipv4.mmdb", DatabaseType: "geolite2-city", IPVersion: 4},
+		},
+	}
+	a := NewAdmin(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/databases", nil)
+	w := httptest.NewRecorder()
+
+	a.Databases(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 2 {
+		t.Fatalf("expected 2 databases, got %d", len(resp))
+	}
+	if resp[0]["name"] != "country" || resp[0]["database_type"] != "geolite2-country" || resp[0]["node_count"] != float64(42) {
+		t.Errorf("unexpected first database: %v", resp[0])
+	}
+	if resp[1]["ip_version"] != float64(4) {
+		t.Errorf("expected city-ipv4 ip_version 4, got %v", resp[1]["ip_version"])
+	}
+}
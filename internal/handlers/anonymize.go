@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/burakcan/ipburack/internal/clientip"
+)
+
+// AnonymizeIP applies clientip.Anonymize to a textual address. Anything that
+// doesn't parse as an IP is returned unchanged.
+func AnonymizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return clientip.Anonymize(addr).String()
+}
+
+// anonymizeHostPort anonymizes the host of a RemoteAddr, keeping the port.
+func anonymizeHostPort(hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return AnonymizeIP(hostport)
+	}
+	return net.JoinHostPort(AnonymizeIP(host), port)
+}
+
+// anonymizeHops anonymizes every address in an X-Forwarded-For style value,
+// keeping the entries that aren't addresses.
+func anonymizeHops(value string) string {
+	hops := strings.Split(value, ",")
+	for i, hop := range hops {
+		trimmed := strings.TrimSpace(hop)
+		hops[i] = strings.Replace(hop, trimmed, AnonymizeIP(trimmed), 1)
+	}
+	return strings.Join(hops, ",")
+}
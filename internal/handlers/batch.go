@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// maxBatchBodyBytes bounds the request body so an oversized payload is
+// rejected before it is fully decoded.
+const maxBatchBodyBytes = 1 << 20
+
+// BatchResult is the per-IP entry in a batch lookup response. Exactly one of
+// the embedded lookup fields or Error and Code is set.
+type BatchResult struct {
+	IP string `json:"ip"`
+	*LookupResponse
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// batchCSVHeader names the columns of a ?format=csv batch response.
+var batchCSVHeader = []string{"ip", "country_code", "postal_code", "error"}
+
+// LookupBatch resolves a JSON array of IPs. Each IP is resolved independently,
+// so one invalid entry doesn't fail the whole request. With ?format=csv the
+// results are returned as CSV rows; request errors stay JSON.
+func (h *Handlers) LookupBatch(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)
+
+	var ips []string
+	if err := json.NewDecoder(r.Body).Decode(&ips); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large", Code: CodeBodyTooLarge})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "request body must be a JSON array of IP addresses", Code: CodeInvalidBody})
+		return
+	}
+
+	if len(ips) > h.maxBatchSize {
+		writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "too many IP addresses in batch", Code: CodeTooManyIPs})
+		return
+	}
+
+	opts := parseLookupOptions(r)
+	// PTR lookups run afterwards, in parallel and under one deadline
+	rdns := opts.rdns
+	opts.rdns = false
+	results := make([]BatchResult, len(ips))
+	for i, ip := range ips {
+		results[i].IP = ip
+		resp, _, err := h.resolve(r.Context(), ip, opts)
+		// Once the request is over the remaining IPs aren't worth resolving
+		if ctxErr := r.Context().Err(); ctxErr != nil {
+			status, code, msg := lookupError(ctxErr)
+			writeJSON(w, status, ErrorResponse{Error: msg, Code: code})
+			return
+		}
+		if err != nil {
+			_, results[i].Code, results[i].Error = lookupError(err)
+			continue
+		}
+		results[i].LookupResponse = resp
+	}
+	if rdns {
+		h.reverseLookupBatch(r.Context(), results)
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeBatchCSV(w, results)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.batchResultsJSON(results))
+}
+
+// writeBatchCSV writes results as RFC 4180 CSV with a header line. The IP
+// column echoes client input, so cells are escaped against formula injection.
+func writeBatchCSV(w http.ResponseWriter, results []BatchResult) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	_ = cw.Write(batchCSVHeader)
+	for _, result := range results {
+		row := []string{result.IP, "", "", result.Error}
+		if result.LookupResponse != nil {
+			row[1] = result.CountryCode
+			row[2] = result.PostalCode
+		}
+		for i := range row {
+			row[i] = csvCell(row[i])
+		}
+		_ = cw.Write(row)
+	}
+	cw.Flush()
+}
+
+// csvCell prefixes a cell that a spreadsheet would evaluate as a formula
+// with a quote, so it's shown as text instead.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+// tableGeoLookup resolves IPs from a fixed table, mimicking GeoDB's errors
+type tableGeoLookup map[string]*geodb.LookupResult
+
+func (m tableGeoLookup) LookupCtx(ctx context.Context, ip string, opts geodb.LookupOptions) (*geodb.LookupResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return nil, geodb.ErrInvalidIP
+	}
+	if result, ok := m[ip]; ok {
+		return result, nil
+	}
+	return nil, geodb.ErrIPNotFound
+}
+
+func (m tableGeoLookup) LookupPrefixCtx(ctx context.Context, prefix string) (*geodb.PrefixResult, error) {
+	return nil, geodb.ErrInvalidPrefix
+}
+
+func (m tableGeoLookup) Databases() map[string]geodb.DatabaseInfo {
+	return nil
+}
+
+func (m tableGeoLookup) Ready() map[string]bool {
+	return nil
+}
+
+func (m tableGeoLookup) Check() map[string]error {
+	return nil
+}
+
+func TestLookupBatch_Success(t *testing.T) {
+	geo := tableGeoLookup{
+		"8.8.8.8": {CountryCode: "US"},
+		"1.1.1.1": {CountryCode: "AU"},
+	}
+	h := New(geo, Options{})
+
+	body := strings.NewReader(`["8.8.8.8", "1.1.1.1"]`)
+	req := httptest.NewRequest(http.MethodPost, "/lookup/batch", body)
+	w := httptest.NewRecorder()
+
+	h.LookupBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp []BatchResult
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(resp))
+	}
+
+	if resp[0].IP != "8.8.8.8" || resp[0].LookupResponse == nil || resp[0].CountryCode != "US" {
+		t.Errorf("unexpected first result: %+v", resp[0])
+	}
+
+	if resp[1].IP != "1.1.1.1" || resp[1].LookupResponse == nil || resp[1].CountryCode != "AU" {
+		t.Errorf("unexpected second result: %+v", resp[1])
+	}
+}
+
+func TestLookupBatch_PartialFailure(t *testing.T) {
+	geo := tableGeoLookup{
+		"8.8.8.8": {CountryCode: "US"},
+	}
+	h := New(geo, Options{})
+
+	body := strings.NewReader(`["8.8.8.8", "invalid", "192.168.1.1"]`)
+	req := httptest.NewRequest(http.MethodPost, "/lookup/batch", body)
+	w := httptest.NewRecorder()
+
+	h.LookupBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp []BatchResult
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(resp))
+	}
+
+	if resp[0].Error != "" || resp[0].CountryCode != "US" {
+		t.Errorf("expected first entry to succeed, got %+v", resp[0])
+	}
+
+	if resp[1].Error != "invalid IP address" || resp[1].Code != CodeInvalidIP {
+		t.Errorf("expected invalid IP error, got %q (%s)", resp[1].Error, resp[1].Code)
+	}
+
+	if resp[2].Error != "IP not found in database" || resp[2].Code != CodeNotFound {
+		t.Errorf("expected not found error, got %q (%s)", resp[2].Error, resp[2].Code)
+	}
+}
+
+func TestLookupBatch_TooLarge(t *testing.T) {
+	h := New(tableGeoLookup{}, Options{MaxBatchSize: 2})
+
+	body := strings.NewReader(`["8.8.8.8", "1.1.1.1", "9.9.9.9"]`)
+	req := httptest.NewRequest(http.MethodPost, "/lookup/batch", body)
+	w := httptest.NewRecorder()
+
+	h.LookupBatch(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestLookupBatch_InvalidBody(t *testing.T) {
+	h := New(tableGeoLookup{}, Options{})
+
+	body := strings.NewReader(`{"ip": "8.8.8.8"}`)
+	req := httptest.NewRequest(http.MethodPost, "/lookup/batch", body)
+	w := httptest.NewRecorder()
+
+	h.LookupBatch(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestLookupBatch_Canceled(t *testing.T) {
+	h := New(tableGeoLookup{"8.8.8.8": {CountryCode: "US"}}, Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	body := strings.NewReader(`["8.8.8.8", "1.1.1.1"]`)
+	req := httptest.NewRequest(http.MethodPost, "/lookup/batch", body).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	h.LookupBatch(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestLookupBatch_CSV(t *testing.T) {
+	geo := tableGeoLookup{
+		"8.8.8.8": {CountryCode: "US", PostalCode: "10001"},
+	}
+	h := New(geo, Options{})
+
+	body := strings.NewReader(`["8.8.8.8", "bad,\"ip\"", "=HYPERLINK(\"http://x\")", "@SUM(1)"]`)
+	req := httptest.NewRequest(http.MethodPost, "/lookup/batch?format=csv&pc=true", body)
+	w := httptest.NewRecorder()
+
+	h.LookupBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("expected Content-Type text/csv, got %s", ct)
+	}
+
+	want := "ip,country_code,postal_code,error\r\n" +
+		"8.8.8.8,US,10001,\r\n" +
+		"\"bad,\"\"ip\"\"\",,,invalid IP address\r\n" +
+		"\"'=HYPERLINK(\"\"http://x\"\")\",,,invalid IP address\r\n" +
+		"'@SUM(1),,,invalid IP address\r\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected CSV body:\n%q\nwant:\n%q", got, want)
+	}
+}
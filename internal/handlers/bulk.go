@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultMaxBulkLines is used when Options.MaxBulkLines is not set.
+const DefaultMaxBulkLines = 100000
+
+const (
+	// maxBulkLineBytes bounds a single input line; nothing longer is an IP.
+	maxBulkLineBytes = 256
+	// bulkFlushEvery is how many results are written between flushes.
+	bulkFlushEvery = 100
+	// bulkIdleTimeout is how long a bulk request may go without the client
+	// sending a line or reading results before the connection is dropped.
+	bulkIdleTimeout = 30 * time.Second
+)
+
+// LookupBulk resolves a newline-delimited list of IPs, taken from the raw
+// body or from the "file" part of a multipart upload, and streams one JSON
+// result per line. Blank lines are skipped and invalid IPs get a per-line
+// error. Once streaming has started, a problem with the input as a whole
+// (too many lines, a line too long) ends the stream with an error object.
+func (h *Handlers) LookupBulk(w http.ResponseWriter, r *http.Request) {
+	body, err := bulkInput(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error(), Code: CodeInvalidBody})
+		return
+	}
+
+	// Large uploads can outlast the server's timeouts, so the deadlines are
+	// pushed back as each line comes in instead. A client that stalls,
+	// sending or reading, still loses the connection. Results are written
+	// while the body is still being read.
+	rc := http.NewResponseController(w)
+	extendDeadlines := func() {
+		deadline := time.Now().Add(h.bulkIdle)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+	}
+	extendDeadlines()
+	_ = rc.EnableFullDuplex()
+
+	ctx := r.Context()
+	opts := parseLookupOptions(r)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, maxBulkLineBytes), maxBulkLineBytes)
+
+	lines := 0
+	for scanner.Scan() {
+		extendDeadlines()
+		ip := strings.TrimSpace(scanner.Text())
+		if ip == "" {
+			continue
+		}
+		if lines == h.maxBulkLines {
+			_ = enc.Encode(ErrorResponse{Error: fmt.Sprintf("too many lines, limit is %d", h.maxBulkLines), Code: CodeTooManyIPs})
+			return
+		}
+		lines++
+
+		result := BatchResult{IP: ip}
+		resp, _, err := h.resolve(ctx, ip, opts)
+		// Nobody is left to read the rest of the results
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			_, result.Code, result.Error = lookupError(err)
+		} else {
+			result.LookupResponse = resp
+		}
+		if err := enc.Encode(h.batchJSON(result)); err != nil {
+			return
+		}
+
+		if lines%bulkFlushEvery == 0 {
+			_ = rc.Flush()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		// The read deadline may be what ended the input; the error line
+		// still gets a full window to be written
+		extendDeadlines()
+		resp := ErrorResponse{Error: "failed to read request body", Code: CodeInvalidBody}
+		if errors.Is(err, bufio.ErrTooLong) {
+			resp = ErrorResponse{Error: fmt.Sprintf("line %d is too long", lines+1), Code: CodeLineTooLong}
+		}
+		_ = enc.Encode(resp)
+	}
+}
+
+// bulkInput returns the reader holding the IP list: the "file" part of a
+// multipart/form-data upload, or the raw body otherwise.
+func bulkInput(r *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("invalid multipart body")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, errors.New(`multipart body must include a "file" part`)
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
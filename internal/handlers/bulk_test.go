@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+// decodeNDJSON decodes one BatchResult per line of body.
+func decodeNDJSON(t *testing.T, body io.Reader) []BatchResult {
+	t.Helper()
+
+	var results []BatchResult
+	dec := json.NewDecoder(body)
+	for dec.More() {
+		var result BatchResult
+		if err := dec.Decode(&result); err != nil {
+			t.Fatalf("failed to decode line %d: %v", len(results)+1, err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestLookupBulk(t *testing.T) {
+	geo := tableGeoLookup{
+		"8.8.8.8": {CountryCode: "US"},
+		"1.1.1.1": {CountryCode: "AU"},
+	}
+	h := New(geo, Options{})
+
+	body := strings.NewReader("8.8.8.8\n\n  \ninvalid\r\n192.0.2.1\n1.1.1.1")
+	req := httptest.NewRequest(http.MethodPost, "/lookup/bulk", body)
+	w := httptest.NewRecorder()
+
+	h.LookupBulk(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected Content-Type application/x-ndjson, got %s", ct)
+	}
+
+	results := decodeNDJSON(t, w.Body)
+	want := []struct{ ip, country, err string }{
+		{"8.8.8.8", "US", ""},
+		{"invalid", "", "invalid IP address"},
+		{"192.0.2.1", "", "IP not found in database"},
+		{"1.1.1.1", "AU", ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+	for i, tt := range want {
+		got := results[i]
+		if got.IP != tt.ip || got.Error != tt.err {
+			t.Errorf("result %d: expected ip %q error %q, got %q %q", i, tt.ip, tt.err, got.IP, got.Error)
+		}
+		if tt.country != "" && (got.LookupResponse == nil || got.CountryCode != tt.country) {
+			t.Errorf("result %d: expected country %s, got %+v", i, tt.country, got.LookupResponse)
+		}
+	}
+}
+
+func TestLookupBulk_Multipart(t *testing.T) {
+	h := New(tableGeoLookup{"8.8.8.8": {CountryCode: "US"}}, Options{})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("note", "ignored")
+	fw, err := mw.CreateFormFile("file", "ips.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(fw, "8.8.8.8\n")
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/lookup/bulk", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	h.LookupBulk(w, req)
+
+	results := decodeNDJSON(t, w.Body)
+	if len(results) != 1 || results[0].LookupResponse == nil || results[0].CountryCode != "US" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestLookupBulk_MissingFilePart(t *testing.T) {
+	h := New(tableGeoLookup{}, Options{})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("ips", "8.8.8.8")
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/lookup/bulk", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	h.LookupBulk(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestLookupBulk_InputErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		results int
+		wantErr string
+	}{
+		{"too many lines", "8.8.8.8\n\n1.1.1.1\n9.9.9.9\n", 2, "too many lines, limit is 2"},
+		{"line too long", "8.8.8.8\n" + strings.Repeat("1", maxBulkLineBytes+1) + "\n", 1, "line 2 is too long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tableGeoLookup{}, Options{MaxBulkLines: 2})
+
+			req := httptest.NewRequest(http.MethodPost, "/lookup/bulk", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.LookupBulk(w, req)
+
+			results := decodeNDJSON(t, w.Body)
+			if len(results) != tt.results+1 {
+				t.Fatalf("expected %d results and an error line, got %+v", tt.results, results)
+			}
+			last := results[len(results)-1]
+			if last.IP != "" || last.Error != tt.wantErr {
+				t.Errorf("expected final error %q, got %+v", tt.wantErr, last)
+			}
+		})
+	}
+}
+
+// TestLookupBulk_Streams checks results arrive while the upload is still
+// open, over a real HTTP/1.1 connection.
+func TestLookupBulk_Streams(t *testing.T) {
+	geo := tableGeoLookup{}
+	for i := range bulkFlushEvery {
+		geo[fmt.Sprintf("192.0.2.%d", i)] = &geodb.LookupResult{CountryCode: "US"}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(New(geo, Options{}).LookupBulk))
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		for i := range bulkFlushEvery {
+			fmt.Fprintf(pw, "192.0.2.%d\n", i)
+		}
+	}()
+
+	resp, err := http.Post(srv.URL, "text/plain", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	lines := bufio.NewScanner(resp.Body)
+	for i := range bulkFlushEvery {
+		if !lines.Scan() {
+			t.Fatalf("stream ended after %d results: %v", i, lines.Err())
+		}
+	}
+}
+
+// TestLookupBulk_StalledClient checks a client that stops sending is cut
+// off once the idle timeout passes rather than holding the connection.
+func TestLookupBulk_StalledClient(t *testing.T) {
+	h := New(tableGeoLookup{"8.8.8.8": {CountryCode: "US"}}, Options{})
+	h.bulkIdle = 100 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(h.LookupBulk))
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		// One line, then nothing more
+		fmt.Fprintf(pw, "8.8.8.8\n")
+	}()
+
+	done := make(chan []byte, 1)
+	go func() {
+		resp, err := http.Post(srv.URL, "text/plain", pr)
+		if err != nil {
+			done <- nil
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		done <- body
+	}()
+	select {
+	case body := <-done:
+		if !strings.Contains(string(body), `"country_code":"US"`) {
+			t.Errorf("expected the line sent before stalling to be answered, got %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stalled bulk request was never cut off")
+	}
+}
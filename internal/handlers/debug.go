@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/burakcan/ipburack/internal/clientip"
+)
+
+// DebugResponse explains how the caller's address was determined.
+type DebugResponse struct {
+	ClientIP string `json:"client_ip"`
+	// Source is the header the address came from, or "RemoteAddr"
+	Source     string `json:"source"`
+	RemoteAddr string `json:"remote_addr"`
+	// HeadersHonored is false when the peer isn't a trusted proxy, so
+	// forwarding headers were ignored
+	HeadersHonored bool `json:"headers_honored"`
+	// Headers holds the raw values of every header that was considered
+	Headers     map[string][]string `json:"headers"`
+	Lookup      *LookupResponse     `json:"lookup,omitempty"`
+	LookupError *ErrorResponse      `json:"lookup_error,omitempty"`
+}
+
+// LookupDebug reports which source won client IP detection, to diagnose
+// proxy setups. The address is only geolocated with ?lookup=true, which
+// takes the same flags as /lookup. With AnonymizeIPs, every address shown,
+// and the one looked up, is anonymized.
+func (h *Handlers) LookupDebug(w http.ResponseWriter, r *http.Request) {
+	ip, source := h.clientIP.Resolve(r)
+	resp := DebugResponse{
+		ClientIP:       ip,
+		Source:         source,
+		RemoteAddr:     r.RemoteAddr,
+		HeadersHonored: h.clientIP.HonorsHeaders(r),
+		Headers:        make(map[string][]string),
+	}
+
+	names := append(slices.Clone(clientip.DefaultHeaders), h.clientIP.Headers()...)
+	for _, name := range names {
+		values := r.Header.Values(name)
+		if values == nil {
+			values = []string{}
+		}
+		resp.Headers[http.CanonicalHeaderKey(name)] = values
+	}
+
+	if h.anonymize {
+		resp.ClientIP = AnonymizeIP(resp.ClientIP)
+		resp.RemoteAddr = anonymizeHostPort(resp.RemoteAddr)
+		for name, values := range resp.Headers {
+			anonymized := make([]string, len(values))
+			for i, v := range values {
+				anonymized[i] = anonymizeHops(v)
+			}
+			resp.Headers[name] = anonymized
+		}
+	}
+
+	if r.URL.Query().Get("lookup") == "true" {
+		result, _, err := h.resolve(r.Context(), resp.ClientIP, parseLookupOptions(r))
+		if err != nil {
+			_, code, msg := lookupError(err)
+			resp.LookupError = &ErrorResponse{Error: msg, Code: code}
+		} else {
+			resp.Lookup = result
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
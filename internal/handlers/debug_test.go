@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/clientip"
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+func TestLookupDebug(t *testing.T) {
+	mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+	resolver := clientip.New([]string{"CF-Connecting-IP", "X-Forwarded-For"}, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	h := New(mock, Options{ClientIP: resolver})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/debug", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+	req.Header.Set("X-Real-IP", "198.51.100.1")
+	w := httptest.NewRecorder()
+
+	h.LookupDebug(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp DebugResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.ClientIP != "203.0.113.7" || resp.Source != "X-Forwarded-For" {
+		t.Errorf("expected 203.0.113.7 from X-Forwarded-For, got %s from %s", resp.ClientIP, resp.Source)
+	}
+	if resp.RemoteAddr != "10.0.0.1:4321" || !resp.HeadersHonored {
+		t.Errorf("unexpected peer details: %+v", resp)
+	}
+	// Default and configured headers are all reported, present or not
+	want := map[string][]string{
+		"X-Forwarded-For":  {"203.0.113.7, 10.0.0.2"},
+		"X-Real-Ip":        {"198.51.100.1"},
+		"Cf-Connecting-Ip": {},
+	}
+	for name, values := range want {
+		if got, ok := resp.Headers[name]; !ok || !slices.Equal(got, values) {
+			t.Errorf("expected header %s = %q, got %q", name, values, got)
+		}
+	}
+	if resp.Lookup != nil || mock.lastIP != "" {
+		t.Error("expected no geo lookup without ?lookup=true")
+	}
+}
+
+func TestLookupDebug_UntrustedPeer(t *testing.T) {
+	resolver := clientip.New(nil, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	h := New(&mockGeoLookup{}, Options{ClientIP: resolver})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/debug", nil)
+	req.RemoteAddr = "192.0.2.9:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w := httptest.NewRecorder()
+
+	h.LookupDebug(w, req)
+
+	var resp DebugResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ClientIP != "192.0.2.9" || resp.Source != clientip.SourceRemoteAddr || resp.HeadersHonored {
+		t.Errorf("expected headers to be ignored for an untrusted peer, got %+v", resp)
+	}
+}
+
+func TestLookupDebug_Anonymize(t *testing.T) {
+	mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+	resolver := clientip.New(nil, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	h := New(mock, Options{ClientIP: resolver, AnonymizeIPs: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/debug?lookup=true", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+	req.Header.Set("X-Real-IP", "unknown")
+	w := httptest.NewRecorder()
+
+	h.LookupDebug(w, req)
+
+	var resp DebugResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ClientIP != "203.0.113.0" || resp.RemoteAddr != "10.0.0.0:4321" {
+		t.Errorf("expected anonymized addresses, got %+v", resp)
+	}
+	if got := resp.Headers["X-Forwarded-For"]; !slices.Equal(got, []string{"203.0.113.0, 10.0.0.0"}) {
+		t.Errorf("expected anonymized header values, got %q", got)
+	}
+	// Values that aren't addresses are shown as they are
+	if got := resp.Headers["X-Real-Ip"]; !slices.Equal(got, []string{"unknown"}) {
+		t.Errorf("expected a non-address value to be kept, got %q", got)
+	}
+	if mock.lastIP != "203.0.113.0" || resp.Lookup == nil {
+		t.Errorf("expected the anonymized address to be looked up, got %q", mock.lastIP)
+	}
+}
+
+func TestLookupDebug_WithLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+	}{
+		{name: "found"},
+		{name: "not found", err: geodb.ErrIPNotFound, wantCode: CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US", PostalCode: "10001"}, err: tt.err}
+			h := New(mock, Options{})
+
+			req := httptest.NewRequest(http.MethodGet, "/lookup/debug?lookup=true&pc=true", nil)
+			req.RemoteAddr = "203.0.113.7:4321"
+			w := httptest.NewRecorder()
+
+			h.LookupDebug(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			var resp DebugResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if mock.lastIP != "203.0.113.7" || !mock.lastOpts.UseCity {
+				t.Errorf("expected a city lookup of the client IP, got %q %+v", mock.lastIP, mock.lastOpts)
+			}
+			if tt.err != nil {
+				if resp.Lookup != nil || resp.LookupError == nil || resp.LookupError.Code != tt.wantCode {
+					t.Errorf("expected lookup error %s, got %+v", tt.wantCode, resp)
+				}
+				return
+			}
+			if resp.Lookup == nil || resp.Lookup.PostalCode != "10001" {
+				t.Errorf("expected lookup result, got %+v", resp.Lookup)
+			}
+		})
+	}
+}
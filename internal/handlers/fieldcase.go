@@ -0,0 +1,80 @@
+package handlers
+
+import "encoding/xml"
+
+// FieldCase selects how the JSON fields of lookup responses are named.
+type FieldCase string
+
+const (
+	// CaseSnake names fields like country_code
+	CaseSnake FieldCase = "snake"
+	// CaseCamel names fields like countryCode
+	CaseCamel FieldCase = "camel"
+)
+
+// camelLookupResponse is LookupResponse with camelCase JSON names. Its fields
+// must stay identical to LookupResponse's, tags aside, so a response converts
+// to it without copying.
+type camelLookupResponse struct {
+	XMLName        xml.Name `json:"-" xml:"lookup"`
+	CountryCode    string   `json:"countryCode" xml:"country_code"`
+	CountryName    string   `json:"countryName,omitempty" xml:"country_name,omitempty"`
+	ContinentCode  string   `json:"continentCode,omitempty" xml:"continent_code,omitempty"`
+	Flag           string   `json:"flag,omitempty" xml:"flag,omitempty"`
+	IsInEU         *bool    `json:"isInEu,omitempty" xml:"is_in_eu,omitempty"`
+	PostalCode     string   `json:"postalCode,omitempty" xml:"postal_code,omitempty"`
+	Region         string   `json:"region,omitempty" xml:"region,omitempty"`
+	City           string   `json:"city,omitempty" xml:"city,omitempty"`
+	Latitude       *float64 `json:"latitude,omitempty" xml:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty" xml:"longitude,omitempty"`
+	AccuracyRadius uint16   `json:"accuracyRadius,omitempty" xml:"accuracy_radius,omitempty"`
+	ASN            uint     `json:"asn,omitempty" xml:"asn,omitempty"`
+	ASOrg          string   `json:"asOrg,omitempty" xml:"as_org,omitempty"`
+	Hostname       string   `json:"hostname,omitempty" xml:"hostname,omitempty"`
+	Timezone       string   `json:"timezone,omitempty" xml:"timezone,omitempty"`
+	IPVersion      string   `json:"ipVersion,omitempty" xml:"ip_version,omitempty"`
+	Private        bool     `json:"private,omitempty" xml:"private,omitempty"`
+	ResolvedIP     string   `json:"resolvedIp,omitempty" xml:"resolved_ip,omitempty"`
+	Network        string   `json:"network,omitempty" xml:"network,omitempty"`
+}
+
+// camelBatchResult is BatchResult with camelCase JSON names.
+type camelBatchResult struct {
+	IP string `json:"ip"`
+	*camelLookupResponse
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// lookupJSON returns resp for JSON encoding in the configured field case.
+func (h *Handlers) lookupJSON(resp *LookupResponse) any {
+	if !h.camel {
+		return resp
+	}
+	return (*camelLookupResponse)(resp)
+}
+
+// batchJSON returns result for JSON encoding in the configured field case.
+func (h *Handlers) batchJSON(result BatchResult) any {
+	if !h.camel {
+		return result
+	}
+	return camelBatchResult{
+		IP:                  result.IP,
+		camelLookupResponse: (*camelLookupResponse)(result.LookupResponse),
+		Error:               result.Error,
+		Code:                result.Code,
+	}
+}
+
+// batchResultsJSON is batchJSON for a whole batch.
+func (h *Handlers) batchResultsJSON(results []BatchResult) any {
+	if !h.camel {
+		return results
+	}
+	out := make([]any, len(results))
+	for i, result := range results {
+		out[i] = h.batchJSON(result)
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+// camelCase turns a snake_case JSON tag into its camelCase form.
+func camelCase(tag string) string {
+	parts := strings.Split(tag, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			r := []rune(parts[i])
+			r[0] = unicode.ToUpper(r[0])
+			parts[i] = string(r)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func TestCamelLookupResponse_Fields(t *testing.T) {
+	snake := reflect.TypeFor[LookupResponse]()
+	camel := reflect.TypeFor[camelLookupResponse]()
+	if snake.NumField() != camel.NumField() {
+		t.Fatalf("LookupResponse has %d fields, camelLookupResponse %d", snake.NumField(), camel.NumField())
+	}
+	for i := range snake.NumField() {
+		s, c := snake.Field(i), camel.Field(i)
+		if s.Name != c.Name || s.Type != c.Type {
+			t.Errorf("field %d: %s %s vs %s %s", i, s.Name, s.Type, c.Name, c.Type)
+		}
+		if want := camelCase(s.Tag.Get("json")); c.Tag.Get("json") != want {
+			t.Errorf("%s: expected json tag %q, got %q", s.Name, want, c.Tag.Get("json"))
+		}
+		if s.Tag.Get("xml") != c.Tag.Get("xml") {
+			t.Errorf("%s: expected xml tag %q, got %q", s.Name, s.Tag.Get("xml"), c.Tag.Get("xml"))
+		}
+	}
+}
+
+func TestLookupIP_CamelCase(t *testing.T) {
+	geo := tableGeoLookup{"8.8.8.8": {CountryCode: "US", ASN: 15169, ASOrg: "Google LLC"}}
+	h := New(geo, Options{FieldCase: CaseCamel})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?asn=true", nil)
+	w := httptest.NewRecorder()
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	want := `{"countryCode":"US","asn":15169,"asOrg":"Google LLC"}`
+	if body := strings.TrimSpace(w.Body.String()); body != want {
+		t.Errorf("expected %s, got %s", want, body)
+	}
+
+	// Batch results follow the same naming
+	req = httptest.NewRequest(http.MethodPost, "/lookup/batch", strings.NewReader(`["8.8.8.8", "bogus"]`))
+	w = httptest.NewRecorder()
+	h.LookupBatch(w, req)
+
+	want = `[{"ip":"8.8.8.8","countryCode":"US"},{"ip":"bogus","error":"invalid IP address","code":"invalid_ip"}]`
+	if body := strings.TrimSpace(w.Body.String()); body != want {
+		t.Errorf("expected %s, got %s", want, body)
+	}
+}
+
+func TestLookupIP_SnakeCaseDefault(t *testing.T) {
+	h := New(&mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+	w := httptest.NewRecorder()
+	h.LookupIP(w, req)
+
+	if body := strings.TrimSpace(w.Body.String()); !strings.Contains(body, `"country_code":"US"`) {
+		t.Errorf("expected snake_case fields by default, got %s", body)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// responseFormat is the representation a lookup is written in.
+type responseFormat int
+
+const (
+	formatJSON responseFormat = iota
+	formatText                // just the country code as text/plain
+	formatXML
+)
+
+// acceptFormats maps the Accept media ranges that select a format other
+// than JSON.
+var acceptFormats = map[string]responseFormat{
+	"text/plain":      formatText,
+	"text/*":          formatText,
+	"application/xml": formatXML,
+	"text/xml":        formatXML,
+}
+
+// negotiateFormat picks the response format from ?format=, falling back to
+// the Accept header. The media range with the highest q-value wins, with
+// anything we don't produce counting as JSON, so text or XML is only chosen
+// when the client strictly prefers it: a browser asking for text/html first
+// gets JSON. Unknown ?format values select JSON. q is the already parsed
+// query string.
+func negotiateFormat(r *http.Request, q url.Values) responseFormat {
+	if format := q.Get("format"); format != "" {
+		switch format {
+		case "text":
+			return formatText
+		case "xml":
+			return formatXML
+		default:
+			return formatJSON
+		}
+	}
+
+	best, bestQ := formatJSON, 0.0
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, _ := strings.Cut(mediaRange, ";")
+			weight := acceptQ(params)
+			// q=0 means not acceptable at all
+			if weight <= 0 {
+				continue
+			}
+			format, ok := acceptFormats[strings.ToLower(strings.TrimSpace(mediaType))]
+			if !ok {
+				format = formatJSON
+			}
+			if weight > bestQ || (weight == bestQ && format == formatJSON) {
+				best, bestQ = format, weight
+			}
+		}
+	}
+	return best
+}
+
+// acceptQ returns the q-value in a media range's parameters, 1 if absent.
+func acceptQ(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(strings.TrimSpace(name), "q") {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return 0
+			}
+			return q
+		}
+	}
+	return 1
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	// Encoding up front means a failure can still be reported as a 500
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		buf.Reset()
+		buf.WriteString(xml.Header)
+		status = http.StatusInternalServerError
+		_ = xml.NewEncoder(&buf).Encode(ErrorResponse{Error: "encoding failed", Code: CodeInternal})
+	}
+	buf.WriteByte('\n')
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   responseFormat
+	}{
+		{name: "default", want: formatJSON},
+		{name: "format text", query: "format=text", want: formatText},
+		{name: "format xml", query: "format=xml", want: formatXML},
+		{name: "format json overrides Accept", query: "format=json", accept: "application/xml", want: formatJSON},
+		{name: "unknown format is JSON", query: "format=yaml", accept: "text/plain", want: formatJSON},
+		{name: "Accept application/xml", accept: "application/xml", want: formatXML},
+		{name: "Accept text/xml", accept: "text/xml; charset=utf-8", want: formatXML},
+		{name: "Accept prefers JSON", accept: "application/json, application/xml", want: formatJSON},
+		{name: "Accept unknown", accept: "application/yaml", want: formatJSON},
+		{name: "Accept text/plain", accept: "text/plain", want: formatText},
+		{name: "Accept q prefers XML", accept: "application/json;q=0.5, application/xml", want: formatXML},
+		{name: "Accept q prefers JSON", accept: "application/xml;q=0.9, application/json", want: formatJSON},
+		{name: "Accept q tie is JSON", accept: "application/xml;q=0.8, application/json;q=0.8", want: formatJSON},
+		{name: "Accept q=0 is excluded", accept: "application/xml;q=0, */*;q=0.1", want: formatJSON},
+		{name: "Accept XML over wildcard", accept: "application/xml, */*;q=0.1", want: formatXML},
+		{name: "browser Accept", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: formatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if got := negotiateFormat(req, req.URL.Query()); got != tt.want {
+				t.Errorf("negotiateFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupIP_VaryAccept(t *testing.T) {
+	h := New(&mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	h.LookupIP(w, req)
+
+	if got := w.Header().Get("Vary"); got != "Accept" {
+		t.Errorf("expected Vary: Accept, got %q", got)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("expected XML, got %s", ct)
+	}
+}
+
+func TestLookupIP_XMLFormat(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", PostalCode: "10001", City: "New York"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?format=xml&city=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+		t.Errorf("expected Content-Type application/xml, got %s", ct)
+	}
+
+	want := xml.Header + "<lookup><country_code>US</country_code><postal_code>10001</postal_code><city>New York</city></lookup>\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected body:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestLookupIP_XMLFormatError(t *testing.T) {
+	h := New(&mockGeoLookup{err: geodb.ErrIPNotFound}, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/192.0.2.1", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	var resp ErrorResponse
+	if err := xml.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != CodeNotFound || resp.Error != "IP not found in database" {
+		t.Errorf("unexpected error response: %+v", resp)
+	}
+}
+
+func TestLookupIP_PrefixXML(t *testing.T) {
+	mock := &mockGeoLookup{
+		prefix: &geodb.PrefixResult{
+			Prefix:    "203.0.113.0/24",
+			Mixed:     true,
+			Countries: []geodb.CountryCount{{CountryCode: "US", Networks: 2}, {CountryCode: "CA", Networks: 1}},
+		},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/203.0.113.0/24?format=xml", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if !strings.Contains(w.Body.String(), "<countries><country><country_code>US</country_code><networks>2</networks></country>") {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
@@ -1,123 +1,861 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"io"
 	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/burakcan/ipburack/internal/clientip"
 	"github.com/burakcan/ipburack/internal/geodb"
+	"github.com/burakcan/ipburack/internal/iso"
+	"github.com/burakcan/ipburack/internal/metrics"
+	"github.com/burakcan/ipburack/internal/tz"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/burakcan/ipburack/internal/handlers")
+
 type GeoLookup interface {
-	Lookup(ip string, useCity bool) (*geodb.LookupResult, error)
+	LookupCtx(ctx context.Context, ip string, opts geodb.LookupOptions) (*geodb.LookupResult, error)
+	LookupPrefixCtx(ctx context.Context, prefix string) (*geodb.PrefixResult, error)
+	Databases() map[string]geodb.DatabaseInfo
+	// Ready reports whether each configured database is loaded
+	Ready() map[string]bool
+	// Check reports whether each configured database can be read
+	Check() map[string]error
+}
+
+// DefaultMaxBatchSize is used when Options.MaxBatchSize is not set.
+const DefaultMaxBatchSize = 100
+
+// LookupMode selects which database a lookup tries first when the request
+// doesn't say.
+type LookupMode string
+
+const (
+	// ModeCountry tries the country database first
+	ModeCountry LookupMode = "country"
+	// ModeCity tries the city databases first, so postal codes are included
+	ModeCity LookupMode = "city"
+)
+
+// Metrics receives per-lookup observations.
+type Metrics interface {
+	ObserveLookup(status string, d time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveLookup(string, time.Duration) {}
+
+// Logger matches logger.Logger so tests can substitute their own.
+type Logger interface {
+	Info(message string, data map[string]any)
+	Warn(message string, data map[string]any)
+	Error(message string, data map[string]any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string, map[string]any)  {}
+func (nopLogger) Warn(string, map[string]any)  {}
+func (nopLogger) Error(string, map[string]any) {}
+
+// Options configures optional handler behaviour. Zero values select defaults.
+type Options struct {
+	MaxBatchSize int
+	// MaxBulkLines caps the number of IPs in a /lookup/bulk upload
+	MaxBulkLines int
+	// DefaultMode applies to requests without ?pc or ?city; an empty or
+	// unknown mode selects ModeCountry
+	DefaultMode LookupMode
+	// SelfMode replaces DefaultMode for GET /lookup, and IPMode for
+	// GET /lookup/{ip} and POST /lookup; empty keeps DefaultMode
+	SelfMode LookupMode
+	IPMode   LookupMode
+	// Strict disables the fallback between the country and city databases
+	// for requests that don't pass ?strict
+	Strict bool
+	// NotFoundAsOK answers addresses missing from the databases with 200 and
+	// an empty country code instead of 404, for clients that retry on 404
+	NotFoundAsOK bool
+	// FieldCase names the JSON fields of lookup responses; empty or unknown
+	// selects CaseSnake
+	FieldCase FieldCase
+	// CheckDB makes /health look up a probe address in every database and
+	// answer 503 when the country database can't be read, instead of only
+	// reporting whether the databases are loaded
+	CheckDB bool
+	// ClientIP determines the caller's address for /lookup; nil uses the
+	// connection's address and ignores forwarding headers
+	ClientIP *clientip.Resolver
+	// AllowIPOverride lets GET /lookup?ip= resolve the given address
+	// instead of the caller's, for integration tests
+	AllowIPOverride bool
+	// Metrics is optional; nil disables instrumentation
+	Metrics Metrics
+	// Logger records failed lookups; nil disables logging
+	Logger Logger
+	// RedactIPs logs IPs as their network (see clientip.Redact) instead of
+	// in full
+	RedactIPs bool
+	// AnonymizeIPs looks up the caller's address with its last octet (IPv4)
+	// or 80 bits (IPv6) zeroed, and shows and logs it that way
+	AnonymizeIPs bool
+	// Resolver serves ?rdns=true; nil uses net.DefaultResolver
+	Resolver ReverseResolver
+	// RDNSTimeout bounds each reverse DNS lookup
+	RDNSTimeout time.Duration
+	// HostResolver serves ?resolve=true; nil uses net.DefaultResolver
+	HostResolver HostResolver
+	// ResolveTimeout bounds each host name lookup
+	ResolveTimeout time.Duration
+	// Build is reported by /version
+	Build BuildInfo
 }
 
 type Handlers struct {
-	geo       GeoLookup
-	startTime time.Time
+	geo          GeoLookup
+	metrics      Metrics
+	logger       Logger
+	redactIPs    bool
+	anonymize    bool
+	startTime    time.Time
+	maxBatchSize int
+	maxBulkLines int
+	bulkIdle     time.Duration
+	cityFirst    bool
+	selfMode     LookupMode
+	ipMode       LookupMode
+	strict       bool
+	notFoundOK   bool
+	camel        bool
+	checkDB      bool
+	clientIP     *clientip.Resolver
+	ipOverride   bool
+	resolver     ReverseResolver
+	rdnsTimeout  time.Duration
+	build        BuildInfo
+
+	hostResolver   HostResolver
+	resolveTimeout time.Duration
 }
 
-func New(geo GeoLookup) *Handlers {
+func New(geo GeoLookup, opts Options) *Handlers {
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if opts.MaxBulkLines <= 0 {
+		opts.MaxBulkLines = DefaultMaxBulkLines
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = nopMetrics{}
+	}
+	if opts.Logger == nil {
+		opts.Logger = nopLogger{}
+	}
+	if opts.ClientIP == nil {
+		opts.ClientIP = clientip.New(nil, nil)
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = net.DefaultResolver
+	}
+	if opts.RDNSTimeout <= 0 {
+		opts.RDNSTimeout = DefaultRDNSTimeout
+	}
+	if opts.HostResolver == nil {
+		opts.HostResolver = net.DefaultResolver
+	}
+	if opts.ResolveTimeout <= 0 {
+		opts.ResolveTimeout = DefaultResolveTimeout
+	}
+
 	return &Handlers{
-		geo:       geo,
-		startTime: time.Now(),
+		geo:          geo,
+		metrics:      opts.Metrics,
+		logger:       opts.Logger,
+		redactIPs:    opts.RedactIPs,
+		anonymize:    opts.AnonymizeIPs,
+		startTime:    time.Now(),
+		maxBatchSize: opts.MaxBatchSize,
+		maxBulkLines: opts.MaxBulkLines,
+		bulkIdle:     bulkIdleTimeout,
+		cityFirst:    opts.DefaultMode == ModeCity,
+		selfMode:     opts.SelfMode,
+		ipMode:       opts.IPMode,
+		strict:       opts.Strict,
+		notFoundOK:   opts.NotFoundAsOK,
+		camel:        opts.FieldCase == CaseCamel,
+		checkDB:      opts.CheckDB,
+		clientIP:     opts.ClientIP,
+		ipOverride:   opts.AllowIPOverride,
+		resolver:     opts.Resolver,
+		rdnsTimeout:  opts.RDNSTimeout,
+		build:        opts.Build,
+
+		hostResolver:   opts.HostResolver,
+		resolveTimeout: opts.ResolveTimeout,
 	}
 }
 
 type HealthResponse struct {
-	Status string `json:"status"`
-	Uptime string `json:"uptime"`
+	Status    string                        `json:"status"`
+	Uptime    string                        `json:"uptime"`
+	Databases map[string]geodb.DatabaseInfo `json:"databases,omitempty"`
+	// Checks holds "ok" or the read error per database, with CheckDB only
+	Checks map[string]string `json:"checks,omitempty"`
 }
 
+// ErrorResponse carries a human-readable message and a stable code for
+// clients to branch on.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	XMLName xml.Name `json:"-" xml:"error"`
+	Error   string   `json:"error" xml:"message"`
+	Code    string   `json:"code" xml:"code"`
 }
 
+// Error codes are part of the API: messages may be reworded, codes may not.
+const (
+	CodeMissingIP       = "missing_ip"
+	CodeClientIP        = "client_ip_unknown"
+	CodeInvalidIP       = "invalid_ip"
+	CodeInvalidPrefix   = "invalid_prefix"
+	CodePrefixTooLarge  = "prefix_too_large"
+	CodeNotFound        = "not_found"
+	CodeHostNotFound    = "host_not_found"
+	CodeResolveTimeout  = "resolve_timeout"
+	CodeResolveFailed   = "resolve_failed"
+	CodeInvalidCallback = "invalid_callback"
+	CodeOutOfScope      = "out_of_scope"
+	CodeInvalidBody     = "invalid_body"
+	CodeBodyTooLarge    = "body_too_large"
+	CodeTooManyIPs      = "too_many_ips"
+	CodeLineTooLong     = "line_too_long"
+	CodeCanceled        = "canceled"
+	CodeInternal        = "internal"
+)
+
+// Health returns 200 while the server is up. The status is "degraded" when an
+// optional database failed to load or a database is stale. With CheckDB it
+// also reads every database, answering 503 "unhealthy" when the country
+// database fails and "degraded" when another one does.
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
+	status := "healthy"
+	for _, loaded := range h.geo.Ready() {
+		if !loaded {
+			status = "degraded"
+		}
+	}
+	databases := h.geo.Databases()
+	for _, info := range databases {
+		if info.Stale {
+			status = "degraded"
+		}
+	}
+
 	resp := HealthResponse{
-		Status: "healthy",
-		Uptime: time.Since(h.startTime).Round(time.Second).String(),
+		Status:    status,
+		Uptime:    time.Since(h.startTime).Round(time.Second).String(),
+		Databases: databases,
+	}
+	code := http.StatusOK
+	if h.checkDB {
+		resp.Checks = make(map[string]string)
+		for name, err := range h.geo.Check() {
+			resp.Checks[name] = "ok"
+			if err == nil {
+				continue
+			}
+			resp.Checks[name] = err.Error()
+			if name == "country" {
+				resp.Status, code = "unhealthy", http.StatusServiceUnavailable
+			} else if resp.Status == "healthy" {
+				resp.Status = "degraded"
+			}
+		}
+	}
+	writeJSON(w, code, resp)
+}
+
+type ReadinessResponse struct {
+	Status    string          `json:"status"`
+	Databases map[string]bool `json:"databases,omitempty"`
+}
+
+// Readiness returns 503 until the country database, which every lookup
+// depends on, is loaded.
+func (h *Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
+	ready := h.geo.Ready()
+	if !ready["country"] {
+		writeJSON(w, http.StatusServiceUnavailable, ReadinessResponse{Status: "not ready", Databases: ready})
+		return
+	}
+	writeJSON(w, http.StatusOK, ReadinessResponse{Status: "ready", Databases: ready})
+}
+
+// Allow answers OPTIONS requests for a route with 204 and an Allow header
+// listing methods. CORS preflights from allowed origins are answered by the
+// CORS middleware before they get here.
+func Allow(methods ...string) http.HandlerFunc {
+	allow := strings.Join(methods, ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
 	}
-	writeJSON(w, http.StatusOK, resp)
 }
 
 func (h *Handlers) LookupIP(w http.ResponseWriter, r *http.Request) {
+	opts := parseLookupOptions(r)
+	opts.routeMode = h.ipMode
+	// The format may come from Accept, so caches must keep them apart
+	w.Header().Add("Vary", "Accept")
+
 	// Extract IP from URL path: /lookup/{ip}
 	path := strings.TrimPrefix(r.URL.Path, "/lookup/")
-	if path == "" || path == r.URL.Path {
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "IP address required"})
+	if path == r.URL.Path {
+		path = ""
+	}
+	h.lookupTarget(r.Context(), w, path, opts)
+}
+
+// lookupTarget resolves the {ip} of /lookup/{ip}: an IP address, or a CIDR
+// prefix when it contains a slash.
+func (h *Handlers) lookupTarget(ctx context.Context, w http.ResponseWriter, target string, opts lookupOptions) {
+	if target == "" {
+		writeError(w, opts, http.StatusBadRequest, CodeMissingIP, "IP address required")
+		return
+	}
+
+	// A slash means a CIDR prefix such as 203.0.113.0/24
+	if strings.Contains(target, "/") {
+		h.doPrefixLookup(ctx, w, target, opts)
+		return
+	}
+
+	h.doLookup(ctx, w, target, opts)
+}
+
+func (h *Handlers) doPrefixLookup(ctx context.Context, w http.ResponseWriter, prefix string, opts lookupOptions) {
+	// A prefix result is nothing but country codes
+	if !scopeAllows(ctx, "country_code") {
+		writeError(w, opts, http.StatusForbidden, CodeOutOfScope, "API key scope doesn't include country_code")
+		return
+	}
+
+	start := time.Now()
+	result, err := h.geo.LookupPrefixCtx(ctx, prefix)
+	h.metrics.ObserveLookup(lookupStatus(err), time.Since(start))
+	if err != nil {
+		status, code, msg := lookupError(err)
+		writeError(w, opts, status, code, msg)
 		return
 	}
 
-	useCity := r.URL.Query().Get("pc") == "true"
-	h.doLookup(w, path, useCity)
+	switch opts.format {
+	case formatText:
+		// One line per country, most networks first
+		codes := make([]string, len(result.Countries))
+		for i, c := range result.Countries {
+			codes[i] = c.CountryCode
+		}
+		writeText(w, http.StatusOK, strings.Join(codes, "\n"))
+	case formatXML:
+		writeXML(w, http.StatusOK, result)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
 }
 
+// LookupSelf resolves the caller's IP. When AllowIPOverride is set, an ?ip=
+// parameter is resolved instead, exactly as /lookup/{ip} would; otherwise
+// the parameter is ignored.
 func (h *Handlers) LookupSelf(w http.ResponseWriter, r *http.Request) {
-	ip := getClientIP(r)
+	opts := parseLookupOptions(r)
+	opts.routeMode = h.selfMode
+	w.Header().Add("Vary", "Accept")
+
+	if q := r.URL.Query(); h.ipOverride && q.Has("ip") {
+		h.lookupTarget(r.Context(), w, q.Get("ip"), opts)
+		return
+	}
+
+	ip := h.selfIP(r)
 	if ip == "" {
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "could not determine client IP"})
+		writeError(w, opts, http.StatusBadRequest, CodeClientIP, "could not determine client IP")
 		return
 	}
 
-	useCity := r.URL.Query().Get("pc") == "true"
-	h.doLookup(w, ip, useCity)
+	h.doLookup(r.Context(), w, ip, opts)
 }
 
-type LookupResponse struct {
-	CountryCode string `json:"country_code"`
-	PostalCode  string `json:"postal_code,omitempty"`
+// selfIP returns the caller's address, anonymized when AnonymizeIPs is set.
+func (h *Handlers) selfIP(r *http.Request) string {
+	ip := h.clientIP.ClientIP(r)
+	if h.anonymize {
+		ip = AnonymizeIP(ip)
+	}
+	return ip
 }
 
-func (h *Handlers) doLookup(w http.ResponseWriter, ip string, useCity bool) {
-	result, err := h.geo.Lookup(ip, useCity)
-	if err != nil {
-		if errors.Is(err, geodb.ErrInvalidIP) {
-			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid IP address"})
-			return
-		}
-		if errors.Is(err, geodb.ErrIPNotFound) {
-			writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "IP not found in database"})
+// maxLookupBodyBytes bounds the body of a POST /lookup request.
+const maxLookupBodyBytes = 4 << 10
+
+// LookupRequest is the body of POST /lookup. The flags mirror the query
+// parameters of GET /lookup/{ip}. PC, City and Strict are pointers so an
+// explicit false overrides the server's default.
+type LookupRequest struct {
+	IP      string `json:"ip"`
+	PC      *bool  `json:"pc"`
+	City    *bool  `json:"city"`
+	Region  bool   `json:"region"`
+	Coords  bool   `json:"coords"`
+	Names   bool   `json:"names"`
+	Flag    bool   `json:"flag"`
+	EU      bool   `json:"eu"`
+	RDNS    bool   `json:"rdns"`
+	TZ      bool   `json:"tz"`
+	Version bool   `json:"version"`
+	ASN     bool   `json:"asn"`
+	Strict  *bool  `json:"strict"`
+	Resolve bool   `json:"resolve"`
+	Network bool   `json:"network"`
+	Full    bool   `json:"full"`
+}
+
+// LookupPost resolves the IP in a JSON body, for clients that can't put it in
+// the URL because intermediaries log request lines.
+func (h *Handlers) LookupPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLookupBodyBytes)
+
+	var req LookupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large", Code: CodeBodyTooLarge})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "lookup failed"})
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "request body must be a JSON object", Code: CodeInvalidBody})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, LookupResponse{
-		CountryCode: result.CountryCode,
-		PostalCode:  result.PostalCode,
+	flags := map[string]*bool{
+		"pc":      req.PC,
+		"city":    req.City,
+		"region":  &req.Region,
+		"coords":  &req.Coords,
+		"names":   &req.Names,
+		"flag":    &req.Flag,
+		"eu":      &req.EU,
+		"rdns":    &req.RDNS,
+		"tz":      &req.TZ,
+		"version": &req.Version,
+		"asn":     &req.ASN,
+		"strict":  req.Strict,
+		"resolve": &req.Resolve,
+		"network": &req.Network,
+		"full":    &req.Full,
+	}
+	opts := lookupFlags(func(name string) (bool, bool) {
+		v := flags[name]
+		return v != nil && *v, v != nil
 	})
+	opts.format = negotiateFormat(r, r.URL.Query())
+	opts.routeMode = h.ipMode
+	w.Header().Add("Vary", "Accept")
+
+	ip := strings.TrimSpace(req.IP)
+	if ip == "" {
+		writeError(w, opts, http.StatusBadRequest, CodeMissingIP, "IP address required")
+		return
+	}
+
+	h.doLookup(r.Context(), w, ip, opts)
+}
+
+type LookupResponse struct {
+	XMLName        xml.Name `json:"-" xml:"lookup"`
+	CountryCode    string   `json:"country_code" xml:"country_code"`
+	CountryName    string   `json:"country_name,omitempty" xml:"country_name,omitempty"`
+	ContinentCode  string   `json:"continent_code,omitempty" xml:"continent_code,omitempty"`
+	Flag           string   `json:"flag,omitempty" xml:"flag,omitempty"`
+	IsInEU         *bool    `json:"is_in_eu,omitempty" xml:"is_in_eu,omitempty"` // pointer so false is still reported
+	PostalCode     string   `json:"postal_code,omitempty" xml:"postal_code,omitempty"`
+	Region         string   `json:"region,omitempty" xml:"region,omitempty"`
+	City           string   `json:"city,omitempty" xml:"city,omitempty"`
+	Latitude       *float64 `json:"latitude,omitempty" xml:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty" xml:"longitude,omitempty"`
+	AccuracyRadius uint16   `json:"accuracy_radius,omitempty" xml:"accuracy_radius,omitempty"`
+	ASN            uint     `json:"asn,omitempty" xml:"asn,omitempty"`
+	ASOrg          string   `json:"as_org,omitempty" xml:"as_org,omitempty"`
+	Hostname       string   `json:"hostname,omitempty" xml:"hostname,omitempty"`
+	Timezone       string   `json:"timezone,omitempty" xml:"timezone,omitempty"`
+	IPVersion      string   `json:"ip_version,omitempty" xml:"ip_version,omitempty"`
+	Private        bool     `json:"private,omitempty" xml:"private,omitempty"`
+	ResolvedIP     string   `json:"resolved_ip,omitempty" xml:"resolved_ip,omitempty"` // address a ?resolve=true host name resolved to
+	Network        string   `json:"network,omitempty" xml:"network,omitempty"`
+}
+
+// lookupOptions holds the per-request lookup flags taken from the query string.
+type lookupOptions struct {
+	geo    geodb.LookupOptions // databases to consult
+	city   bool                // include the city name in the response
+	region bool                // include the subdivision name in the response
+	coords bool                // include latitude/longitude in the response
+	names  bool                // include the country name and continent code
+	flag   bool                // include the country's flag emoji
+	eu     bool                // include EU membership
+	rdns   bool                // include the reverse DNS host name
+	tz     bool                // include the IANA time zone
+	ver    bool                // include the IP version
+	host   bool                // resolve a host name given instead of an IP
+	net    bool                // include the matched network prefix
+	format responseFormat      // representation of the response
+	// explicitMode is set when the request chose the database itself via
+	// pc or city, true or false, so the server default doesn't apply
+	explicitMode bool
+	// explicitStrict is set when the request passed strict, true or false
+	explicitStrict bool
+	// routeMode is the route's default mode; empty uses the server's
+	routeMode LookupMode
+	// callback wraps successful JSON responses in a JSONP call
+	callback string
+}
+
+func parseLookupOptions(r *http.Request) lookupOptions {
+	q := r.URL.Query()
+	opts := lookupFlags(func(name string) (bool, bool) { return q.Get(name) == "true", q.Has(name) })
+	opts.format = negotiateFormat(r, q)
+	opts.callback = q.Get("callback")
+	return opts
 }
 
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the chain
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
+// lookupFlags builds the options from named boolean flags, so query strings
+// and request bodies share the same names. flag reports a flag's value and
+// whether it was given at all.
+func lookupFlags(flag func(name string) (value, ok bool)) lookupOptions {
+	is := func(name string) bool {
+		v, _ := flag(name)
+		return v
+	}
+	opts := lookupOptions{
+		city:   is("city"),
+		region: is("region"),
+		coords: is("coords"),
+		names:  is("names"),
+		flag:   is("flag"),
+		eu:     is("eu"),
+		rdns:   is("rdns"),
+		tz:     is("tz"),
+		ver:    is("version"),
+		host:   is("resolve"),
+		net:    is("network"),
+	}
+	if is("full") {
+		// Every field the databases can provide, from all of them
+		opts.city, opts.region, opts.coords = true, true, true
+		opts.geo.Full = true
+	}
+	// City-level fields only come from the city database
+	opts.geo.UseCity = is("pc") || opts.city || opts.region || opts.coords || opts.tz
+	opts.geo.ASN = is("asn") || opts.geo.Full
+	opts.geo.Strict, opts.explicitStrict = flag("strict")
+	_, pcSet := flag("pc")
+	_, citySet := flag("city")
+	opts.explicitMode = pcSet || citySet
+	return opts
+}
+
+func (h *Handlers) doLookup(ctx context.Context, w http.ResponseWriter, ip string, opts lookupOptions) {
+	if opts.callback != "" && !validCallback(opts.callback) {
+		writeError(w, opts, http.StatusBadRequest, CodeInvalidCallback, "invalid callback name")
+		return
+	}
+
+	// With ?resolve=true, anything that isn't an IP is taken for a host name
+	var resolvedIP string
+	if _, err := netip.ParseAddr(ip); err != nil && opts.host {
+		if resolvedIP, err = h.lookupHost(ctx, ip); err != nil {
+			status, code, msg := lookupError(err)
+			if ctx.Err() != nil {
+				status, code, msg = lookupError(ctx.Err())
+			}
+			h.logLookupError(ip, opts, err, status, code)
+			writeError(w, opts, status, code, msg)
+			return
 		}
-		return strings.TrimSpace(xff)
+		ip = resolvedIP
+	}
+
+	resp, took, err := h.resolve(ctx, ip, opts)
+	w.Header().Set(HeaderLookupDuration, formatMillis(took))
+	if errors.Is(err, geodb.ErrIPNotFound) && h.notFoundOK {
+		resp, err = &LookupResponse{}, nil
+	}
+	if err != nil {
+		status, code, msg := lookupError(err)
+		h.logLookupError(ip, opts, err, status, code)
+		writeError(w, opts, status, code, msg)
+		return
+	}
+	resp.ResolvedIP = resolvedIP
+
+	switch {
+	case opts.format == formatText:
+		writeText(w, http.StatusOK, resp.CountryCode)
+	case opts.format == formatXML:
+		writeXML(w, http.StatusOK, resp)
+	case opts.callback != "":
+		writeJSONP(w, http.StatusOK, opts.callback, h.lookupJSON(resp))
+	default:
+		writeJSON(w, http.StatusOK, h.lookupJSON(resp))
+	}
+}
+
+// logLookupError records a failed lookup: server-side failures as errors,
+// everything the client can fix or caused as warnings.
+func (h *Handlers) logLookupError(ip string, opts lookupOptions, err error, status int, code string) {
+	switch {
+	case h.redactIPs:
+		ip = clientip.Redact(ip)
+	case h.anonymize:
+		ip = AnonymizeIP(ip)
+	}
+	data := map[string]any{
+		"ip":     ip,
+		"code":   code,
+		"status": status,
+		"mode":   string(h.mode(opts)),
 	}
+	if code == CodeInternal {
+		data["error"] = err.Error()
+		h.logger.Error("lookup failed", data)
+		return
+	}
+	h.logger.Warn("lookup failed", data)
+}
+
+// mode reports which database a lookup with opts tries first, after the
+// route's or the server's default lookup mode is applied.
+func (h *Handlers) mode(opts lookupOptions) LookupMode {
+	cityFirst := h.cityFirst
+	if opts.routeMode != "" {
+		cityFirst = opts.routeMode == ModeCity
+	}
+	if opts.geo.UseCity || (cityFirst && !opts.explicitMode) {
+		return ModeCity
+	}
+	return ModeCountry
+}
+
+// HeaderLookupDuration carries how long the database lookup behind a single-IP
+// response took, in milliseconds.
+const HeaderLookupDuration = "X-Lookup-Duration-Ms"
+
+// formatMillis renders d in milliseconds with microsecond precision, since
+// most lookups take well under one.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
 
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return strings.TrimSpace(xri)
+// resolve looks up a single IP and builds the response for the given options.
+// It backs doLookup as well as batch and bulk lookups, so the default lookup
+// mode and the API key's scope are applied here. took is the time spent in
+// the database lookup alone.
+func (h *Handlers) resolve(ctx context.Context, ip string, opts lookupOptions) (_ *LookupResponse, took time.Duration, _ error) {
+	opts.geo.UseCity = h.mode(opts) == ModeCity
+	if !opts.explicitStrict {
+		opts.geo.Strict = h.strict
 	}
 
-	// Fall back to RemoteAddr
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	spanCtx, span := tracer.Start(ctx, "geodb.Lookup", trace.WithAttributes(
+		attribute.Bool("geodb.use_city", opts.geo.UseCity),
+		attribute.Bool("geodb.asn", opts.geo.ASN),
+	))
+	start := time.Now()
+	result, err := h.geo.LookupCtx(spanCtx, ip, opts.geo)
+	took = time.Since(start)
+	status := lookupStatus(err)
+	h.metrics.ObserveLookup(status, took)
+	span.SetAttributes(attribute.String("geodb.result", status))
+	if status == metrics.StatusError {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	// Private addresses are a valid answer, just one without a location
+	if errors.Is(err, geodb.ErrPrivateIP) {
+		return &LookupResponse{Private: true}, took, nil
+	}
 	if err != nil {
-		return r.RemoteAddr
+		return nil, took, err
+	}
+
+	resp := &LookupResponse{
+		CountryCode: result.CountryCode,
+		PostalCode:  result.PostalCode,
+	}
+	if opts.names {
+		if country, ok := iso.Lookup(result.CountryCode); ok {
+			resp.CountryName = country.Name
+			resp.ContinentCode = country.Continent
+		}
+	}
+	if opts.flag {
+		resp.Flag = iso.Flag(result.CountryCode)
+	}
+	if opts.eu {
+		inEU := iso.IsEU(result.CountryCode)
+		resp.IsInEU = &inEU
+	}
+	if opts.region {
+		resp.Region = result.Region
+	}
+	if opts.city {
+		resp.City = result.City
+	}
+	if opts.coords {
+		resp.Latitude = result.Latitude
+		resp.Longitude = result.Longitude
+		resp.AccuracyRadius = result.AccuracyRadius
+	}
+	if opts.geo.ASN {
+		resp.ASN = result.ASN
+		resp.ASOrg = result.ASOrg
+	}
+	// The time zone is derived from coordinates, so country-only matches have none
+	if opts.tz && result.Latitude != nil && result.Longitude != nil {
+		resp.Timezone = tz.Lookup(*result.Latitude, *result.Longitude, result.CountryCode)
+	}
+	if opts.ver {
+		resp.IPVersion = result.IPVersion
+	}
+	if opts.net {
+		resp.Network = result.Network
+	}
+	if opts.rdns {
+		resp.Hostname = h.reverseLookup(ctx, ip)
+	}
+	applyScope(ctx, resp)
+	return resp, took, nil
+}
+
+// lookupStatus classifies a lookup error for metrics.
+func lookupStatus(err error) string {
+	switch {
+	case err == nil:
+		return metrics.StatusOK
+	case errors.Is(err, geodb.ErrInvalidIP), errors.Is(err, geodb.ErrInvalidPrefix), errors.Is(err, geodb.ErrPrefixTooLarge):
+		return metrics.StatusInvalid
+	case errors.Is(err, geodb.ErrIPNotFound):
+		return metrics.StatusNotFound
+	case errors.Is(err, geodb.ErrPrivateIP):
+		return metrics.StatusPrivate
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return metrics.StatusCanceled
+	default:
+		return metrics.StatusError
 	}
-	return host
 }
 
+// lookupError maps a lookup error to an HTTP status, error code and
+// client-facing message.
+func lookupError(err error) (status int, code, msg string) {
+	switch {
+	case errors.Is(err, geodb.ErrInvalidIP):
+		return http.StatusBadRequest, CodeInvalidIP, "invalid IP address"
+	case errors.Is(err, geodb.ErrInvalidPrefix):
+		return http.StatusBadRequest, CodeInvalidPrefix, "invalid network prefix"
+	case errors.Is(err, geodb.ErrPrefixTooLarge):
+		return http.StatusBadRequest, CodePrefixTooLarge, err.Error()
+	case errors.Is(err, geodb.ErrIPNotFound):
+		return http.StatusNotFound, CodeNotFound, "IP not found in database"
+	case errors.Is(err, errHostNotFound):
+		return http.StatusNotFound, CodeHostNotFound, "could not resolve host"
+	case errors.Is(err, errResolveTimeout):
+		return http.StatusGatewayTimeout, CodeResolveTimeout, "host lookup timed out"
+	case errors.Is(err, errResolveFailed):
+		return http.StatusServiceUnavailable, CodeResolveFailed, "host lookup failed"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// Usually nobody is left to read this; a deadline set by a proxy is the exception
+		return http.StatusServiceUnavailable, CodeCanceled, "lookup canceled"
+	default:
+		return http.StatusInternalServerError, CodeInternal, "lookup failed"
+	}
+}
+
+// jsonBuffer pairs a buffer with an encoder writing to it so both can be
+// reused across responses.
+type jsonBuffer struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var jsonBuffers = sync.Pool{
+	New: func() any {
+		b := new(jsonBuffer)
+		b.enc = json.NewEncoder(&b.buf)
+		return b
+	},
+}
+
+// jsonContentType is shared by every JSON response instead of allocating a
+// new header value each time. net/http never modifies header values in place.
+var jsonContentType = []string{"application/json"}
+
+// maxPooledJSONBuffer keeps the occasional huge batch response from pinning
+// its buffer in the pool.
+const maxPooledJSONBuffer = 64 << 10
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
-	w.Header().Set("Content-Type", "application/json")
+	b := jsonBuffers.Get().(*jsonBuffer)
+	defer func() {
+		if b.buf.Cap() <= maxPooledJSONBuffer {
+			b.buf.Reset()
+			jsonBuffers.Put(b)
+		}
+	}()
+
+	// Encoding up front means a failure can still be reported as a 500
+	if err := b.enc.Encode(v); err != nil {
+		b.buf.Reset()
+		status = http.StatusInternalServerError
+		_ = b.enc.Encode(ErrorResponse{Error: "encoding failed", Code: CodeInternal})
+	}
+
+	h := w.Header()
+	h["Content-Type"] = jsonContentType
+	h.Set("Content-Length", strconv.Itoa(b.buf.Len()))
+	w.WriteHeader(status)
+	_, _ = w.Write(b.buf.Bytes())
+}
+
+func writeText(w http.ResponseWriter, status int, s string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(v)
+	_, _ = io.WriteString(w, s+"\n")
+}
+
+// writeError writes a lookup error in the format the client asked for. Text
+// responses carry only the message.
+func writeError(w http.ResponseWriter, opts lookupOptions, status int, code, msg string) {
+	switch opts.format {
+	case formatText:
+		writeText(w, status, msg)
+	case formatXML:
+		writeXML(w, status, ErrorResponse{Error: msg, Code: code})
+	default:
+		writeJSON(w, status, ErrorResponse{Error: msg, Code: code})
+	}
 }
@@ -1,29 +1,71 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/burakcan/ipburack/internal/clientip"
 	"github.com/burakcan/ipburack/internal/geodb"
+	"github.com/burakcan/ipburack/internal/logger/logtest"
+	"github.com/burakcan/ipburack/internal/metrics"
 )
 
 // mockGeoLookup implements GeoLookup for testing
 type mockGeoLookup struct {
-	result *geodb.LookupResult
-	err    error
+	result    *geodb.LookupResult
+	prefix    *geodb.PrefixResult
+	err       error
+	databases map[string]geodb.DatabaseInfo
+	ready     map[string]bool
+	check     map[string]error
+
+	// Arguments of the last Lookup call
+	lastIP   string
+	lastOpts geodb.LookupOptions
 }
 
-func (m *mockGeoLookup) Lookup(ip string, useCity bool) (*geodb.LookupResult, error) {
+func (m *mockGeoLookup) LookupCtx(ctx context.Context, ip string, opts geodb.LookupOptions) (*geodb.LookupResult, error) {
+	m.lastIP = ip
+	m.lastOpts = opts
 	if m.err != nil {
 		return nil, m.err
 	}
 	return m.result, nil
 }
 
+func (m *mockGeoLookup) LookupPrefixCtx(ctx context.Context, prefix string) (*geodb.PrefixResult, error) {
+	m.lastIP = prefix
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.prefix, nil
+}
+
+func (m *mockGeoLookup) Databases() map[string]geodb.DatabaseInfo {
+	return m.databases
+}
+
+func (m *mockGeoLookup) Ready() map[string]bool {
+	return m.ready
+}
+
+func (m *mockGeoLookup) Check() map[string]error {
+	return m.check
+}
+
 func TestHealth(t *testing.T) {
-	h := New(&mockGeoLookup{})
+	h := New(&mockGeoLookup{}, Options{})
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	w := httptest.NewRecorder()
@@ -48,11 +90,205 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealth_Databases(t *testing.T) {
+	buildTime := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	lastUpdated := time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC)
+	mock := &mockGeoLookup{
+		databases: map[string]geodb.DatabaseInfo{
+			"country":   {Loaded: true, BuildTime: buildTime, LastUpdated: lastUpdated},
+			"city-ipv4": {Loaded: false},
+		},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+
+	h.Health(w, req)
+
+	var resp HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Status != "healthy" {
+		t.Errorf("expected status 'healthy', got %q", resp.Status)
+	}
+
+	country, ok := resp.Databases["country"]
+	if !ok {
+		t.Fatal("expected country database in response")
+	}
+	if !country.Loaded || !country.BuildTime.Equal(buildTime) || !country.LastUpdated.Equal(lastUpdated) {
+		t.Errorf("unexpected country database info: %+v", country)
+	}
+
+	if resp.Databases["city-ipv4"].Loaded {
+		t.Error("expected city-ipv4 to be reported as not loaded")
+	}
+}
+
+func TestHealth_Degraded(t *testing.T) {
+	mock := &mockGeoLookup{
+		ready: map[string]bool{"country": true, "city-ipv4": false, "city-ipv6": true},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+
+	h.Health(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Status != "degraded" {
+		t.Errorf("expected status 'degraded', got %q", resp.Status)
+	}
+}
+
+func TestHealth_Stale(t *testing.T) {
+	mock := &mockGeoLookup{
+		ready: map[string]bool{"country": true},
+		databases: map[string]geodb.DatabaseInfo{
+			"country": {Loaded: true, Stale: true},
+		},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+
+	h.Health(w, req)
+
+	var resp HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Status != "degraded" {
+		t.Errorf("expected status 'degraded', got %q", resp.Status)
+	}
+	if !resp.Databases["country"].Stale {
+		t.Error("expected country to be reported as stale")
+	}
+}
+
+func TestHealth_CheckDB(t *testing.T) {
+	tests := []struct {
+		name   string
+		check  map[string]error
+		status string
+		code   int
+	}{
+		{"readable", map[string]error{"country": nil, "city-ipv4": nil}, "healthy", http.StatusOK},
+		{"optional unreadable", map[string]error{"country": nil, "city-ipv4": errors.New("corrupt")}, "degraded", http.StatusOK},
+		{"country unreadable", map[string]error{"country": errors.New("corrupt"), "city-ipv4": nil}, "unhealthy", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockGeoLookup{ready: map[string]bool{"country": true, "city-ipv4": true}, check: tt.check}
+			h := New(mock, Options{CheckDB: true})
+
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			w := httptest.NewRecorder()
+			h.Health(w, req)
+
+			if w.Code != tt.code {
+				t.Errorf("expected status %d, got %d", tt.code, w.Code)
+			}
+			var resp HealthResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.status {
+				t.Errorf("expected status %q, got %q", tt.status, resp.Status)
+			}
+			for name, err := range tt.check {
+				want := "ok"
+				if err != nil {
+					want = err.Error()
+				}
+				if resp.Checks[name] != want {
+					t.Errorf("expected check %s = %q, got %q", name, want, resp.Checks[name])
+				}
+			}
+		})
+	}
+
+	// Without CheckDB the databases aren't read
+	mock := &mockGeoLookup{check: map[string]error{"country": errors.New("corrupt")}}
+	h := New(mock, Options{})
+	w := httptest.NewRecorder()
+	h.Health(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "checks") {
+		t.Errorf("expected the cheap health check by default, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		ready      map[string]bool
+		wantCode   int
+		wantStatus string
+	}{
+		{
+			name:       "nothing loaded",
+			ready:      map[string]bool{"country": false, "city-ipv4": false, "city-ipv6": false},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: "not ready",
+		},
+		{
+			name:       "country loaded",
+			ready:      map[string]bool{"country": true, "city-ipv4": false, "city-ipv6": false},
+			wantCode:   http.StatusOK,
+			wantStatus: "ready",
+		},
+		{
+			name:       "all loaded",
+			ready:      map[string]bool{"country": true, "city-ipv4": true, "city-ipv6": true},
+			wantCode:   http.StatusOK,
+			wantStatus: "ready",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(&mockGeoLookup{ready: tt.ready}, Options{})
+
+			req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+			w := httptest.NewRecorder()
+
+			h.Readiness(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+
+			var resp ReadinessResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, resp.Status)
+			}
+		})
+	}
+}
+
 func TestLookupIP_Success(t *testing.T) {
 	mock := &mockGeoLookup{
 		result: &geodb.LookupResult{CountryCode: "US"},
 	}
-	h := New(mock)
+	h := New(mock, Options{})
 
 	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
 	w := httptest.NewRecorder()
@@ -73,11 +309,67 @@ func TestLookupIP_Success(t *testing.T) {
 	}
 }
 
+// TestLookupIP_Head checks a HEAD request, which the mux routes to the GET
+// handler, gets the GET status and headers but no body.
+func TestLookupIP_Head(t *testing.T) {
+	tests := []struct {
+		name   string
+		geo    *mockGeoLookup
+		status int
+	}{
+		{"found", &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}, http.StatusOK},
+		{"not found", &mockGeoLookup{err: geodb.ErrIPNotFound}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /lookup/{ip...}", New(tt.geo, Options{}).LookupIP)
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			resp, err := http.Head(srv.URL + "/lookup/8.8.8.8")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %s", ct)
+			}
+			if len(body) != 0 {
+				t.Errorf("expected no body, got %q", body)
+			}
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/lookup", nil)
+	w := httptest.NewRecorder()
+
+	Allow("GET", "HEAD", "POST", "OPTIONS")(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Allow"); got != "GET, HEAD, POST, OPTIONS" {
+		t.Errorf("expected Allow header listing the methods, got %q", got)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no body, got %q", w.Body.String())
+	}
+}
+
 func TestLookupIP_WithPostalCode(t *testing.T) {
 	mock := &mockGeoLookup{
 		result: &geodb.LookupResult{CountryCode: "US", PostalCode: "10001"},
 	}
-	h := New(mock)
+	h := New(mock, Options{})
 
 	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?pc=true", nil)
 	w := httptest.NewRecorder()
@@ -102,86 +394,350 @@ func TestLookupIP_WithPostalCode(t *testing.T) {
 	}
 }
 
+func TestLookupIP_PostalCodeUsesCityDB(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        LookupMode
+		url         string
+		wantUseCity bool
+	}{
+		{name: "pc=true", url: "/lookup/8.8.8.8?pc=true", wantUseCity: true},
+		{name: "city=true", url: "/lookup/8.8.8.8?city=true", wantUseCity: true},
+		{name: "no flags", url: "/lookup/8.8.8.8", wantUseCity: false},
+		{name: "pc=false", url: "/lookup/8.8.8.8?pc=false", wantUseCity: false},
+		{name: "city mode default", mode: ModeCity, url: "/lookup/8.8.8.8", wantUseCity: true},
+		{name: "city mode pc=false", mode: ModeCity, url: "/lookup/8.8.8.8?pc=false", wantUseCity: false},
+		{name: "city mode city=false", mode: ModeCity, url: "/lookup/8.8.8.8?city=false", wantUseCity: false},
+		{name: "country mode pc=true", mode: ModeCountry, url: "/lookup/8.8.8.8?pc=true", wantUseCity: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockGeoLookup{
+				result: &geodb.LookupResult{CountryCode: "US", PostalCode: "10001"},
+			}
+			h := New(mock, Options{DefaultMode: tt.mode})
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			if mock.lastOpts.UseCity != tt.wantUseCity {
+				t.Errorf("expected useCity=%v to reach the geo layer, got %v", tt.wantUseCity, mock.lastOpts.UseCity)
+			}
+
+			if mock.lastIP != "8.8.8.8" {
+				t.Errorf("expected IP '8.8.8.8' to reach the geo layer, got %q", mock.lastIP)
+			}
+
+			var resp LookupResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if resp.PostalCode != "10001" {
+				t.Errorf("expected postal code '10001', got %q", resp.PostalCode)
+			}
+		})
+	}
+}
+
+func TestLookupIP_Strict(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		url        string
+		wantStrict bool
+	}{
+		{name: "default", url: "/lookup/8.8.8.8", wantStrict: false},
+		{name: "strict=true", url: "/lookup/8.8.8.8?strict=true", wantStrict: true},
+		{name: "strict default", strict: true, url: "/lookup/8.8.8.8", wantStrict: true},
+		{name: "strict default strict=false", strict: true, url: "/lookup/8.8.8.8?strict=false", wantStrict: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockGeoLookup{err: geodb.ErrIPNotFound}
+			h := New(mock, Options{Strict: tt.strict})
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			if mock.lastOpts.Strict != tt.wantStrict {
+				t.Errorf("expected strict=%v to reach the geo layer, got %v", tt.wantStrict, mock.lastOpts.Strict)
+			}
+			assertError(t, w, http.StatusNotFound, CodeNotFound)
+		})
+	}
+}
+
+func TestLookupIP_NotFoundAsOK(t *testing.T) {
+	h := New(&mockGeoLookup{err: geodb.ErrIPNotFound}, Options{NotFoundAsOK: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+	w := httptest.NewRecorder()
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"country_code":""}` {
+		t.Errorf("expected an empty country code, got %s", body)
+	}
+
+	// Invalid input is still the client's error
+	h = New(&mockGeoLookup{err: geodb.ErrInvalidIP}, Options{NotFoundAsOK: true})
+	req = httptest.NewRequest(http.MethodGet, "/lookup/invalid", nil)
+	w = httptest.NewRecorder()
+	h.LookupIP(w, req)
+	assertError(t, w, http.StatusBadRequest, CodeInvalidIP)
+}
+
+func TestLookupIP_DurationHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		geo    *mockGeoLookup
+		status int
+	}{
+		{"found", &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}, http.StatusOK},
+		{"not found", &mockGeoLookup{err: geodb.ErrIPNotFound}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.geo, Options{})
+
+			req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			header := w.Header().Get(HeaderLookupDuration)
+			if ms, err := strconv.ParseFloat(header, 64); err != nil || ms < 0 {
+				t.Errorf("expected a duration in milliseconds, got %q", header)
+			}
+		})
+	}
+}
+
+func TestFormatMillis(t *testing.T) {
+	if got := formatMillis(1500 * time.Microsecond); got != "1.500" {
+		t.Errorf("formatMillis(1.5ms) = %q, want 1.500", got)
+	}
+}
+
+// assertError checks a JSON error response's status and code.
+func assertError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Errorf("expected status %d, got %d", status, w.Code)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != code {
+		t.Errorf("expected code %q, got %q", code, resp.Code)
+	}
+	if resp.Error == "" {
+		t.Error("expected an error message")
+	}
+}
+
 func TestLookupIP_MissingIP(t *testing.T) {
-	h := New(&mockGeoLookup{})
+	h := New(&mockGeoLookup{}, Options{})
 
 	req := httptest.NewRequest(http.MethodGet, "/lookup/", nil)
 	w := httptest.NewRecorder()
 
 	h.LookupIP(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
-	}
+	assertError(t, w, http.StatusBadRequest, CodeMissingIP)
 }
 
 func TestLookupIP_InvalidIP(t *testing.T) {
 	mock := &mockGeoLookup{err: geodb.ErrInvalidIP}
-	h := New(mock)
+	h := New(mock, Options{})
 
 	req := httptest.NewRequest(http.MethodGet, "/lookup/invalid", nil)
 	w := httptest.NewRecorder()
 
 	h.LookupIP(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
-	}
+	assertError(t, w, http.StatusBadRequest, CodeInvalidIP)
 }
 
 func TestLookupIP_NotFound(t *testing.T) {
 	mock := &mockGeoLookup{err: geodb.ErrIPNotFound}
-	h := New(mock)
+	h := New(mock, Options{})
 
 	req := httptest.NewRequest(http.MethodGet, "/lookup/192.168.1.1", nil)
 	w := httptest.NewRecorder()
 
 	h.LookupIP(w, req)
 
-	if w.Code != http.StatusNotFound {
-		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
-	}
+	assertError(t, w, http.StatusNotFound, CodeNotFound)
 }
 
-func TestLookupSelf_XForwardedFor(t *testing.T) {
-	mock := &mockGeoLookup{
-		result: &geodb.LookupResult{CountryCode: "DE"},
+func TestLookupError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{geodb.ErrInvalidIP, http.StatusBadRequest, CodeInvalidIP},
+		{geodb.ErrInvalidPrefix, http.StatusBadRequest, CodeInvalidPrefix},
+		{geodb.ErrPrefixTooLarge, http.StatusBadRequest, CodePrefixTooLarge},
+		{geodb.ErrIPNotFound, http.StatusNotFound, CodeNotFound},
+		{context.Canceled, http.StatusServiceUnavailable, CodeCanceled},
+		{errors.New("disk on fire"), http.StatusInternalServerError, CodeInternal},
 	}
-	h := New(mock)
 
-	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
-	req.Header.Set("X-Forwarded-For", "203.0.113.1, 198.51.100.1")
-	w := httptest.NewRecorder()
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			h := New(&mockGeoLookup{err: tt.err}, Options{})
 
-	h.LookupSelf(w, req)
+			req := httptest.NewRequest(http.MethodGet, "/lookup/203.0.113.1", nil)
+			w := httptest.NewRecorder()
 
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+			h.LookupIP(w, req)
+
+			assertError(t, w, tt.status, tt.code)
+		})
 	}
 }
 
-func TestLookupSelf_XRealIP(t *testing.T) {
-	mock := &mockGeoLookup{
-		result: &geodb.LookupResult{CountryCode: "FR"},
+func TestLookupIP_LogsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		path   string
+		opts   Options
+		level  string
+		code   string
+		ip     string
+		mode   LookupMode
+		detail bool
+	}{
+		{name: "invalid", err: geodb.ErrInvalidIP, path: "/lookup/bogus", level: "warn", code: CodeInvalidIP, ip: "bogus", mode: ModeCountry},
+		{name: "not found", err: geodb.ErrIPNotFound, path: "/lookup/203.0.113.1?pc=true", level: "warn", code: CodeNotFound, ip: "203.0.113.1", mode: ModeCity},
+		{name: "default mode", err: geodb.ErrIPNotFound, path: "/lookup/203.0.113.1", opts: Options{DefaultMode: ModeCity}, level: "warn", code: CodeNotFound, ip: "203.0.113.1", mode: ModeCity},
+		{name: "internal", err: errors.New("disk on fire"), path: "/lookup/203.0.113.1", level: "error", code: CodeInternal, ip: "203.0.113.1", mode: ModeCountry, detail: true},
+		{name: "redacted", err: geodb.ErrIPNotFound, path: "/lookup/203.0.113.1", opts: Options{RedactIPs: true}, level: "warn", code: CodeNotFound, ip: "203.0.113.0/24", mode: ModeCountry},
 	}
-	h := New(mock)
 
-	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
-	req.Header.Set("X-Real-IP", "203.0.113.50")
-	w := httptest.NewRecorder()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &logtest.Recorder{}
+			tt.opts.Logger = log
+			h := New(&mockGeoLookup{err: tt.err}, tt.opts)
 
-	h.LookupSelf(w, req)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
 
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+			h.LookupIP(w, req)
+
+			if len(log.Entries()) != 1 {
+				t.Fatalf("expected one log entry, got %+v", log.Entries())
+			}
+			e := log.Entries()[0]
+			if e.Level != tt.level || e.Data["code"] != tt.code || e.Data["ip"] != tt.ip || e.Data["mode"] != string(tt.mode) {
+				t.Errorf("unexpected log entry: %+v", e)
+			}
+			if _, ok := e.Data["error"]; ok != tt.detail {
+				t.Errorf("expected error detail %v, got %+v", tt.detail, e.Data)
+			}
+		})
 	}
 }
 
-func TestLookupSelf_RemoteAddr(t *testing.T) {
+func TestLookupIP_SuccessNotLogged(t *testing.T) {
+	log := &logtest.Recorder{}
+	h := New(&mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}, Options{Logger: log})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+	h.LookupIP(httptest.NewRecorder(), req)
+
+	if len(log.Entries()) != 0 {
+		t.Errorf("expected nothing logged, got %+v", log.Entries())
+	}
+}
+
+func TestLookupIP_Private(t *testing.T) {
+	mock := &mockGeoLookup{err: geodb.ErrPrivateIP}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/192.168.1.1", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["country_code"] != "" || resp["private"] != true {
+		t.Errorf("expected empty country code and private flag, got %v", resp)
+	}
+}
+
+func TestLookupSelf_XForwardedFor(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "DE"},
+	}
+	// httptest requests come from 192.0.2.1
+	h := New(mock, Options{ClientIP: clientip.New(nil, []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.1, 198.51.100.1")
+	w := httptest.NewRecorder()
+
+	h.LookupSelf(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if mock.lastIP != "198.51.100.1" {
+		t.Errorf("expected the rightmost untrusted hop to be looked up, got %q", mock.lastIP)
+	}
+}
+
+func TestLookupSelf_XRealIP(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "FR"},
+	}
+	h := New(mock, Options{ClientIP: clientip.New(nil, []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+	req.Header.Set("X-Real-IP", "203.0.113.50")
+	w := httptest.NewRecorder()
+
+	h.LookupSelf(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if mock.lastIP != "203.0.113.50" {
+		t.Errorf("expected the X-Real-IP address to be looked up, got %q", mock.lastIP)
+	}
+}
+
+func TestLookupSelf_RemoteAddr(t *testing.T) {
 	mock := &mockGeoLookup{
 		result: &geodb.LookupResult{CountryCode: "GB"},
 	}
-	h := New(mock)
+	h := New(mock, Options{})
 
 	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
 	req.RemoteAddr = "203.0.113.100:12345"
@@ -194,76 +750,940 @@ func TestLookupSelf_RemoteAddr(t *testing.T) {
 	}
 }
 
-func TestGetClientIP(t *testing.T) {
+func TestLookupSelf_IPOverride(t *testing.T) {
 	tests := []struct {
-		name       string
-		xff        string
-		xRealIP    string
-		remoteAddr string
-		want       string
+		name    string
+		allow   bool
+		query   string
+		status  int
+		code    string
+		looksUp string
 	}{
-		{
-			name: "X-Forwarded-For single",
-			xff:  "203.0.113.1",
-			want: "203.0.113.1",
-		},
-		{
-			name: "X-Forwarded-For multiple",
-			xff:  "203.0.113.1, 198.51.100.1, 192.0.2.1",
-			want: "203.0.113.1",
-		},
-		{
-			name: "X-Forwarded-For with spaces",
-			xff:  "  203.0.113.1  ",
-			want: "203.0.113.1",
-		},
-		{
-			name:    "X-Real-IP",
-			xRealIP: "203.0.113.50",
-			want:    "203.0.113.50",
-		},
-		{
-			name:       "RemoteAddr with port",
-			remoteAddr: "203.0.113.100:12345",
-			want:       "203.0.113.100",
-		},
-		{
-			name:       "RemoteAddr without port",
-			remoteAddr: "203.0.113.100",
-			want:       "203.0.113.100",
-		},
-		{
-			name:       "X-Forwarded-For takes precedence",
-			xff:        "203.0.113.1",
-			xRealIP:    "203.0.113.50",
-			remoteAddr: "203.0.113.100:12345",
-			want:       "203.0.113.1",
+		{name: "enabled", allow: true, query: "?ip=8.8.8.8", status: http.StatusOK, looksUp: "8.8.8.8"},
+		{name: "enabled with prefix", allow: true, query: "?ip=8.8.8.0/24", status: http.StatusOK, looksUp: "8.8.8.0/24"},
+		{name: "enabled but empty", allow: true, query: "?ip=", status: http.StatusBadRequest, code: CodeMissingIP},
+		{name: "enabled without param", allow: true, status: http.StatusOK, looksUp: "203.0.113.100"},
+		{name: "disabled", query: "?ip=8.8.8.8", status: http.StatusOK, looksUp: "203.0.113.100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockGeoLookup{
+				result: &geodb.LookupResult{CountryCode: "US"},
+				prefix: &geodb.PrefixResult{Prefix: "8.8.8.0/24", CountryCode: "US"},
+			}
+			h := New(mock, Options{AllowIPOverride: tt.allow})
+
+			req := httptest.NewRequest(http.MethodGet, "/lookup"+tt.query, nil)
+			req.RemoteAddr = "203.0.113.100:12345"
+			w := httptest.NewRecorder()
+
+			h.LookupSelf(w, req)
+
+			if tt.code != "" {
+				assertError(t, w, tt.status, tt.code)
+				return
+			}
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if mock.lastIP != tt.looksUp {
+				t.Errorf("expected lookup of %s, got %s", tt.looksUp, mock.lastIP)
+			}
+		})
+	}
+}
+
+func TestLookupSelf_Anonymize(t *testing.T) {
+	log := &logtest.Recorder{}
+	mock := &mockGeoLookup{err: geodb.ErrIPNotFound}
+	h := New(mock, Options{AnonymizeIPs: true, Logger: log})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+	req.RemoteAddr = "[2001:db8:abcd:1234::77]:12345"
+	h.LookupSelf(httptest.NewRecorder(), req)
+
+	if mock.lastIP != "2001:db8:abcd::" {
+		t.Errorf("expected the anonymized address to be looked up, got %q", mock.lastIP)
+	}
+	if len(log.Entries()) != 1 || log.Entries()[0].Data["ip"] != "2001:db8:abcd::" {
+		t.Errorf("expected the anonymized address to be logged, got %+v", log.Entries())
+	}
+
+	// Addresses given explicitly are looked up as they are
+	req = httptest.NewRequest(http.MethodGet, "/lookup/203.0.113.77", nil)
+	h.LookupIP(httptest.NewRecorder(), req)
+	if mock.lastIP != "203.0.113.77" {
+		t.Errorf("expected an explicit address to be looked up as given, got %q", mock.lastIP)
+	}
+}
+
+func TestLookupSelf_UnknownClientIP(t *testing.T) {
+	h := New(&mockGeoLookup{}, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+	req.RemoteAddr = ""
+	w := httptest.NewRecorder()
+
+	h.LookupSelf(w, req)
+
+	assertError(t, w, http.StatusBadRequest, CodeClientIP)
+}
+
+func TestLookupIP_WithCity(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", PostalCode: "10001", City: "New York"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?city=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.City != "New York" {
+		t.Errorf("expected city 'New York', got %q", resp.City)
+	}
+}
+
+func TestLookupIP_CityOmittedByDefault(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", City: "New York"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if _, ok := resp["city"]; ok {
+		t.Error("expected city to be omitted without ?city=true")
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestLookupIP_WithCoords(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{
+			CountryCode:    "US",
+			Latitude:       floatPtr(40.7128),
+			Longitude:      floatPtr(-74.006),
+			AccuracyRadius: 20,
 		},
-		{
-			name:       "X-Real-IP takes precedence over RemoteAddr",
-			xRealIP:    "203.0.113.50",
-			remoteAddr: "203.0.113.100:12345",
-			want:       "203.0.113.50",
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?coords=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Latitude == nil || *resp.Latitude != 40.7128 {
+		t.Errorf("expected latitude 40.7128, got %v", resp.Latitude)
+	}
+
+	if resp.Longitude == nil || *resp.Longitude != -74.006 {
+		t.Errorf("expected longitude -74.006, got %v", resp.Longitude)
+	}
+
+	if resp.AccuracyRadius != 20 {
+		t.Errorf("expected accuracy_radius 20, got %d", resp.AccuracyRadius)
+	}
+}
+
+func TestLookupIP_WithZeroCoords(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{
+			CountryCode: "GH",
+			Latitude:    floatPtr(0),
+			Longitude:   floatPtr(0),
 		},
 	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?coords=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if _, ok := resp["latitude"]; !ok {
+		t.Error("expected latitude to be present for a (0,0) match")
+	}
+
+	if _, ok := resp["longitude"]; !ok {
+		t.Error("expected longitude to be present for a (0,0) match")
+	}
+}
+
+func TestLookupIP_CoordsOmittedForCountryMatch(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?coords=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if _, ok := resp["latitude"]; ok {
+		t.Error("expected latitude to be omitted for a country-only match")
+	}
+}
+
+func TestLookupIP_WithASN(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", ASN: 15169, ASOrg: "Google LLC"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?asn=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if !mock.lastOpts.ASN {
+		t.Error("expected ASN flag to reach the geo layer")
+	}
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.ASN != 15169 {
+		t.Errorf("expected asn 15169, got %d", resp.ASN)
+	}
+
+	if resp.ASOrg != "Google LLC" {
+		t.Errorf("expected as_org 'Google LLC', got %q", resp.ASOrg)
+	}
+}
+
+func TestLookupIP_ASNNotConfigured(t *testing.T) {
+	// Without an ASN database the geo layer returns no ASN data
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?asn=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if _, ok := resp["asn"]; ok {
+		t.Error("expected asn to be omitted when no ASN data is available")
+	}
+}
+
+// recordingMetrics captures observed lookup statuses
+type recordingMetrics struct {
+	statuses []string
+}
+
+func (m *recordingMetrics) ObserveLookup(status string, d time.Duration) {
+	m.statuses = append(m.statuses, status)
+}
+
+func TestLookupIP_RecordsMetrics(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "ok", want: metrics.StatusOK},
+		{name: "not found", err: geodb.ErrIPNotFound, want: metrics.StatusNotFound},
+		{name: "invalid", err: geodb.ErrInvalidIP, want: metrics.StatusInvalid},
+		{name: "internal", err: errors.New("boom"), want: metrics.StatusError},
+	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, "/", nil)
-			if tt.xff != "" {
-				req.Header.Set("X-Forwarded-For", tt.xff)
+			rec := &recordingMetrics{}
+			mock := &mockGeoLookup{
+				result: &geodb.LookupResult{CountryCode: "US"},
+				err:    tt.err,
 			}
-			if tt.xRealIP != "" {
-				req.Header.Set("X-Real-IP", tt.xRealIP)
+			h := New(mock, Options{Metrics: rec})
+
+			req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			if len(rec.statuses) != 1 || rec.statuses[0] != tt.want {
+				t.Errorf("expected status %q to be recorded, got %v", tt.want, rec.statuses)
 			}
-			if tt.remoteAddr != "" {
-				req.RemoteAddr = tt.remoteAddr
+		})
+	}
+}
+
+func TestLookupIP_TextFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		text   bool
+	}{
+		{name: "default is JSON", text: false},
+		{name: "format param", query: "?format=text", text: true},
+		{name: "format param overrides Accept", query: "?format=json", accept: "text/plain", text: false},
+		{name: "Accept text/plain", accept: "text/plain", text: true},
+		{name: "Accept prefers JSON", accept: "application/json, text/plain", text: false},
+		{name: "Accept prefers text", accept: "text/plain;q=0.9, application/json;q=0.8", text: true},
+		{name: "Accept wildcard", accept: "*/*", text: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockGeoLookup{
+				result: &geodb.LookupResult{CountryCode: "US"},
 			}
+			h := New(mock, Options{})
 
-			got := getClientIP(req)
-			if got != tt.want {
-				t.Errorf("getClientIP() = %q, want %q", got, tt.want)
+			req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
 			}
-		})
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+
+			ct := w.Header().Get("Content-Type")
+			if tt.text {
+				if ct != "text/plain; charset=utf-8" {
+					t.Errorf("expected text/plain Content-Type, got %s", ct)
+				}
+				if got := w.Body.String(); got != "US\n" {
+					t.Errorf("expected body %q, got %q", "US\n", got)
+				}
+			} else if ct != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %s", ct)
+			}
+		})
+	}
+}
+
+func TestLookupIP_TextFormatErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		err      error
+		wantCode int
+		wantBody string
+	}{
+		{name: "not found", path: "/lookup/10.0.0.1", err: geodb.ErrIPNotFound, wantCode: http.StatusNotFound, wantBody: "IP not found in database\n"},
+		{name: "invalid", path: "/lookup/garbage", err: geodb.ErrInvalidIP, wantCode: http.StatusBadRequest, wantBody: "invalid IP address\n"},
+		{name: "missing IP", path: "/lookup/", wantCode: http.StatusBadRequest, wantBody: "IP address required\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(&mockGeoLookup{err: tt.err}, Options{})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path+"?format=text", nil)
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+				t.Errorf("expected text/plain Content-Type, got %s", ct)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestLookupIP_WithNames(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "DE"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?names=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.CountryName != "Germany" {
+		t.Errorf("expected country name 'Germany', got %q", resp.CountryName)
+	}
+	if resp.ContinentCode != "EU" {
+		t.Errorf("expected continent code 'EU', got %q", resp.ContinentCode)
+	}
+}
+
+func TestLookupIP_Flag(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"US", "🇺🇸"},
+		{"", ""},
+		{"ZZ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			h := New(&mockGeoLookup{result: &geodb.LookupResult{CountryCode: tt.code}}, Options{})
+
+			req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?flag=true", nil)
+			w := httptest.NewRecorder()
+			h.LookupIP(w, req)
+
+			var body map[string]any
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			flag, ok := body["flag"]
+			if tt.want == "" && ok {
+				t.Errorf("expected flag to be omitted for %q, got %v", tt.code, flag)
+			}
+			if tt.want != "" && flag != tt.want {
+				t.Errorf("expected flag %q, got %v", tt.want, flag)
+			}
+		})
+	}
+}
+
+func TestLookupIP_NamesOmittedByDefault(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "DE"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	for _, key := range []string{"country_name", "continent_code"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("expected %s to be omitted without names=true", key)
+		}
+	}
+}
+
+func TestLookupIP_WithEU(t *testing.T) {
+	tests := []struct {
+		country string
+		want    bool
+	}{
+		{country: "DE", want: true},
+		{country: "US", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			mock := &mockGeoLookup{
+				result: &geodb.LookupResult{CountryCode: tt.country},
+			}
+			h := New(mock, Options{})
+
+			req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?eu=true", nil)
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			var resp LookupResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if resp.IsInEU == nil {
+				t.Fatal("expected is_in_eu to be set")
+			}
+			if *resp.IsInEU != tt.want {
+				t.Errorf("expected is_in_eu %v, got %v", tt.want, *resp.IsInEU)
+			}
+		})
+	}
+}
+
+func TestLookupIP_WithTimezone(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{
+			CountryCode: "US",
+			Latitude:    floatPtr(34.05),
+			Longitude:   floatPtr(-118.24),
+		},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?tz=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Timezone != "America/Los_Angeles" {
+		t.Errorf("expected timezone 'America/Los_Angeles', got %q", resp.Timezone)
+	}
+	if !mock.lastOpts.UseCity {
+		t.Error("expected tz=true to use the city database")
+	}
+	if resp.Latitude != nil {
+		t.Error("expected coordinates to stay omitted without coords=true")
+	}
+}
+
+func TestLookupIP_TimezoneOmittedForCountryMatch(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?tz=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if _, ok := body["timezone"]; ok {
+		t.Error("expected timezone to be omitted without coordinates")
+	}
+}
+
+func TestLookupIP_WithVersion(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", IPVersion: "v6"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/2001:4860:4860::8888?version=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.IPVersion != "v6" {
+		t.Errorf("expected ip_version 'v6', got %q", resp.IPVersion)
+	}
+
+	// Omitted unless requested
+	req = httptest.NewRequest(http.MethodGet, "/lookup/2001:4860:4860::8888", nil)
+	w = httptest.NewRecorder()
+	h.LookupIP(w, req)
+
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := body["ip_version"]; ok {
+		t.Error("expected ip_version to be omitted without version=true")
+	}
+}
+
+func TestLookupIP_Network(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", Network: "8.8.8.0/24"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?network=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Network != "8.8.8.0/24" {
+		t.Errorf("expected network '8.8.8.0/24', got %q", resp.Network)
+	}
+
+	// Omitted unless requested
+	req = httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+	w = httptest.NewRecorder()
+	h.LookupIP(w, req)
+
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := body["network"]; ok {
+		t.Error("expected network to be omitted without network=true")
+	}
+}
+
+func TestLookupIP_Full(t *testing.T) {
+	lat, lon := 52.37, 4.89
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{
+			CountryCode: "NL", PostalCode: "1012", Region: "North Holland", City: "Amsterdam",
+			Latitude: &lat, Longitude: &lon, ASN: 64496, ASOrg: "Example",
+		},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/203.0.113.5?full=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if !mock.lastOpts.Full || !mock.lastOpts.UseCity || !mock.lastOpts.ASN {
+		t.Errorf("expected full=true to consult every database, got %+v", mock.lastOpts)
+	}
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.City != "Amsterdam" || resp.Region != "North Holland" || resp.PostalCode != "1012" {
+		t.Errorf("expected the city fields, got %+v", resp)
+	}
+	if resp.Latitude == nil || resp.Longitude == nil {
+		t.Errorf("expected coordinates, got %+v", resp)
+	}
+	if resp.ASN != 64496 || resp.ASOrg != "Example" {
+		t.Errorf("expected ASN data, got %+v", resp)
+	}
+}
+
+func TestLookupIP_WithRegion(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", Region: "California", City: "Mountain View"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?region=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if !mock.lastOpts.UseCity {
+		t.Error("expected region=true to use the city database")
+	}
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Region != "California" {
+		t.Errorf("expected region 'California', got %q", resp.Region)
+	}
+	if resp.City != "" {
+		t.Errorf("expected city to be omitted without city=true, got %q", resp.City)
+	}
+}
+
+func TestLookupIP_Prefix(t *testing.T) {
+	mock := &mockGeoLookup{
+		prefix: &geodb.PrefixResult{
+			Prefix:    "203.0.113.0/24",
+			Mixed:     true,
+			Countries: []geodb.CountryCount{{CountryCode: "US", Networks: 2}, {CountryCode: "CA", Networks: 1}},
+		},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/203.0.113.0/24", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if mock.lastIP != "203.0.113.0/24" {
+		t.Errorf("expected prefix lookup for 203.0.113.0/24, got %q", mock.lastIP)
+	}
+
+	var resp geodb.PrefixResult
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Mixed || len(resp.Countries) != 2 || resp.Countries[0].CountryCode != "US" {
+		t.Errorf("unexpected prefix response: %+v", resp)
+	}
+}
+
+func TestLookupIP_PrefixTooLarge(t *testing.T) {
+	mock := &mockGeoLookup{err: fmt.Errorf("%w: must be /16 or smaller", geodb.ErrPrefixTooLarge)}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/10.0.0.0/8", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestLookupPost(t *testing.T) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", PostalCode: "10001", City: "New York"},
+	}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodPost, "/lookup", strings.NewReader(`{"ip":"8.8.8.8","city":true}`))
+	w := httptest.NewRecorder()
+
+	h.LookupPost(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if mock.lastIP != "8.8.8.8" {
+		t.Errorf("expected lookup of 8.8.8.8, got %q", mock.lastIP)
+	}
+	if !mock.lastOpts.UseCity {
+		t.Error("expected city=true to use the city database")
+	}
+
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.CountryCode != "US" || resp.City != "New York" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestLookupPost_DefaultMode(t *testing.T) {
+	tests := []struct {
+		body        string
+		wantUseCity bool
+	}{
+		{`{"ip":"8.8.8.8"}`, true},
+		{`{"ip":"8.8.8.8","pc":false}`, false},
+		{`{"ip":"8.8.8.8","city":false,"names":true}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+			h := New(mock, Options{DefaultMode: ModeCity})
+
+			req := httptest.NewRequest(http.MethodPost, "/lookup", strings.NewReader(tt.body))
+			h.LookupPost(httptest.NewRecorder(), req)
+
+			if mock.lastOpts.UseCity != tt.wantUseCity {
+				t.Errorf("expected useCity=%v, got %v", tt.wantUseCity, mock.lastOpts.UseCity)
+			}
+		})
+	}
+}
+
+func TestLookup_RouteModes(t *testing.T) {
+	type route = func(*Handlers, http.ResponseWriter, *http.Request)
+	tests := []struct {
+		name        string
+		route       route
+		method      string
+		url         string
+		body        string
+		wantUseCity bool
+	}{
+		{"self", (*Handlers).LookupSelf, http.MethodGet, "/lookup", "", false},
+		{"self pc=true", (*Handlers).LookupSelf, http.MethodGet, "/lookup?pc=true", "", true},
+		{"ip", (*Handlers).LookupIP, http.MethodGet, "/lookup/8.8.8.8", "", true},
+		{"ip pc=false", (*Handlers).LookupIP, http.MethodGet, "/lookup/8.8.8.8?pc=false", "", false},
+		{"post", (*Handlers).LookupPost, http.MethodPost, "/lookup", `{"ip":"8.8.8.8"}`, true},
+		// Batch lookups keep the server default
+		{"batch", (*Handlers).LookupBatch, http.MethodPost, "/lookup/batch", `["8.8.8.8"]`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+			h := New(mock, Options{DefaultMode: ModeCountry, SelfMode: ModeCountry, IPMode: ModeCity})
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.RemoteAddr = "8.8.8.8:1234"
+			w := httptest.NewRecorder()
+			tt.route(h, w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if mock.lastOpts.UseCity != tt.wantUseCity {
+				t.Errorf("expected useCity=%v, got %v", tt.wantUseCity, mock.lastOpts.UseCity)
+			}
+		})
+	}
+}
+
+func TestLookupPost_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		msg    string
+	}{
+		{"missing ip", `{"city":true}`, http.StatusBadRequest, CodeMissingIP, "IP address required"},
+		{"blank ip", `{"ip":"  "}`, http.StatusBadRequest, CodeMissingIP, "IP address required"},
+		{"invalid ip", `{"ip":"not-an-ip"}`, http.StatusBadRequest, CodeInvalidIP, "invalid IP address"},
+		{"not an object", `["8.8.8.8"]`, http.StatusBadRequest, CodeInvalidBody, "request body must be a JSON object"},
+		{"too large", `{"ip":"` + strings.Repeat("1", maxLookupBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "request body too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tableGeoLookup{}, Options{})
+
+			req := httptest.NewRequest(http.MethodPost, "/lookup", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.LookupPost(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Error != tt.msg || resp.Code != tt.code {
+				t.Errorf("expected error %q (%s), got %q (%s)", tt.msg, tt.code, resp.Error, resp.Code)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	v := LookupResponse{CountryCode: "US", ASOrg: "AT&T <Services>"}
+
+	// Output must match what a plain json.Encoder would write
+	var want bytes.Buffer
+	_ = json.NewEncoder(&want).Encode(v)
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		writeJSON(w, http.StatusCreated, v)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected Content-Type application/json, got %s", ct)
+		}
+		if cl := w.Header().Get("Content-Length"); cl != fmt.Sprint(want.Len()) {
+			t.Errorf("expected Content-Length %d, got %s", want.Len(), cl)
+		}
+		if got := w.Body.String(); got != want.String() {
+			t.Errorf("expected body %q, got %q", want.String(), got)
+		}
+	}
+}
+
+func TestWriteJSON_EncodingError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusOK, map[string]any{"bad": func() {}})
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if got := w.Body.String(); got != "{\"error\":\"encoding failed\",\"code\":\"internal\"}\n" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
+
+// discardResponseWriter is a ResponseWriter that allocates nothing per request,
+// so benchmarks measure the handler alone.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+func BenchmarkLookupIP(b *testing.B) {
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US", PostalCode: "10001", City: "New York"},
+	}
+	h := New(mock, Options{})
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?city=true", nil)
+	w := &discardResponseWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		h.LookupIP(w, req)
 	}
 }
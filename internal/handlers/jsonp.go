@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+)
+
+// callbackPattern accepts plain and dotted JavaScript identifiers such as
+// handleGeo or app.geo.done. Anything else could inject script.
+var callbackPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// maxCallbackLen bounds the callback name echoed back to the client.
+const maxCallbackLen = 128
+
+func validCallback(name string) bool {
+	return len(name) <= maxCallbackLen && callbackPattern.MatchString(name)
+}
+
+// writeJSONP wraps v in a call to callback for clients loading the response
+// via a <script> tag. The callback must already have been validated.
+func writeJSONP(w http.ResponseWriter, status int, callback string, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "encoding failed", Code: CodeInternal})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(callback + "("))
+	_, _ = w.Write(body)
+	_, _ = w.Write([]byte(");\n"))
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+func TestLookupIP_JSONP(t *testing.T) {
+	mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?callback=app.onGeo", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/javascript; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if body := w.Body.String(); body != "app.onGeo({\"country_code\":\"US\"});\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestLookupIP_JSONPInvalidCallback(t *testing.T) {
+	for _, callback := range []string{"alert(1)", "a;b", "1abc", "a..b", "<script>", strings.Repeat("a", maxCallbackLen+1)} {
+		mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+		h := New(mock, Options{})
+
+		req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8", nil)
+		q := req.URL.Query()
+		q.Set("callback", callback)
+		req.URL.RawQuery = q.Encode()
+		w := httptest.NewRecorder()
+
+		h.LookupIP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("callback %q: expected status %d, got %d", callback, http.StatusBadRequest, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("callback %q: expected JSON error, got Content-Type %q", callback, ct)
+		}
+		if mock.lastIP != "" {
+			t.Errorf("callback %q: lookup should not run", callback)
+		}
+	}
+}
+
+func TestLookupIP_JSONPError(t *testing.T) {
+	mock := &mockGeoLookup{err: geodb.ErrIPNotFound}
+	h := New(mock, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?callback=onGeo", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected errors to stay JSON, got Content-Type %q", ct)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRDNSTimeout bounds reverse DNS lookups when Options.RDNSTimeout is not set.
+const DefaultRDNSTimeout = 2 * time.Second
+
+const (
+	// batchRDNSTimeout bounds all the PTR lookups of one batch request
+	// together, so a large batch can't hold its slot for minutes.
+	batchRDNSTimeout = 5 * time.Second
+	// batchRDNSParallel caps the PTR lookups a batch request runs at once.
+	batchRDNSParallel = 8
+)
+
+// ReverseResolver resolves an IP to host names. *net.Resolver implements it.
+type ReverseResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// reverseLookup returns the first PTR name for ip, or "" if the lookup fails
+// or doesn't finish within the timeout.
+func (h *Handlers) reverseLookup(ctx context.Context, ip string) string {
+	ctx, cancel := context.WithTimeout(ctx, h.rdnsTimeout)
+	defer cancel()
+
+	names, err := h.resolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// reverseLookupBatch fills in the host names of the successful results,
+// running the PTR lookups in parallel under a shared deadline. Lookups that
+// don't finish in time leave the host name out, as a single lookup does.
+func (h *Handlers) reverseLookupBatch(ctx context.Context, results []BatchResult) {
+	if !scopeAllows(ctx, "hostname") {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, batchRDNSTimeout)
+	defer cancel()
+
+	sem := make(chan struct{}, batchRDNSParallel)
+	var wg sync.WaitGroup
+	for i := range results {
+		resp := results[i].LookupResponse
+		if resp == nil || resp.Private {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp.Hostname = h.reverseLookup(ctx, ip)
+		}(results[i].IP)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+// stubResolver returns fixed names, or blocks until the context is done
+type stubResolver struct {
+	names  []string
+	err    error
+	block  bool
+	lastIP string
+}
+
+func (s *stubResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	s.lastIP = addr
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.names, s.err
+}
+
+func doRDNSLookup(t *testing.T, resolver ReverseResolver, timeout time.Duration, query string) map[string]any {
+	t.Helper()
+
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{CountryCode: "US"},
+	}
+	h := New(mock, Options{Resolver: resolver, RDNSTimeout: timeout})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8"+query, nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return body
+}
+
+func TestLookupIP_WithRDNS(t *testing.T) {
+	resolver := &stubResolver{names: []string{"dns.google.", "other.example."}}
+
+	body := doRDNSLookup(t, resolver, 0, "?rdns=true")
+
+	if body["hostname"] != "dns.google" {
+		t.Errorf("expected hostname 'dns.google', got %v", body["hostname"])
+	}
+	if resolver.lastIP != "8.8.8.8" {
+		t.Errorf("expected PTR lookup for 8.8.8.8, got %q", resolver.lastIP)
+	}
+}
+
+func TestLookupIP_RDNSOmittedByDefault(t *testing.T) {
+	resolver := &stubResolver{names: []string{"dns.google."}}
+
+	body := doRDNSLookup(t, resolver, 0, "")
+
+	if _, ok := body["hostname"]; ok {
+		t.Error("expected hostname to be omitted without rdns=true")
+	}
+	if resolver.lastIP != "" {
+		t.Error("expected no PTR lookup without rdns=true")
+	}
+}
+
+func TestLookupIP_RDNSFailureOmitted(t *testing.T) {
+	resolver := &stubResolver{err: errors.New("no such host")}
+
+	body := doRDNSLookup(t, resolver, 0, "?rdns=true")
+
+	if _, ok := body["hostname"]; ok {
+		t.Error("expected hostname to be omitted when the PTR lookup fails")
+	}
+	if body["country_code"] != "US" {
+		t.Errorf("expected lookup to succeed, got %v", body)
+	}
+}
+
+func TestLookupIP_RDNSTimeout(t *testing.T) {
+	resolver := &stubResolver{block: true}
+
+	start := time.Now()
+	body := doRDNSLookup(t, resolver, 50*time.Millisecond, "?rdns=true")
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("slow resolver held the request for %v", elapsed)
+	}
+	if _, ok := body["hostname"]; ok {
+		t.Error("expected hostname to be omitted after a timeout")
+	}
+}
+
+// slowResolver answers after a delay and records the most lookups it saw
+// running at once.
+type slowResolver struct {
+	delay   time.Duration
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (s *slowResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	s.mu.Lock()
+	s.running++
+	s.peak = max(s.peak, s.running)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(s.delay):
+		return []string{"host-" + addr + "."}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestLookupBatch_RDNSParallel(t *testing.T) {
+	resolver := &slowResolver{delay: 20 * time.Millisecond}
+	geo := tableGeoLookup{}
+	var ips []string
+	for i := range 3 * batchRDNSParallel {
+		ip := fmt.Sprintf("203.0.113.%d", i+1)
+		geo[ip] = &geodb.LookupResult{CountryCode: "US"}
+		ips = append(ips, ip)
+	}
+	ips = append(ips, "bogus")
+	h := New(geo, Options{Resolver: resolver})
+
+	body, _ := json.Marshal(ips)
+	req := httptest.NewRequest(http.MethodPost, "/lookup/batch?rdns=true", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	h.LookupBatch(w, req)
+
+	var results []BatchResult
+	if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, r := range results {
+		if r.IP == "bogus" {
+			if r.LookupResponse != nil {
+				t.Errorf("expected no lookup for an invalid IP, got %+v", r)
+			}
+			continue
+		}
+		if r.LookupResponse == nil || r.Hostname != "host-"+r.IP {
+			t.Errorf("expected a host name for %s, got %+v", r.IP, r)
+		}
+	}
+	if resolver.peak < 2 || resolver.peak > batchRDNSParallel {
+		t.Errorf("expected between 2 and %d concurrent PTR lookups, got %d", batchRDNSParallel, resolver.peak)
+	}
+}
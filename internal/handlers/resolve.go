@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// DefaultResolveTimeout bounds host name lookups when Options.ResolveTimeout
+// is not set.
+const DefaultResolveTimeout = 2 * time.Second
+
+// HostResolver resolves a host name to addresses. *net.Resolver implements it.
+type HostResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Errors resolving a host name passed with ?resolve=true. Only a name that
+// doesn't exist or has no address is the client's problem; a DNS server
+// that fails or doesn't answer in time is ours.
+var (
+	errHostNotFound   = errors.New("host not found")
+	errResolveTimeout = errors.New("host lookup timed out")
+	errResolveFailed  = errors.New("host lookup failed")
+)
+
+// lookupHost returns the first A or AAAA address of host.
+func (h *Handlers) lookupHost(ctx context.Context, host string) (string, error) {
+	resolveCtx, cancel := context.WithTimeout(ctx, h.resolveTimeout)
+	defer cancel()
+
+	addrs, err := h.hostResolver.LookupNetIP(resolveCtx, "ip", host)
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(addrs) == 0:
+		return "", errHostNotFound
+	case err == nil:
+		return addrs[0].Unmap().String(), nil
+	case ctx.Err() != nil:
+		// The client gave up, not the DNS server
+		return "", ctx.Err()
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return "", errHostNotFound
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &dnsErr) && dnsErr.IsTimeout:
+		return "", errResolveTimeout
+	default:
+		return "", errResolveFailed
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/burakcan/ipburack/internal/geodb"
+)
+
+// stubHostResolver returns fixed addresses, or blocks until the context is done
+type stubHostResolver struct {
+	addrs    []netip.Addr
+	err      error
+	block    bool
+	lastHost string
+}
+
+func (s *stubHostResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	s.lastHost = host
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.addrs, s.err
+}
+
+func TestLookupIP_ResolveHost(t *testing.T) {
+	mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+	resolver := &stubHostResolver{addrs: []netip.Addr{
+		netip.MustParseAddr("::ffff:93.184.215.14"),
+		netip.MustParseAddr("2606:2800:21f:cb07:6820:80da:af6b:8b2c"),
+	}}
+	h := New(mock, Options{HostResolver: resolver})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/example.com?resolve=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if resolver.lastHost != "example.com" || mock.lastIP != "93.184.215.14" {
+		t.Errorf("expected example.com to be looked up as 93.184.215.14, got %q as %q", resolver.lastHost, mock.lastIP)
+	}
+	var resp LookupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ResolvedIP != "93.184.215.14" || resp.CountryCode != "US" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestLookupIP_ResolveHostNotNeeded(t *testing.T) {
+	mock := &mockGeoLookup{result: &geodb.LookupResult{CountryCode: "US"}}
+	resolver := &stubHostResolver{}
+	h := New(mock, Options{HostResolver: resolver})
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?resolve=true", nil)
+	w := httptest.NewRecorder()
+
+	h.LookupIP(w, req)
+
+	if resolver.lastHost != "" {
+		t.Errorf("expected an IP not to be resolved, got a lookup of %q", resolver.lastHost)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := body["resolved_ip"]; ok {
+		t.Error("expected no resolved_ip for an IP")
+	}
+}
+
+func TestLookupIP_ResolveHostErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		resolver *stubHostResolver
+		status   int
+		code     string
+	}{
+		{"without resolve", "", &stubHostResolver{addrs: []netip.Addr{netip.MustParseAddr("8.8.8.8")}}, http.StatusBadRequest, CodeInvalidIP},
+		{"unknown host", "?resolve=true", &stubHostResolver{err: &net.DNSError{Err: "no such host", IsNotFound: true}}, http.StatusNotFound, CodeHostNotFound},
+		{"no addresses", "?resolve=true", &stubHostResolver{}, http.StatusNotFound, CodeHostNotFound},
+		{"timeout", "?resolve=true", &stubHostResolver{block: true}, http.StatusGatewayTimeout, CodeResolveTimeout},
+		{"DNS server timeout", "?resolve=true", &stubHostResolver{err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, http.StatusGatewayTimeout, CodeResolveTimeout},
+		{"SERVFAIL", "?resolve=true", &stubHostResolver{err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}}, http.StatusServiceUnavailable, CodeResolveFailed},
+		{"other failure", "?resolve=true", &stubHostResolver{err: errors.New("connection refused")}, http.StatusServiceUnavailable, CodeResolveFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without resolving, the geo layer rejects the name as it always has
+			mock := &mockGeoLookup{err: geodb.ErrInvalidIP}
+			h := New(mock, Options{HostResolver: tt.resolver, ResolveTimeout: 10 * time.Millisecond})
+
+			req := httptest.NewRequest(http.MethodGet, "/lookup/example.invalid"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.LookupIP(w, req)
+
+			assertError(t, w, tt.status, tt.code)
+			if tt.query == "" && tt.resolver.lastHost != "" {
+				t.Error("expected no DNS lookup without ?resolve=true")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/burakcan/ipburack/internal/middleware"
+)
+
+// scopeFields clears each response field an API key scope can withhold,
+// keyed by JSON name. private and resolved_ip aren't database data and are
+// always kept.
+var scopeFields = map[string]func(*LookupResponse){
+	"country_code":    func(r *LookupResponse) { r.CountryCode = "" },
+	"country_name":    func(r *LookupResponse) { r.CountryName = "" },
+	"continent_code":  func(r *LookupResponse) { r.ContinentCode = "" },
+	"flag":            func(r *LookupResponse) { r.Flag = "" },
+	"is_in_eu":        func(r *LookupResponse) { r.IsInEU = nil },
+	"postal_code":     func(r *LookupResponse) { r.PostalCode = "" },
+	"region":          func(r *LookupResponse) { r.Region = "" },
+	"city":            func(r *LookupResponse) { r.City = "" },
+	"latitude":        func(r *LookupResponse) { r.Latitude = nil },
+	"longitude":       func(r *LookupResponse) { r.Longitude = nil },
+	"accuracy_radius": func(r *LookupResponse) { r.AccuracyRadius = 0 },
+	"asn":             func(r *LookupResponse) { r.ASN = 0 },
+	"as_org":          func(r *LookupResponse) { r.ASOrg = "" },
+	"hostname":        func(r *LookupResponse) { r.Hostname = "" },
+	"timezone":        func(r *LookupResponse) { r.Timezone = "" },
+	"ip_version":      func(r *LookupResponse) { r.IPVersion = "" },
+	"network":         func(r *LookupResponse) { r.Network = "" },
+}
+
+// applyScope drops the fields the caller's API key isn't entitled to,
+// whatever flags the request passed. Unscoped keys see everything.
+func applyScope(ctx context.Context, resp *LookupResponse) {
+	allowed, ok := middleware.KeyScope(ctx)
+	if !ok {
+		return
+	}
+	for name, drop := range scopeFields {
+		if !slices.Contains(allowed, name) {
+			drop(resp)
+		}
+	}
+}
+
+// scopeAllows reports whether the caller's API key may see field.
+func scopeAllows(ctx context.Context, field string) bool {
+	allowed, ok := middleware.KeyScope(ctx)
+	return !ok || slices.Contains(allowed, field)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/config"
+	"github.com/burakcan/ipburack/internal/geodb"
+	"github.com/burakcan/ipburack/internal/middleware"
+)
+
+func TestLookupIP_KeyScope(t *testing.T) {
+	lat, lon := 37.39, -122.08
+	mock := &mockGeoLookup{
+		result: &geodb.LookupResult{
+			CountryCode: "US", PostalCode: "94043", City: "Mountain View",
+			Latitude: &lat, Longitude: &lon, ASN: 15169, ASOrg: "Google LLC",
+		},
+	}
+	h := New(mock, Options{})
+	auth := middleware.NewAuth(map[string]string{"partner": "partner-key", "internal": "internal-key"}, middleware.SchemeAPIKey)
+	auth.SetScopes(map[string][]string{"partner": {"country_code", "country_name"}})
+	handler := auth.Wrap(h.LookupIP)
+
+	lookup := func(key string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.8?full=true&names=true", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return body
+	}
+
+	// The scope wins over the requested flags
+	body := lookup("partner-key")
+	if body["country_code"] != "US" || body["country_name"] != "United States" {
+		t.Errorf("expected the scoped fields, got %v", body)
+	}
+	for _, field := range []string{"postal_code", "city", "latitude", "longitude", "asn", "as_org"} {
+		if _, ok := body[field]; ok {
+			t.Errorf("expected %s to be withheld from a scoped key, got %v", field, body)
+		}
+	}
+
+	// Unscoped keys get everything they ask for
+	body = lookup("internal-key")
+	for _, field := range []string{"country_code", "postal_code", "city", "latitude", "asn"} {
+		if _, ok := body[field]; !ok {
+			t.Errorf("expected %s for an unscoped key, got %v", field, body)
+		}
+	}
+}
+
+func TestLookupPrefix_KeyScope(t *testing.T) {
+	mock := &mockGeoLookup{
+		prefix: &geodb.PrefixResult{Prefix: "8.8.8.0/24", CountryCode: "US", Countries: []geodb.CountryCount{{CountryCode: "US", Networks: 1}}},
+	}
+	h := New(mock, Options{})
+	auth := middleware.NewAuth(map[string]string{"geo": "geo-key", "asn": "asn-key"}, middleware.SchemeAPIKey)
+	auth.SetScopes(map[string][]string{"geo": {"country_code"}, "asn": {"asn", "as_org"}})
+	handler := auth.Wrap(h.LookupIP)
+
+	lookup := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/lookup/8.8.8.0/24", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	if w := lookup("geo-key"); w.Code != http.StatusOK {
+		t.Errorf("expected a key scoped to country_code to look up prefixes, got %d", w.Code)
+	}
+
+	mock.lastIP = ""
+	assertError(t, lookup("asn-key"), http.StatusForbidden, CodeOutOfScope)
+	if mock.lastIP != "" {
+		t.Error("expected no prefix lookup for a key without country_code")
+	}
+}
+
+// TestScopeFields keeps the fields config accepts in scopes in step with the
+// ones the handlers can withhold.
+func TestScopeFields(t *testing.T) {
+	if len(config.ScopeFields) != len(scopeFields) {
+		t.Errorf("config allows %d scope fields, handlers know %d", len(config.ScopeFields), len(scopeFields))
+	}
+	for _, name := range config.ScopeFields {
+		if _, ok := scopeFields[name]; !ok {
+			t.Errorf("scope field %q is accepted by config but not withheld", name)
+		}
+	}
+}
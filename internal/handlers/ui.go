@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed ui/index.html
+var uiFiles embed.FS
+
+var uiTemplate = template.Must(template.ParseFS(uiFiles, "ui/index.html"))
+
+// uiCSP confines the lookup page to its own inline script and style, and
+// to requests back to this server.
+const uiCSP = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; form-action 'none'; frame-ancestors 'none'"
+
+// UI serves a page for manual lookups. The page itself is public; it calls
+// POST /lookup from the browser, so lookups go through the usual API key
+// check and rate limits.
+func (h *Handlers) UI(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := uiTemplate.Execute(&buf, h.build); err != nil {
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", uiCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUI(t *testing.T) {
+	h := New(&mockGeoLookup{}, Options{Build: BuildInfo{Version: "v1.2.3<"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	h.UI(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("expected an HTML content type, got %q", ct)
+	}
+	if w.Header().Get("Content-Security-Policy") == "" {
+		t.Error("expected a Content-Security-Policy")
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `<form id="lookup">`) || !strings.Contains(body, "fetch('lookup'") {
+		t.Error("expected a lookup form posting to /lookup")
+	}
+	// The version is escaped like any other template value
+	if !strings.Contains(body, "ipburack v1.2.3&lt;") {
+		t.Error("expected the escaped build version in the page")
+	}
+}
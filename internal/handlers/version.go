@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+)
+
+// BuildInfo identifies the running binary. main fills it from variables set
+// at build time with -ldflags.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+}
+
+// VersionResponse describes the binary, as opposed to the databases it
+// serves (see /admin/databases).
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Version reports which build is running.
+func (h *Handlers) Version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, VersionResponse{
+		Version:   h.build.Version,
+		Commit:    h.build.Commit,
+		BuildDate: h.build.BuildDate,
+		GoVersion: runtime.Version(),
+	})
+}
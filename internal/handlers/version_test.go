@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	build := BuildInfo{Version: "v1.2.3", Commit: "abc1234", BuildDate: "2026-01-02T00:00:00Z"}
+	h := New(&mockGeoLookup{}, Options{Build: build})
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+
+	h.Version(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var fields map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&fields); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := map[string]string{
+		"version":    "v1.2.3",
+		"commit":     "abc1234",
+		"build_date": "2026-01-02T00:00:00Z",
+		"go_version": runtime.Version(),
+	}
+	for name, value := range want {
+		if got, ok := fields[name]; !ok || got != value {
+			t.Errorf("expected %s = %q, got %q", name, value, got)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package iso
+
+// countries maps ISO 3166-1 alpha-2 codes to English short names and
+// continent codes. Continent assignments follow GeoNames, which is what the
+// upstream MMDB sources use.
+var countries = map[string]Country{
+	"AD": {Name: "Andorra", Continent: "EU"},
+	"AE": {Name: "United Arab Emirates", Continent: "AS"},
+	"AF": {Name: "Afghanistan", Continent: "AS"},
+	"AG": {Name: "Antigua and Barbuda", Continent: "NA"},
+	"AI": {Name: "Anguilla", Continent: "NA"},
+	"AL": {Name: "Albania", Continent: "EU"},
+	"AM": {Name: "Armenia", Continent: "AS"},
+	"AO": {Name: "Angola", Continent: "AF"},
+	"AQ": {Name: "Antarctica", Continent: "AN"},
+	"AR": {Name: "Argentina", Continent: "SA"},
+	"AS": {Name: "American Samoa", Continent: "OC"},
+	"AT": {Name: "Austria", Continent: "EU"},
+	"AU": {Name: "Australia", Continent: "OC"},
+	"AW": {Name: "Aruba", Continent: "NA"},
+	"AX": {Name: "Åland Islands", Continent: "EU"},
+	"AZ": {Name: "Azerbaijan", Continent: "AS"},
+	"BA": {Name: "Bosnia and Herzegovina", Continent: "EU"},
+	"BB": {Name: "Barbados", Continent: "NA"},
+	"BD": {Name: "Bangladesh", Continent: "AS"},
+	"BE": {Name: "Belgium", Continent: "EU"},
+	"BF": {Name: "Burkina Faso", Continent: "AF"},
+	"BG": {Name: "Bulgaria", Continent: "EU"},
+	"BH": {Name: "Bahrain", Continent: "AS"},
+	"BI": {Name: "Burundi", Continent: "AF"},
+	"BJ": {Name: "Benin", Continent: "AF"},
+	"BL": {Name: "Saint Barthélemy", Continent: "NA"},
+	"BM": {Name: "Bermuda", Continent: "NA"},
+	"BN": {Name: "Brunei", Continent: "AS"},
+	"BO": {Name: "Bolivia", Continent: "SA"},
+	"BQ": {Name: "Bonaire, Sint Eustatius, and Saba", Continent: "NA"},
+	"BR": {Name: "Brazil", Continent: "SA"},
+	"BS": {Name: "Bahamas", Continent: "NA"},
+	"BT": {Name: "Bhutan", Continent: "AS"},
+	"BV": {Name: "Bouvet Island", Continent: "AN"},
+	"BW": {Name: "Botswana", Continent: "AF"},
+	"BY": {Name: "Belarus", Continent: "EU"},
+	"BZ": {Name: "Belize", Continent: "NA"},
+	"CA": {Name: "Canada", Continent: "NA"},
+	"CC": {Name: "Cocos (Keeling) Islands", Continent: "AS"},
+	"CD": {Name: "DR Congo", Continent: "AF"},
+	"CF": {Name: "Central African Republic", Continent: "AF"},
+	"CG": {Name: "Congo Republic", Continent: "AF"},
+	"CH": {Name: "Switzerland", Continent: "EU"},
+	"CI": {Name: "Ivory Coast", Continent: "AF"},
+	"CK": {Name: "Cook Islands", Continent: "OC"},
+	"CL": {Name: "Chile", Continent: "SA"},
+	"CM": {Name: "Cameroon", Continent: "AF"},
+	"CN": {Name: "China", Continent: "AS"},
+	"CO": {Name: "Colombia", Continent: "SA"},
+	"CR": {Name: "Costa Rica", Continent: "NA"},
+	"CU": {Name: "Cuba", Continent: "NA"},
+	"CV": {Name: "Cabo Verde", Continent: "AF"},
+	"CW": {Name: "Curaçao", Continent: "NA"},
+	"CX": {Name: "Christmas Island", Continent: "AS"},
+	"CY": {Name: "Cyprus", Continent: "EU"},
+	"CZ": {Name: "Czechia", Continent: "EU"},
+	"DE": {Name: "Germany", Continent: "EU"},
+	"DJ": {Name: "Djibouti", Continent: "AF"},
+	"DK": {Name: "Denmark", Continent: "EU"},
+	"DM": {Name: "Dominica", Continent: "NA"},
+	"DO": {Name: "Dominican Republic", Continent: "NA"},
+	"DZ": {Name: "Algeria", Continent: "AF"},
+	"EC": {Name: "Ecuador", Continent: "SA"},
+	"EE": {Name: "Estonia", Continent: "EU"},
+	"EG": {Name: "Egypt", Continent: "AF"},
+	"EH": {Name: "Western Sahara", Continent: "AF"},
+	"ER": {Name: "Eritrea", Continent: "AF"},
+	"ES": {Name: "Spain", Continent: "EU"},
+	"ET": {Name: "Ethiopia", Continent: "AF"},
+	"FI": {Name: "Finland", Continent: "EU"},
+	"FJ": {Name: "Fiji", Continent: "OC"},
+	"FK": {Name: "Falkland Islands", Continent: "SA"},
+	"FM": {Name: "Micronesia", Continent: "OC"},
+	"FO": {Name: "Faroe Islands", Continent: "EU"},
+	"FR": {Name: "France", Continent: "EU"},
+	"GA": {Name: "Gabon", Continent: "AF"},
+	"GB": {Name: "United Kingdom", Continent: "EU"},
+	"GD": {Name: "Grenada", Continent: "NA"},
+	"GE": {Name: "Georgia", Continent: "AS"},
+	"GF": {Name: "French Guiana", Continent: "SA"},
+	"GG": {Name: "Guernsey", Continent: "EU"},
+	"GH": {Name: "Ghana", Continent: "AF"},
+	"GI": {Name: "Gibraltar", Continent: "EU"},
+	"GL": {Name: "Greenland", Continent: "NA"},
+	"GM": {Name: "Gambia", Continent: "AF"},
+	"GN": {Name: "Guinea", Continent: "AF"},
+	"GP": {Name: "Guadeloupe", Continent: "NA"},
+	"GQ": {Name: "Equatorial Guinea", Continent: "AF"},
+	"GR": {Name: "Greece", Continent: "EU"},
+	"GS": {Name: "South Georgia and the South Sandwich Islands", Continent: "AN"},
+	"GT": {Name: "Guatemala", Continent: "NA"},
+	"GU": {Name: "Guam", Continent: "OC"},
+	"GW": {Name: "Guinea-Bissau", Continent: "AF"},
+	"GY": {Name: "Guyana", Continent: "SA"},
+	"HK": {Name: "Hong Kong", Continent: "AS"},
+	"HM": {Name: "Heard Island and McDonald Islands", Continent: "AN"},
+	"HN": {Name: "Honduras", Continent: "NA"},
+	"HR": {Name: "Croatia", Continent: "EU"},
+	"HT": {Name: "Haiti", Continent: "NA"},
+	"HU": {Name: "Hungary", Continent: "EU"},
+	"ID": {Name: "Indonesia", Continent: "AS"},
+	"IE": {Name: "Ireland", Continent: "EU"},
+	"IL": {Name: "Israel", Continent: "AS"},
+	"IM": {Name: "Isle of Man", Continent: "EU"},
+	"IN": {Name: "India", Continent: "AS"},
+	"IO": {Name: "British Indian Ocean Territory", Continent: "AS"},
+	"IQ": {Name: "Iraq", Continent: "AS"},
+	"IR": {Name: "Iran", Continent: "AS"},
+	"IS": {Name: "Iceland", Continent: "EU"},
+	"IT": {Name: "Italy", Continent: "EU"},
+	"JE": {Name: "Jersey", Continent: "EU"},
+	"JM": {Name: "Jamaica", Continent: "NA"},
+	"JO": {Name: "Jordan", Continent: "AS"},
+	"JP": {Name: "Japan", Continent: "AS"},
+	"KE": {Name: "Kenya", Continent: "AF"},
+	"KG": {Name: "Kyrgyzstan", Continent: "AS"},
+	"KH": {Name: "Cambodia", Continent: "AS"},
+	"KI": {Name: "Kiribati", Continent: "OC"},
+	"KM": {Name: "Comoros", Continent: "AF"},
+	"KN": {Name: "Saint Kitts and Nevis", Continent: "NA"},
+	"KP": {Name: "North Korea", Continent: "AS"},
+	"KR": {Name: "South Korea", Continent: "AS"},
+	"KW": {Name: "Kuwait", Continent: "AS"},
+	"KY": {Name: "Cayman Islands", Continent: "NA"},
+	"KZ": {Name: "Kazakhstan", Continent: "AS"},
+	"LA": {Name: "Laos", Continent: "AS"},
+	"LB": {Name: "Lebanon", Continent: "AS"},
+	"LC": {Name: "Saint Lucia", Continent: "NA"},
+	"LI": {Name: "Liechtenstein", Continent: "EU"},
+	"LK": {Name: "Sri Lanka", Continent: "AS"},
+	"LR": {Name: "Liberia", Continent: "AF"},
+	"LS": {Name: "Lesotho", Continent: "AF"},
+	"LT": {Name: "Lithuania", Continent: "EU"},
+	"LU": {Name: "Luxembourg", Continent: "EU"},
+	"LV": {Name: "Latvia", Continent: "EU"},
+	"LY": {Name: "Libya", Continent: "AF"},
+	"MA": {Name: "Morocco", Continent: "AF"},
+	"MC": {Name: "Monaco", Continent: "EU"},
+	"MD": {Name: "Moldova", Continent: "EU"},
+	"ME": {Name: "Montenegro", Continent: "EU"},
+	"MF": {Name: "Saint Martin", Continent: "NA"},
+	"MG": {Name: "Madagascar", Continent: "AF"},
+	"MH": {Name: "Marshall Islands", Continent: "OC"},
+	"MK": {Name: "North Macedonia", Continent: "EU"},
+	"ML": {Name: "Mali", Continent: "AF"},
+	"MM": {Name: "Myanmar", Continent: "AS"},
+	"MN": {Name: "Mongolia", Continent: "AS"},
+	"MO": {Name: "Macao", Continent: "AS"},
+	"MP": {Name: "Northern Mariana Islands", Continent: "OC"},
+	"MQ": {Name: "Martinique", Continent: "NA"},
+	"MR": {Name: "Mauritania", Continent: "AF"},
+	"MS": {Name: "Montserrat", Continent: "NA"},
+	"MT": {Name: "Malta", Continent: "EU"},
+	"MU": {Name: "Mauritius", Continent: "AF"},
+	"MV": {Name: "Maldives", Continent: "AS"},
+	"MW": {Name: "Malawi", Continent: "AF"},
+	"MX": {Name: "Mexico", Continent: "NA"},
+	"MY": {Name: "Malaysia", Continent: "AS"},
+	"MZ": {Name: "Mozambique", Continent: "AF"},
+	"NA": {Name: "Namibia", Continent: "AF"},
+	"NC": {Name: "New Caledonia", Continent: "OC"},
+	"NE": {Name: "Niger", Continent: "AF"},
+	"NF": {Name: "Norfolk Island", Continent: "OC"},
+	"NG": {Name: "Nigeria", Continent: "AF"},
+	"NI": {Name: "Nicaragua", Continent: "NA"},
+	"NL": {Name: "Netherlands", Continent: "EU"},
+	"NO": {Name: "Norway", Continent: "EU"},
+	"NP": {Name: "Nepal", Continent: "AS"},
+	"NR": {Name: "Nauru", Continent: "OC"},
+	"NU": {Name: "Niue", Continent: "OC"},
+	"NZ": {Name: "New Zealand", Continent: "OC"},
+	"OM": {Name: "Oman", Continent: "AS"},
+	"PA": {Name: "Panama", Continent: "NA"},
+	"PE": {Name: "Peru", Continent: "SA"},
+	"PF": {Name: "French Polynesia", Continent: "OC"},
+	"PG": {Name: "Papua New Guinea", Continent: "OC"},
+	"PH": {Name: "Philippines", Continent: "AS"},
+	"PK": {Name: "Pakistan", Continent: "AS"},
+	"PL": {Name: "Poland", Continent: "EU"},
+	"PM": {Name: "Saint Pierre and Miquelon", Continent: "NA"},
+	"PN": {Name: "Pitcairn Islands", Continent: "OC"},
+	"PR": {Name: "Puerto Rico", Continent: "NA"},
+	"PS": {Name: "Palestine", Continent: "AS"},
+	"PT": {Name: "Portugal", Continent: "EU"},
+	"PW": {Name: "Palau", Continent: "OC"},
+	"PY": {Name: "Paraguay", Continent: "SA"},
+	"QA": {Name: "Qatar", Continent: "AS"},
+	"RE": {Name: "Réunion", Continent: "AF"},
+	"RO": {Name: "Romania", Continent: "EU"},
+	"RS": {Name: "Serbia", Continent: "EU"},
+	"RU": {Name: "Russia", Continent: "EU"},
+	"RW": {Name: "Rwanda", Continent: "AF"},
+	"SA": {Name: "Saudi Arabia", Continent: "AS"},
+	"SB": {Name: "Solomon Islands", Continent: "OC"},
+	"SC": {Name: "Seychelles", Continent: "AF"},
+	"SD": {Name: "Sudan", Continent: "AF"},
+	"SE": {Name: "Sweden", Continent: "EU"},
+	"SG": {Name: "Singapore", Continent: "AS"},
+	"SH": {Name: "Saint Helena", Continent: "AF"},
+	"SI": {Name: "Slovenia", Continent: "EU"},
+	"SJ": {Name: "Svalbard and Jan Mayen", Continent: "EU"},
+	"SK": {Name: "Slovakia", Continent: "EU"},
+	"SL": {Name: "Sierra Leone", Continent: "AF"},
+	"SM": {Name: "San Marino", Continent: "EU"},
+	"SN": {Name: "Senegal", Continent: "AF"},
+	"SO": {Name: "Somalia", Continent: "AF"},
+	"SR": {Name: "Suriname", Continent: "SA"},
+	"SS": {Name: "South Sudan", Continent: "AF"},
+	"ST": {Name: "São Tomé and Príncipe", Continent: "AF"},
+	"SV": {Name: "El Salvador", Continent: "NA"},
+	"SX": {Name: "Sint Maarten", Continent: "NA"},
+	"SY": {Name: "Syria", Continent: "AS"},
+	"SZ": {Name: "Eswatini", Continent: "AF"},
+	"TC": {Name: "Turks and Caicos Islands", Continent: "NA"},
+	"TD": {Name: "Chad", Continent: "AF"},
+	"TF": {Name: "French Southern Territories", Continent: "AN"},
+	"TG": {Name: "Togo", Continent: "AF"},
+	"TH": {Name: "Thailand", Continent: "AS"},
+	"TJ": {Name: "Tajikistan", Continent: "AS"},
+	"TK": {Name: "Tokelau", Continent: "OC"},
+	"TL": {Name: "Timor-Leste", Continent: "AS"},
+	"TM": {Name: "Turkmenistan", Continent: "AS"},
+	"TN": {Name: "Tunisia", Continent: "AF"},
+	"TO": {Name: "Tonga", Continent: "OC"},
+	"TR": {Name: "Turkey", Continent: "AS"},
+	"TT": {Name: "Trinidad and Tobago", Continent: "NA"},
+	"TV": {Name: "Tuvalu", Continent: "OC"},
+	"TW": {Name: "Taiwan", Continent: "AS"},
+	"TZ": {Name: "Tanzania", Continent: "AF"},
+	"UA": {Name: "Ukraine", Continent: "EU"},
+	"UG": {Name: "Uganda", Continent: "AF"},
+	"UM": {Name: "U.S. Outlying Islands", Continent: "OC"},
+	"US": {Name: "United States", Continent: "NA"},
+	"UY": {Name: "Uruguay", Continent: "SA"},
+	"UZ": {Name: "Uzbekistan", Continent: "AS"},
+	"VA": {Name: "Vatican City", Continent: "EU"},
+	"VC": {Name: "Saint Vincent and the Grenadines", Continent: "NA"},
+	"VE": {Name: "Venezuela", Continent: "SA"},
+	"VG": {Name: "British Virgin Islands", Continent: "NA"},
+	"VI": {Name: "U.S. Virgin Islands", Continent: "NA"},
+	"VN": {Name: "Vietnam", Continent: "AS"},
+	"VU": {Name: "Vanuatu", Continent: "OC"},
+	"WF": {Name: "Wallis and Futuna", Continent: "OC"},
+	"WS": {Name: "Samoa", Continent: "OC"},
+	"XK": {Name: "Kosovo", Continent: "EU"}, // user-assigned, used by MaxMind
+	"YE": {Name: "Yemen", Continent: "AS"},
+	"YT": {Name: "Mayotte", Continent: "AF"},
+	"ZA": {Name: "South Africa", Continent: "AF"},
+	"ZM": {Name: "Zambia", Continent: "AF"},
+	"ZW": {Name: "Zimbabwe", Continent: "AF"},
+}
@@ -0,0 +1,17 @@
+package iso
+
+import "strings"
+
+// euMembers holds the EU member states as of 2020 (after the UK left).
+var euMembers = map[string]struct{}{
+	"AT": {}, "BE": {}, "BG": {}, "CY": {}, "CZ": {}, "DE": {}, "DK": {},
+	"EE": {}, "ES": {}, "FI": {}, "FR": {}, "GR": {}, "HR": {}, "HU": {},
+	"IE": {}, "IT": {}, "LT": {}, "LU": {}, "LV": {}, "MT": {}, "NL": {},
+	"PL": {}, "PT": {}, "RO": {}, "SE": {}, "SI": {}, "SK": {},
+}
+
+// IsEU reports whether an alpha-2 code is an EU member state.
+func IsEU(code string) bool {
+	_, ok := euMembers[strings.ToUpper(code)]
+	return ok
+}
@@ -0,0 +1,34 @@
+package iso
+
+import "testing"
+
+func TestIsEU(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{code: "DE", want: true},
+		{code: "fr", want: true},
+		{code: "US", want: false},
+		{code: "GB", want: false},
+		{code: "CH", want: false},
+		{code: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsEU(tt.code); got != tt.want {
+			t.Errorf("IsEU(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestEUMembers_Known(t *testing.T) {
+	if len(euMembers) != 27 {
+		t.Errorf("expected 27 EU member states, got %d", len(euMembers))
+	}
+	for code := range euMembers {
+		if _, ok := countries[code]; !ok {
+			t.Errorf("EU member %q missing from country table", code)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package iso
+
+import "strings"
+
+// regionalIndicatorA is the regional indicator symbol for the letter A.
+// A pair of them spelling an alpha-2 code renders as that country's flag.
+const regionalIndicatorA = 0x1F1E6
+
+// Flag returns the flag emoji for an alpha-2 code, case-insensitively, or ""
+// when the code isn't a known country.
+func Flag(code string) string {
+	code = strings.ToUpper(code)
+	if _, ok := countries[code]; !ok {
+		return ""
+	}
+	return string([]rune{
+		regionalIndicatorA + rune(code[0]-'A'),
+		regionalIndicatorA + rune(code[1]-'A'),
+	})
+}
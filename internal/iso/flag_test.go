@@ -0,0 +1,24 @@
+package iso
+
+import "testing"
+
+func TestFlag(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"US", "🇺🇸"},
+		{"de", "🇩🇪"},
+		{"JP", "🇯🇵"},
+		{"", ""},
+		{"ZZ", ""},
+		{"USA", ""},
+		{"1A", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Flag(tt.code); got != tt.want {
+			t.Errorf("Flag(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
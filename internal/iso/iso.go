@@ -0,0 +1,18 @@
+// Package iso provides static ISO 3166-1 country data so responses can be
+// enriched without a larger database.
+package iso
+
+import "strings"
+
+// Country holds the static data for an ISO 3166-1 alpha-2 code.
+type Country struct {
+	Name string
+	// Continent is a two-letter code: AF, AN, AS, EU, NA, OC or SA
+	Continent string
+}
+
+// Lookup returns the country for an alpha-2 code, case-insensitively.
+func Lookup(code string) (Country, bool) {
+	c, ok := countries[strings.ToUpper(code)]
+	return c, ok
+}
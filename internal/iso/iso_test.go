@@ -0,0 +1,54 @@
+package iso
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		code      string
+		name      string
+		continent string
+	}{
+		{code: "US", name: "United States", continent: "NA"},
+		{code: "de", name: "Germany", continent: "EU"},
+		{code: "JP", name: "Japan", continent: "AS"},
+		{code: "BR", name: "Brazil", continent: "SA"},
+		{code: "AU", name: "Australia", continent: "OC"},
+		{code: "NG", name: "Nigeria", continent: "AF"},
+		{code: "AQ", name: "Antarctica", continent: "AN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			c, ok := Lookup(tt.code)
+			if !ok {
+				t.Fatalf("Lookup(%q) not found", tt.code)
+			}
+			if c.Name != tt.name || c.Continent != tt.continent {
+				t.Errorf("Lookup(%q) = %+v, want {%s %s}", tt.code, c, tt.name, tt.continent)
+			}
+		})
+	}
+}
+
+func TestLookup_Unknown(t *testing.T) {
+	for _, code := range []string{"", "ZZ", "USA"} {
+		if _, ok := Lookup(code); ok {
+			t.Errorf("Lookup(%q) unexpectedly found", code)
+		}
+	}
+}
+
+func TestCountries_Valid(t *testing.T) {
+	continents := map[string]bool{"AF": true, "AN": true, "AS": true, "EU": true, "NA": true, "OC": true, "SA": true}
+	for code, c := range countries {
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			t.Errorf("invalid code %q", code)
+		}
+		if c.Name == "" {
+			t.Errorf("%s: empty name", code)
+		}
+		if !continents[c.Continent] {
+			t.Errorf("%s: invalid continent %q", code, c.Continent)
+		}
+	}
+}
@@ -2,13 +2,49 @@ package logger
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Level is the minimum severity a Logger emits.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (lv Level) String() string {
+	return levelNames[lv]
+}
+
+// ParseLevel parses a level name (debug, info, warn or error), ignoring case.
+func ParseLevel(s string) (Level, error) {
+	for lv, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return lv, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
 type Logger struct {
-	mu sync.Mutex
+	mu    sync.Mutex
+	level Level
+	out   io.Writer
 }
 
 type LogEntry struct {
@@ -18,14 +54,24 @@ type LogEntry struct {
 	Data    map[string]any `json:"data,omitempty"`
 }
 
+// New returns a Logger that emits info and above.
 func New() *Logger {
-	return &Logger{}
+	return NewWithLevel(LevelInfo)
+}
+
+// NewWithLevel returns a Logger that drops entries below level.
+func NewWithLevel(level Level) *Logger {
+	return &Logger{level: level, out: os.Stdout}
 }
 
-func (l *Logger) log(level, message string, data map[string]any) {
+func (l *Logger) log(level Level, message string, data map[string]any) {
+	if level < l.level {
+		return
+	}
+
 	entry := LogEntry{
 		Time:    time.Now().UTC().Format(time.RFC3339),
-		Level:   level,
+		Level:   level.String(),
 		Message: message,
 		Data:    data,
 	}
@@ -33,17 +79,21 @@ func (l *Logger) log(level, message string, data map[string]any) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	_ = json.NewEncoder(os.Stdout).Encode(entry)
+	_ = json.NewEncoder(l.out).Encode(entry)
+}
+
+func (l *Logger) Debug(message string, data map[string]any) {
+	l.log(LevelDebug, message, data)
 }
 
 func (l *Logger) Info(message string, data map[string]any) {
-	l.log("info", message, data)
+	l.log(LevelInfo, message, data)
 }
 
 func (l *Logger) Error(message string, data map[string]any) {
-	l.log("error", message, data)
+	l.log(LevelError, message, data)
 }
 
 func (l *Logger) Warn(message string, data map[string]any) {
-	l.log("warn", message, data)
+	l.log(LevelWarn, message, data)
 }
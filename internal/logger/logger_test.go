@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Level
+		wantErr bool
+	}{
+		{in: "debug", want: LevelDebug},
+		{in: "info", want: LevelInfo},
+		{in: "WARN", want: LevelWarn},
+		{in: "Error", want: LevelError},
+		{in: "verbose", want: LevelInfo, wantErr: true},
+		{in: "", want: LevelInfo, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLevel(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevel_String(t *testing.T) {
+	for lv, name := range levelNames {
+		if got := lv.String(); got != name {
+			t.Errorf("Level(%d).String() = %q, want %q", lv, got, name)
+		}
+		if parsed, err := ParseLevel(lv.String()); err != nil || parsed != lv {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", name, parsed, err, lv)
+		}
+	}
+}
+
+func TestLogger_FiltersBelowLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := &Logger{level: LevelWarn, out: &buf}
+
+	l.Debug("debug", nil)
+	l.Info("info", nil)
+	l.Warn("warn", nil)
+	l.Error("error", nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %q", len(lines), buf.String())
+	}
+	for i, want := range []string{"warn", "error"} {
+		var entry LogEntry
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("entry %d is not JSON: %v", i, err)
+		}
+		if entry.Level != want || entry.Message != want {
+			t.Errorf("entry %d = %+v, want level and message %q", i, entry, want)
+		}
+	}
+}
+
+func TestLogger_Entry(t *testing.T) {
+	var buf bytes.Buffer
+	l := &Logger{level: LevelInfo, out: &buf}
+
+	l.Info("database loaded", map[string]any{"path": "/data/country.mmdb"})
+
+	var entry LogEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("entry is not JSON: %v", err)
+	}
+	if entry.Level != "info" || entry.Message != "database loaded" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Data["path"] != "/data/country.mmdb" {
+		t.Errorf("expected path in data, got %v", entry.Data)
+	}
+	if _, err := time.Parse(time.RFC3339, entry.Time); err != nil {
+		t.Errorf("expected RFC 3339 time, got %q", entry.Time)
+	}
+}
+
+func TestLogger_OmitsEmptyData(t *testing.T) {
+	var buf bytes.Buffer
+	l := &Logger{level: LevelInfo, out: &buf}
+
+	l.Info("started", nil)
+
+	if strings.Contains(buf.String(), `"data"`) {
+		t.Errorf("expected no data field, got %s", buf.String())
+	}
+}
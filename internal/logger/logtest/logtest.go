@@ -0,0 +1,57 @@
+// Package logtest provides a logger that records entries for tests to
+// inspect, satisfying the Logger interfaces of the other packages.
+package logtest
+
+import "sync"
+
+// Entry is a line written to a Recorder.
+type Entry struct {
+	Level   string
+	Message string
+	Data    map[string]any
+}
+
+// Recorder keeps every entry logged to it. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (r *Recorder) log(level, message string, data map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, Entry{Level: level, Message: message, Data: data})
+}
+
+func (r *Recorder) Debug(message string, data map[string]any) { r.log("debug", message, data) }
+func (r *Recorder) Info(message string, data map[string]any)  { r.log("info", message, data) }
+func (r *Recorder) Warn(message string, data map[string]any)  { r.log("warn", message, data) }
+func (r *Recorder) Error(message string, data map[string]any) { r.log("error", message, data) }
+
+// Entries returns everything logged so far, oldest first.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Level returns the entries logged at level, oldest first.
+func (r *Recorder) Level(level string) []Entry {
+	var entries []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Find returns the first entry with message.
+func (r *Recorder) Find(message string) (Entry, bool) {
+	for _, e := range r.Entries() {
+		if e.Message == message {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
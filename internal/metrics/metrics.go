@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lookup result statuses
+const (
+	StatusOK       = "ok"
+	StatusNotFound = "not_found"
+	StatusInvalid  = "invalid"
+	StatusError    = "error"
+	StatusPrivate  = "private"
+	// StatusCanceled counts lookups abandoned because the request ended
+	StatusCanceled = "canceled"
+)
+
+var statuses = []string{StatusOK, StatusNotFound, StatusInvalid, StatusPrivate, StatusCanceled, StatusError}
+
+// latencyBuckets are histogram upper bounds in seconds. MMDB lookups take
+// microseconds, so the buckets are skewed well below the usual defaults.
+var latencyBuckets = []float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01}
+
+// Metrics collects service metrics and serves them in the Prometheus text
+// exposition format. Lookup observations are lock-free since they sit on the
+// hot path; database gauges change rarely and use a mutex.
+type Metrics struct {
+	lookups      atomic.Uint64
+	byStatus     map[string]*atomic.Uint64
+	buckets      []atomic.Uint64
+	latencyCount atomic.Uint64
+	latencySum   atomic.Int64 // nanoseconds
+
+	mu        sync.Mutex
+	dbUpdated map[string]time.Time
+	dbStale   map[string]bool
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		byStatus:  make(map[string]*atomic.Uint64, len(statuses)),
+		buckets:   make([]atomic.Uint64, len(latencyBuckets)),
+		dbUpdated: make(map[string]time.Time),
+		dbStale:   make(map[string]bool),
+	}
+	for _, s := range statuses {
+		m.byStatus[s] = new(atomic.Uint64)
+	}
+	return m
+}
+
+// ObserveLookup records a lookup with its result status and latency.
+// Unknown statuses are counted as StatusError.
+func (m *Metrics) ObserveLookup(status string, d time.Duration) {
+	m.lookups.Add(1)
+
+	counter, ok := m.byStatus[status]
+	if !ok {
+		counter = m.byStatus[StatusError]
+	}
+	counter.Add(1)
+
+	seconds := d.Seconds()
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			m.buckets[i].Add(1)
+		}
+	}
+	m.latencyCount.Add(1)
+	m.latencySum.Add(int64(d))
+}
+
+// DatabaseUpdated records the time a database was last successfully loaded.
+func (m *Metrics) DatabaseUpdated(name string, t time.Time) {
+	m.mu.Lock()
+	m.dbUpdated[name] = t
+	m.mu.Unlock()
+}
+
+// DatabaseStale records whether a database is older than the configured max age.
+func (m *Metrics) DatabaseStale(name string, stale bool) {
+	m.mu.Lock()
+	m.dbStale[name] = stale
+	m.mu.Unlock()
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	m.write(w)
+}
+
+func (m *Metrics) write(w io.Writer) {
+	writeHeader(w, "ipburack_lookups_total", "counter", "Total number of lookups.")
+	_, _ = fmt.Fprintf(w, "ipburack_lookups_total %d\n", m.lookups.Load())
+
+	writeHeader(w, "ipburack_lookup_results_total", "counter", "Number of lookups by result status.")
+	for _, s := range statuses {
+		_, _ = fmt.Fprintf(w, "ipburack_lookup_results_total{status=%q} %d\n", s, m.byStatus[s].Load())
+	}
+
+	// Each observation increments every bucket it fits under, so the stored
+	// counts are already cumulative as Prometheus expects.
+	writeHeader(w, "ipburack_lookup_duration_seconds", "histogram", "Lookup latency in seconds.")
+	for i, le := range latencyBuckets {
+		_, _ = fmt.Fprintf(w, "ipburack_lookup_duration_seconds_bucket{le=%q} %d\n", formatFloat(le), m.buckets[i].Load())
+	}
+	count := m.latencyCount.Load()
+	_, _ = fmt.Fprintf(w, "ipburack_lookup_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	_, _ = fmt.Fprintf(w, "ipburack_lookup_duration_seconds_sum %s\n", formatFloat(time.Duration(m.latencySum.Load()).Seconds()))
+	_, _ = fmt.Fprintf(w, "ipburack_lookup_duration_seconds_count %d\n", count)
+
+	m.mu.Lock()
+	names := make([]string, 0, len(m.dbUpdated))
+	for name := range m.dbUpdated {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	writeHeader(w, "ipburack_database_last_update_timestamp_seconds", "gauge", "Unix time of the last successful database load.")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "ipburack_database_last_update_timestamp_seconds{database=%q} %d\n", name, m.dbUpdated[name].Unix())
+	}
+
+	names = names[:0]
+	for name := range m.dbStale {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	writeHeader(w, "ipburack_database_stale", "gauge", "Whether the database is older than the configured max age (1) or not (0).")
+	for _, name := range names {
+		stale := 0
+		if m.dbStale[name] {
+			stale = 1
+		}
+		_, _ = fmt.Fprintf(w, "ipburack_database_stale{database=%q} %d\n", name, stale)
+	}
+	m.mu.Unlock()
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain content type, got %q", ct)
+	}
+	return w.Body.String()
+}
+
+func TestMetrics_Lookups(t *testing.T) {
+	m := New()
+	m.ObserveLookup(StatusOK, 20*time.Microsecond)
+	m.ObserveLookup(StatusOK, 2*time.Millisecond)
+	m.ObserveLookup(StatusNotFound, 5*time.Microsecond)
+	m.ObserveLookup("bogus", time.Microsecond)
+
+	body := scrape(t, m)
+
+	for _, want := range []string{
+		"ipburack_lookups_total 4\n",
+		`ipburack_lookup_results_total{status="ok"} 2` + "\n",
+		`ipburack_lookup_results_total{status="not_found"} 1` + "\n",
+		`ipburack_lookup_results_total{status="invalid"} 0` + "\n",
+		`ipburack_lookup_results_total{status="error"} 1` + "\n",
+		`ipburack_lookup_duration_seconds_bucket{le="1e-05"} 2` + "\n",
+		`ipburack_lookup_duration_seconds_bucket{le="2.5e-05"} 3` + "\n",
+		`ipburack_lookup_duration_seconds_bucket{le="0.0025"} 4` + "\n",
+		`ipburack_lookup_duration_seconds_bucket{le="+Inf"} 4` + "\n",
+		"ipburack_lookup_duration_seconds_count 4\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_DatabaseUpdated(t *testing.T) {
+	m := New()
+	m.DatabaseUpdated("country", time.Unix(1700000000, 0))
+	m.DatabaseUpdated("city-ipv4", time.Unix(1700000100, 0))
+
+	body := scrape(t, m)
+
+	for _, want := range []string{
+		`ipburack_database_last_update_timestamp_seconds{database="country"} 1700000000` + "\n",
+		`ipburack_database_last_update_timestamp_seconds{database="city-ipv4"} 1700000100` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_DatabaseStale(t *testing.T) {
+	m := New()
+	m.DatabaseStale("country", true)
+	m.DatabaseStale("city-ipv4", false)
+
+	body := scrape(t, m)
+
+	for _, want := range []string{
+		`ipburack_database_stale{database="country"} 1` + "\n",
+		`ipburack_database_stale{database="city-ipv4"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
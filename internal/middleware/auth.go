@@ -1,38 +1,164 @@
 package middleware
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"strings"
+	"sync/atomic"
 )
 
+type contextKey struct{}
+
+type scopeKey struct{}
+
+// AuthScheme selects where clients may present their API key.
+type AuthScheme string
+
+const (
+	// SchemeAPIKey reads the key from the X-API-Key header
+	SchemeAPIKey AuthScheme = "apikey"
+	// SchemeBearer reads the key from an "Authorization: Bearer" header
+	SchemeBearer AuthScheme = "bearer"
+	// SchemeBoth accepts either, preferring the Authorization header
+	SchemeBoth AuthScheme = "both"
+)
+
+type apiKey struct {
+	name string
+	key  []byte
+}
+
 type AuthMiddleware struct {
-	apiKey []byte
+	// keys is replaced as a whole by SetKeys, so a request sees either the
+	// old set or the new one
+	keys atomic.Pointer[[]apiKey]
+	// scopes maps key names to the response fields they may see
+	scopes atomic.Pointer[map[string][]string]
+	scheme AuthScheme
 }
 
-func NewAuth(apiKey string) *AuthMiddleware {
-	return &AuthMiddleware{
-		apiKey: []byte(apiKey),
+// NewAuth accepts any of keys, a map of key name to key. Names identify the
+// caller to downstream handlers via KeyName. An empty or unknown scheme
+// selects SchemeAPIKey.
+func NewAuth(keys map[string]string, scheme AuthScheme) *AuthMiddleware {
+	if scheme != SchemeBearer && scheme != SchemeBoth {
+		scheme = SchemeAPIKey
 	}
+
+	a := &AuthMiddleware{scheme: scheme}
+	a.SetKeys(keys)
+	return a
 }
 
-func (a *AuthMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
-	// No API key configured = auth disabled
-	if len(a.apiKey) == 0 {
-		return next
+// SetKeys replaces the accepted keys, e.g. to rotate them without a
+// restart. Requests already authenticated are unaffected; with no keys,
+// authentication is disabled.
+func (a *AuthMiddleware) SetKeys(keys map[string]string) {
+	var list []apiKey
+	for name, key := range keys {
+		if key == "" {
+			continue
+		}
+		list = append(list, apiKey{name: name, key: []byte(key)})
 	}
+	a.keys.Store(&list)
+}
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		key := r.Header.Get("X-API-Key")
+// SetScopes limits the named keys to the given response fields (JSON
+// names). Keys without an entry are unscoped and see every field.
+func (a *AuthMiddleware) SetScopes(scopes map[string][]string) {
+	a.scopes.Store(&scopes)
+}
 
-		// Constant-time comparison prevents timing attacks
-		if subtle.ConstantTimeCompare([]byte(key), a.apiKey) != 1 {
+// Enabled reports whether any key is configured.
+func (a *AuthMiddleware) Enabled() bool {
+	return len(*a.keys.Load()) > 0
+}
+
+// KeyName returns the name of the API key that authenticated the request.
+func KeyName(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(contextKey{}).(string)
+	return name, ok
+}
+
+// KeyScope returns the response fields the API key that authenticated the
+// request may see; ok is false when the key is unscoped or auth is off.
+func KeyScope(ctx context.Context) (fields []string, ok bool) {
+	fields, ok = ctx.Value(scopeKey{}).([]string)
+	return fields, ok
+}
+
+// Wrap rejects requests without a valid key. The keys are checked per
+// request, so SetKeys applies to handlers already wrapped.
+func (a *AuthMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := a.Authenticate(r.Context(), r.Header.Get)
+		if !ok {
+			if a.scheme != SchemeAPIKey {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid or missing API key"})
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid or missing API key", "code": "unauthorized"})
 			return
 		}
 
-		next(w, r)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// Authenticate checks the key presented in the headers the scheme allows,
+// read through header, which returns the first value of a header. On success
+// the returned context carries the key name for KeyName and its scope for
+// KeyScope. It lets transports other than HTTP share the key checks; with no
+// keys configured everything is accepted.
+func (a *AuthMiddleware) Authenticate(ctx context.Context, header func(name string) string) (context.Context, bool) {
+	keys := *a.keys.Load()
+	// No API key configured = auth disabled
+	if len(keys) == 0 {
+		return ctx, true
+	}
+	name, ok := match(keys, []byte(a.credential(header)))
+	if !ok {
+		return ctx, false
+	}
+	ctx = context.WithValue(ctx, contextKey{}, name)
+	if scopes := a.scopes.Load(); scopes != nil {
+		if fields, ok := (*scopes)[name]; ok {
+			ctx = context.WithValue(ctx, scopeKey{}, fields)
+		}
+	}
+	return ctx, true
+}
+
+// credential returns the key presented in the headers the scheme allows.
+func (a *AuthMiddleware) credential(header func(name string) string) string {
+	if a.scheme != SchemeAPIKey {
+		// The auth scheme name is case-insensitive (RFC 9110)
+		auth := header("Authorization")
+		if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+			return strings.TrimSpace(auth[len("Bearer "):])
+		}
+		if a.scheme == SchemeBearer {
+			return ""
+		}
+	}
+	return header("X-API-Key")
+}
+
+// match compares key against every one of keys, without stopping at the
+// first hit, so timing doesn't reveal which key matched.
+func match(keys []apiKey, key []byte) (string, bool) {
+	var name string
+	matched := 0
+	for _, k := range keys {
+		// Constant-time comparison prevents timing attacks
+		if subtle.ConstantTimeCompare(key, k.key) == 1 {
+			name = k.name
+			matched = 1
+		}
 	}
+	return name, matched == 1
 }
@@ -8,7 +8,7 @@ import (
 )
 
 func TestAuthMiddleware_NoKeyConfigured(t *testing.T) {
-	auth := NewAuth("")
+	auth := NewAuth(nil, SchemeAPIKey)
 	called := false
 
 	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +31,7 @@ func TestAuthMiddleware_NoKeyConfigured(t *testing.T) {
 }
 
 func TestAuthMiddleware_ValidKey(t *testing.T) {
-	auth := NewAuth("secret-key")
+	auth := NewAuth(map[string]string{"default": "secret-key"}, SchemeAPIKey)
 	called := false
 
 	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
@@ -55,7 +55,7 @@ func TestAuthMiddleware_ValidKey(t *testing.T) {
 }
 
 func TestAuthMiddleware_InvalidKey(t *testing.T) {
-	auth := NewAuth("secret-key")
+	auth := NewAuth(map[string]string{"default": "secret-key"}, SchemeAPIKey)
 	called := false
 
 	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
@@ -82,13 +82,13 @@ func TestAuthMiddleware_InvalidKey(t *testing.T) {
 		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if resp["error"] == "" {
-		t.Error("expected error message in response")
+	if resp["error"] == "" || resp["code"] != "unauthorized" {
+		t.Errorf("expected error message and code in response, got %v", resp)
 	}
 }
 
 func TestAuthMiddleware_MissingKey(t *testing.T) {
-	auth := NewAuth("secret-key")
+	auth := NewAuth(map[string]string{"default": "secret-key"}, SchemeAPIKey)
 	called := false
 
 	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
@@ -111,7 +111,7 @@ func TestAuthMiddleware_MissingKey(t *testing.T) {
 }
 
 func TestAuthMiddleware_EmptyKey(t *testing.T) {
-	auth := NewAuth("secret-key")
+	auth := NewAuth(map[string]string{"default": "secret-key"}, SchemeAPIKey)
 	called := false
 
 	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
@@ -135,7 +135,7 @@ func TestAuthMiddleware_EmptyKey(t *testing.T) {
 }
 
 func TestAuthMiddleware_ContentType(t *testing.T) {
-	auth := NewAuth("secret-key")
+	auth := NewAuth(map[string]string{"default": "secret-key"}, SchemeAPIKey)
 
 	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -152,3 +152,256 @@ func TestAuthMiddleware_ContentType(t *testing.T) {
 		t.Errorf("expected Content-Type 'application/json', got %q", contentType)
 	}
 }
+
+func TestAuthMiddleware_MultipleKeys(t *testing.T) {
+	auth := NewAuth(map[string]string{
+		"frontend": "frontend-key",
+		"batch":    "batch-key",
+	}, SchemeAPIKey)
+
+	tests := []struct {
+		key      string
+		wantCode int
+		wantName string
+	}{
+		{key: "frontend-key", wantCode: http.StatusOK, wantName: "frontend"},
+		{key: "batch-key", wantCode: http.StatusOK, wantName: "batch"},
+		{key: "other-key", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			var gotName string
+			handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+				gotName, _ = KeyName(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if gotName != tt.wantName {
+				t.Errorf("expected key name %q, got %q", tt.wantName, gotName)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_Rotation(t *testing.T) {
+	do := func(auth *AuthMiddleware, key string) int {
+		handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+
+	// During rotation both the old and new key are accepted
+	overlap := NewAuth(map[string]string{"old": "key-v1", "new": "key-v2"}, SchemeAPIKey)
+	if code := do(overlap, "key-v1"); code != http.StatusOK {
+		t.Errorf("old key during rotation: expected status %d, got %d", http.StatusOK, code)
+	}
+	if code := do(overlap, "key-v2"); code != http.StatusOK {
+		t.Errorf("new key during rotation: expected status %d, got %d", http.StatusOK, code)
+	}
+
+	// Once the old key is retired it is rejected
+	rotated := NewAuth(map[string]string{"new": "key-v2"}, SchemeAPIKey)
+	if code := do(rotated, "key-v1"); code != http.StatusUnauthorized {
+		t.Errorf("retired key: expected status %d, got %d", http.StatusUnauthorized, code)
+	}
+	if code := do(rotated, "key-v2"); code != http.StatusOK {
+		t.Errorf("new key: expected status %d, got %d", http.StatusOK, code)
+	}
+}
+
+func TestAuthMiddleware_IgnoresEmptyKeys(t *testing.T) {
+	auth := NewAuth(map[string]string{"blank": ""}, SchemeAPIKey)
+	called := false
+
+	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, ok := KeyName(r.Context()); ok {
+			t.Error("expected no key name when auth is disabled")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if !called {
+		t.Error("handler should be called when only empty keys are configured")
+	}
+}
+
+func TestAuthMiddleware_Schemes(t *testing.T) {
+	tests := []struct {
+		name          string
+		scheme        AuthScheme
+		apiKey        string
+		authorization string
+		wantCode      int
+	}{
+		{name: "apikey accepts header", scheme: SchemeAPIKey, apiKey: "secret-key", wantCode: http.StatusOK},
+		{name: "apikey rejects bearer", scheme: SchemeAPIKey, authorization: "Bearer secret-key", wantCode: http.StatusUnauthorized},
+		{name: "bearer accepts token", scheme: SchemeBearer, authorization: "Bearer secret-key", wantCode: http.StatusOK},
+		{name: "bearer scheme is case-insensitive", scheme: SchemeBearer, authorization: "bearer secret-key", wantCode: http.StatusOK},
+		{name: "bearer rejects header", scheme: SchemeBearer, apiKey: "secret-key", wantCode: http.StatusUnauthorized},
+		{name: "bearer rejects wrong token", scheme: SchemeBearer, authorization: "Bearer wrong-key", wantCode: http.StatusUnauthorized},
+		{name: "bearer rejects basic auth", scheme: SchemeBearer, authorization: "Basic c2VjcmV0LWtleQ==", wantCode: http.StatusUnauthorized},
+		{name: "both accepts header", scheme: SchemeBoth, apiKey: "secret-key", wantCode: http.StatusOK},
+		{name: "both accepts token", scheme: SchemeBoth, authorization: "Bearer secret-key", wantCode: http.StatusOK},
+		{name: "both rejects missing", scheme: SchemeBoth, wantCode: http.StatusUnauthorized},
+		{name: "unknown scheme falls back to apikey", scheme: "digest", apiKey: "secret-key", wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuth(map[string]string{"default": "secret-key"}, tt.scheme)
+			handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_WWWAuthenticate(t *testing.T) {
+	tests := []struct {
+		scheme AuthScheme
+		want   string
+	}{
+		{scheme: SchemeAPIKey, want: ""},
+		{scheme: SchemeBearer, want: "Bearer"},
+		{scheme: SchemeBoth, want: "Bearer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scheme), func(t *testing.T) {
+			auth := NewAuth(map[string]string{"default": "secret-key"}, tt.scheme)
+			handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.want {
+				t.Errorf("expected WWW-Authenticate %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_SetKeys(t *testing.T) {
+	auth := NewAuth(map[string]string{"default": "old-key"}, SchemeAPIKey)
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(inFlight)
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(path, key string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+
+	// A request authenticated with the old key is in progress during the swap
+	slow := make(chan int, 1)
+	go func() { slow <- request("/slow", "old-key") }()
+	<-inFlight
+
+	// Lookups keep racing the swap; run with -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			request("/", "new-key")
+		}
+	}()
+	auth.SetKeys(map[string]string{"default": "new-key"})
+	<-done
+
+	close(release)
+	if code := <-slow; code != http.StatusOK {
+		t.Errorf("expected the in-flight request to complete, got %d", code)
+	}
+	if code := request("/", "old-key"); code != http.StatusUnauthorized {
+		t.Errorf("expected the old key to be rejected, got %d", code)
+	}
+	if code := request("/", "new-key"); code != http.StatusOK {
+		t.Errorf("expected the new key to be accepted, got %d", code)
+	}
+
+	// Keys can also be added to a server started without any
+	auth = NewAuth(nil, SchemeAPIKey)
+	handler = auth.Wrap(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	auth.SetKeys(map[string]string{"default": "new-key"})
+	if !auth.Enabled() {
+		t.Error("expected auth to be enabled after SetKeys")
+	}
+	if code := request("/", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong key to be rejected once keys are set, got %d", code)
+	}
+}
+
+func TestAuthMiddleware_KeyScope(t *testing.T) {
+	auth := NewAuth(map[string]string{"partner": "partner-key", "internal": "internal-key"}, SchemeAPIKey)
+	auth.SetScopes(map[string][]string{"partner": {"country_code"}})
+
+	var fields []string
+	var scoped bool
+	handler := auth.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		fields, scoped = KeyScope(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "partner-key")
+	handler(httptest.NewRecorder(), req)
+	if !scoped || len(fields) != 1 || fields[0] != "country_code" {
+		t.Errorf("KeyScope() = %v, %v, want the partner scope", fields, scoped)
+	}
+
+	// Keys without a scope see everything
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "internal-key")
+	handler(httptest.NewRecorder(), req)
+	if scoped {
+		t.Errorf("KeyScope() = %v, want an unscoped key", fields)
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCompressMinSize is used when NewCompressor is given no threshold.
+// Below it gzip's framing costs more than it saves.
+const DefaultCompressMinSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// Compressor gzips responses for clients that accept it.
+type Compressor struct {
+	minSize int
+	skip    []string
+}
+
+// NewCompressor compresses responses of at least minSize bytes. Requests for
+// any of the skip paths are passed through untouched.
+func NewCompressor(minSize int, skip ...string) *Compressor {
+	if minSize <= 0 {
+		minSize = DefaultCompressMinSize
+	}
+	return &Compressor{minSize: minSize, skip: skip}
+}
+
+func (c *Compressor) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(c.skip, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The response differs by Accept-Encoding even when this client
+		// gets it uncompressed
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Not deferred: after a panic the buffered output is dropped so the
+		// recoverer can still send a clean 500
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: c.minSize}
+		next.ServeHTTP(gw, r)
+		gw.close()
+	})
+}
+
+// acceptsGzip reports whether Accept-Encoding lists gzip with a non-zero q.
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				continue
+			}
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body reaches minSize, then either compresses or passes it through.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	started bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.started || w.status != 0 {
+		return
+	}
+	// Informational responses go straight out and don't end the header
+	if code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.started {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush commits to compression, since a streaming response is unlikely to
+// stay small, and pushes everything written so far to the client.
+func (w *gzipResponseWriter) Flush() {
+	if !w.started {
+		_ = w.start(true)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start sends the header, compressing if asked and the response allows it,
+// followed by anything buffered.
+func (w *gzipResponseWriter) start(compress bool) error {
+	w.started = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	h := w.ResponseWriter.Header()
+	// Don't double-encode, and leave bodiless responses alone
+	if h.Get("Content-Encoding") != "" || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		compress = false
+	}
+	if compress {
+		// net/http would otherwise sniff the compressed bytes
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// close finishes the response once the handler returns.
+func (w *gzipResponseWriter) close() {
+	if !w.started {
+		// The whole body fit under the threshold
+		if w.status == 0 && len(w.buf) == 0 {
+			// Nothing was written; let net/http send its implicit 200
+			return
+		}
+		_ = w.start(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveCompressed(t *testing.T, c *Compressor, path, acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	c.Wrap(handler).ServeHTTP(w, req)
+	return w
+}
+
+func writeBody(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}
+}
+
+func TestCompressor_LargeResponse(t *testing.T) {
+	body := strings.Repeat(`{"ip":"8.8.8.8","country_code":"US"},`, 100)
+	w := serveCompressed(t, NewCompressor(0), "/lookup/batch", "br, gzip", writeBody(http.StatusCreated, body))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("expected Vary: Accept-Encoding, got %q", got)
+	}
+	if w.Body.Len() >= len(body) {
+		t.Errorf("expected compressed body smaller than %d bytes, got %d", len(body), w.Body.Len())
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	got, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if string(got) != body {
+		t.Error("decompressed body does not match")
+	}
+}
+
+func TestCompressor_Uncompressed(t *testing.T) {
+	large := strings.Repeat("x", DefaultCompressMinSize)
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		body           string
+		wantVary       bool
+	}{
+		{"below threshold", "/lookup/8.8.8.8", "gzip", `{"country_code":"US"}`, true},
+		{"gzip not accepted", "/lookup/batch", "", large, true},
+		{"gzip refused", "/lookup/batch", "gzip;q=0", large, true},
+		{"skipped path", "/health", "gzip", large, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveCompressed(t, NewCompressor(0, "/health"), tt.path, tt.acceptEncoding, writeBody(http.StatusOK, tt.body))
+
+			if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("expected no Content-Encoding, got %q", got)
+			}
+			if w.Body.String() != tt.body {
+				t.Error("expected body to pass through unchanged")
+			}
+			if got := w.Header().Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Errorf("expected Vary set = %v, got header %q", tt.wantVary, w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCompressor_AlreadyEncoded(t *testing.T) {
+	body := strings.Repeat("x", DefaultCompressMinSize)
+	w := serveCompressed(t, NewCompressor(0), "/debug/pprof/profile", "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = io.WriteString(w, body)
+	})
+
+	if got := w.Header().Get("Content-Encoding"); got != "br" {
+		t.Errorf("expected existing encoding to be kept, got %q", got)
+	}
+	if w.Body.String() != body {
+		t.Error("expected body to pass through unchanged")
+	}
+}
+
+func TestCompressor_Flush(t *testing.T) {
+	w := serveCompressed(t, NewCompressor(0), "/lookup/bulk", "gzip", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "first\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() error = %v", err)
+		}
+		_, _ = io.WriteString(w, "second\n")
+	})
+
+	if !w.Flushed {
+		t.Error("expected the flush to reach the client")
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	got, _ := io.ReadAll(gz)
+	if string(got) != "first\nsecond\n" {
+		t.Errorf("unexpected streamed body %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// maxQueueWait is how long a request waits for a free slot before it is
+// turned away, so brief bursts queue while sustained overload fails fast.
+const maxQueueWait = 100 * time.Millisecond
+
+// ErrBusy is returned by Acquire when no slot frees up in time.
+var ErrBusy = errors.New("server busy, too many concurrent lookups")
+
+// ConcurrencyLimiter caps the number of requests handled at once.
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewConcurrencyLimiter allows up to max requests in flight; max <= 0
+// disables the limit.
+func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{wait: maxQueueWait}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+func (l *ConcurrencyLimiter) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	// No limit configured = unlimited
+	if l.slots == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		release, err := l.Acquire(r.Context())
+		if errors.Is(err, ErrBusy) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": ErrBusy.Error(), "code": "busy"})
+			return
+		}
+		if err != nil {
+			// The client gave up while queued; nobody is left to answer
+			return
+		}
+		defer release()
+
+		next(w, r)
+	}
+}
+
+// Acquire takes a slot, queueing briefly as Wrap does, for callers outside
+// net/http such as the gRPC server. It fails with ErrBusy if none frees up
+// in time, or with ctx's error if ctx ends first. On success release must be
+// called once the work is done.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, ErrBusy
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// blockingHandler holds its slot until release is closed.
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}
+}
+
+func TestConcurrencyLimiter_Disabled(t *testing.T) {
+	l := NewConcurrencyLimiter(0)
+	w := httptest.NewRecorder()
+	l.Wrap(okHandler)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestConcurrencyLimiter_Rejects(t *testing.T) {
+	l := NewConcurrencyLimiter(1)
+	l.wait = 10 * time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := l.Wrap(blockingHandler(started, release))
+
+	done := make(chan struct{})
+	go func() {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+
+	// The slot is released once the first request finishes
+	close(release)
+	<-done
+	w = httptest.NewRecorder()
+	l.Wrap(okHandler)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d after release, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestConcurrencyLimiter_CancelledWhileQueued(t *testing.T) {
+	l := NewConcurrencyLimiter(1)
+	l.wait = time.Minute
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := l.Wrap(blockingHandler(started, release))
+	go handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	called := false
+	finished := make(chan struct{})
+	go func() {
+		l.Wrap(func(w http.ResponseWriter, r *http.Request) { called = true })(httptest.NewRecorder(), req)
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued request did not give up after cancellation")
+	}
+	if called {
+		t.Error("expected cancelled request not to reach the handler")
+	}
+}
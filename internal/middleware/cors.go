@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+)
+
+const (
+	corsAllowMethods = "GET, HEAD, POST, OPTIONS"
+	corsAllowHeaders = "X-API-Key, Authorization, Content-Type"
+)
+
+// CORS adds cross-origin headers for browser clients on allowed origins.
+type CORS struct {
+	origins []string
+	any     bool
+}
+
+// NewCORS allows requests from origins; "*" allows any origin. With no
+// origins the middleware is a pass-through.
+func NewCORS(origins []string) *CORS {
+	return &CORS{
+		origins: origins,
+		any:     slices.Contains(origins, "*"),
+	}
+}
+
+func (c *CORS) Wrap(next http.Handler) http.Handler {
+	if len(c.origins) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Responses differ by origin, so caches must key on it
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" || !c.allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.any {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		// Answer preflights here; the routes don't register OPTIONS
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *CORS) allowed(origin string) bool {
+	return c.any || slices.Contains(c.origins, origin)
+}
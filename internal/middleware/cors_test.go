@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS_Disabled(t *testing.T) {
+	handler := NewCORS(nil).Wrap(http.HandlerFunc(okHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no Access-Control-Allow-Origin, got %q", got)
+	}
+}
+
+func TestCORS_AllowedOrigin(t *testing.T) {
+	handler := NewCORS([]string{"https://app.example.com"}).Wrap(http.HandlerFunc(okHandler))
+
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{origin: "https://app.example.com", want: "https://app.example.com"},
+		{origin: "https://evil.example.com", want: ""},
+		{origin: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.want, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("expected Vary: Origin, got %q", got)
+			}
+		})
+	}
+}
+
+func TestCORS_Wildcard(t *testing.T) {
+	handler := NewCORS([]string{"*"}).Wrap(http.HandlerFunc(okHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected Access-Control-Allow-Origin *, got %q", got)
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	called := false
+	handler := NewCORS([]string{"https://app.example.com"}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/lookup/8.8.8.8", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "X-API-Key")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if called {
+		t.Error("preflight should not reach the wrapped handler")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != corsAllowMethods {
+		t.Errorf("expected Access-Control-Allow-Methods %q, got %q", corsAllowMethods, got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != corsAllowHeaders {
+		t.Errorf("expected Access-Control-Allow-Headers %q, got %q", corsAllowHeaders, got)
+	}
+}
+
+func TestCORS_PreflightDisallowedOrigin(t *testing.T) {
+	called := false
+	handler := NewCORS([]string{"https://app.example.com"}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/lookup", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !called {
+		t.Error("disallowed preflight should be passed through")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Errorf("expected no Access-Control-Allow-Methods, got %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Logger matches logger.Logger so tests can substitute their own.
+type Logger interface {
+	Info(message string, data map[string]any)
+	Warn(message string, data map[string]any)
+	Error(message string, data map[string]any)
+}
+
+// responseWriter records the status code and body size written by a handler.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RequestLogger logs one line per HTTP request.
+type RequestLogger struct {
+	logger   Logger
+	clientIP func(*http.Request) string
+}
+
+// NewRequestLogger logs through logger, recording the client address
+// returned by clientIP.
+func NewRequestLogger(logger Logger, clientIP func(*http.Request) string) *RequestLogger {
+	return &RequestLogger{
+		logger:   logger,
+		clientIP: clientIP,
+	}
+}
+
+func (l *RequestLogger) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(rw, r)
+
+		status := rw.status
+		if status == 0 {
+			// Handler wrote nothing; net/http sends 200
+			status = http.StatusOK
+		}
+
+		data := map[string]any{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      status,
+			"bytes":       rw.bytes,
+			"client_ip":   l.clientIP(r),
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if id, ok := RequestID(r.Context()); ok {
+			data["request_id"] = id
+		}
+		l.logger.Info("request", data)
+	})
+}
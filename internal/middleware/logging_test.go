@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burakcan/ipburack/internal/logger/logtest"
+)
+
+func TestRequestLogger(t *testing.T) {
+	log := &logtest.Recorder{}
+	mw := NewRequestLogger(log, remoteAddrKey)
+
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not here"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup/10.0.0.1?city=true", nil)
+	req.RemoteAddr = "203.0.113.1"
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if len(log.Entries()) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(log.Entries()))
+	}
+	entry := log.Entries()[0]
+	if entry.Message != "request" {
+		t.Errorf("expected message 'request', got %q", entry.Message)
+	}
+
+	want := map[string]any{
+		"method":    http.MethodGet,
+		"path":      "/lookup/10.0.0.1",
+		"status":    http.StatusNotFound,
+		"bytes":     len("not here"),
+		"client_ip": "203.0.113.1",
+	}
+	for k, v := range want {
+		if entry.Data[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, entry.Data[k])
+		}
+	}
+	if _, ok := entry.Data["duration_ms"].(float64); !ok {
+		t.Errorf("expected duration_ms to be recorded, got %v", entry.Data["duration_ms"])
+	}
+}
+
+func TestRequestLogger_ImplicitStatus(t *testing.T) {
+	log := &logtest.Recorder{}
+	mw := NewRequestLogger(log, remoteAddrKey)
+
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if got := log.Entries()[0].Data["status"]; got != http.StatusOK {
+		t.Errorf("expected status %d, got %v", http.StatusOK, got)
+	}
+}
+
+func TestRequestLogger_ResponseController(t *testing.T) {
+	mw := NewRequestLogger(&logtest.Recorder{}, remoteAddrKey)
+
+	var deadlineErr error
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadlineErr = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}))
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if deadlineErr != nil {
+		t.Errorf("expected wrapped writer to support deadlines, got %v", deadlineErr)
+	}
+}
+
+func TestRequestLogger_RequestID(t *testing.T) {
+	log := &logtest.Recorder{}
+	handler := NewRequestIDs().Wrap(NewRequestLogger(log, remoteAddrKey).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(RequestIDHeader, "trace-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := log.Entries()[0].Data["request_id"]; got != "trace-42" {
+		t.Errorf("expected request_id 'trace-42', got %v", got)
+	}
+}
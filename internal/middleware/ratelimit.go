@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// minIdleTTL bounds how often idle buckets are swept
+const minIdleTTL = time.Minute
+
+// bucket is a token bucket for a single client.
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter throttles requests per client IP using token buckets.
+type RateLimiter struct {
+	rate     float64
+	burst    float64
+	clientIP func(*http.Request) string
+	now      func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	idleTTL   time.Duration
+	lastSweep time.Time
+}
+
+// NewRateLimiter allows rps requests per second per client with bursts of up
+// to burst requests. clientIP extracts the key for each request. rps <= 0
+// disables limiting; burst <= 0 defaults to one second's worth of requests.
+func NewRateLimiter(rps float64, burst int, clientIP func(*http.Request) string) *RateLimiter {
+	b := float64(burst)
+	if b <= 0 {
+		b = math.Max(1, math.Ceil(rps))
+	}
+
+	// A bucket idle long enough to refill is indistinguishable from a new
+	// one, so it can be dropped
+	idleTTL := minIdleTTL
+	if rps > 0 {
+		idleTTL = max(idleTTL, time.Duration(b/rps*float64(time.Second)))
+	}
+
+	return &RateLimiter{
+		rate:     rps,
+		burst:    b,
+		clientIP: clientIP,
+		now:      time.Now,
+		buckets:  make(map[string]*bucket),
+		idleTTL:  idleTTL,
+	}
+}
+
+func (l *RateLimiter) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	// No rate configured = limiting disabled
+	if l.rate <= 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if wait, ok := l.allow(l.clientIP(r)); !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "rate limit exceeded", "code": "rate_limited"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Allow takes a token from key's bucket, for callers outside net/http such as
+// the gRPC server. It always succeeds when limiting is disabled.
+func (l *RateLimiter) Allow(key string) (time.Duration, bool) {
+	if l.rate <= 0 {
+		return 0, true
+	}
+	return l.allow(key)
+}
+
+// allow takes a token from key's bucket. When none is available it returns
+// how long until one will be.
+func (l *RateLimiter) allow(key string) (time.Duration, bool) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst}
+		l.buckets[key] = b
+	} else {
+		elapsed := now.Sub(b.lastSeen).Seconds()
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return max(wait, time.Second), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep drops idle buckets so memory doesn't grow with every client ever
+// seen. Callers must hold l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func remoteAddrKey(r *http.Request) string {
+	return r.RemoteAddr
+}
+
+// newTestLimiter returns a limiter with a controllable clock.
+func newTestLimiter(rps float64, burst int) (*RateLimiter, *time.Time) {
+	l := NewRateLimiter(rps, burst, remoteAddrKey)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func doRateLimited(handler http.HandlerFunc, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	l := NewRateLimiter(0, 0, remoteAddrKey)
+	handler := l.Wrap(okHandler)
+
+	for range 100 {
+		if w := doRateLimited(handler, "203.0.113.1"); w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	l, _ := newTestLimiter(1, 3)
+	handler := l.Wrap(okHandler)
+
+	for i := range 3 {
+		if w := doRateLimited(handler, "203.0.113.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+		}
+	}
+
+	w := doRateLimited(handler, "203.0.113.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", ct)
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["error"] != "rate limit exceeded" || resp["code"] != "rate_limited" {
+		t.Errorf("unexpected error: %v", resp)
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	l, now := newTestLimiter(2, 1)
+	handler := l.Wrap(okHandler)
+
+	if w := doRateLimited(handler, "203.0.113.1"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := doRateLimited(handler, "203.0.113.1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	*now = now.Add(500 * time.Millisecond)
+	if w := doRateLimited(handler, "203.0.113.1"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d after refill, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRateLimiter_RetryAfter(t *testing.T) {
+	l, _ := newTestLimiter(0.2, 1)
+	handler := l.Wrap(okHandler)
+
+	doRateLimited(handler, "203.0.113.1")
+	w := doRateLimited(handler, "203.0.113.1")
+	if got := w.Header().Get("Retry-After"); got != "5" {
+		t.Errorf("expected Retry-After 5, got %q", got)
+	}
+}
+
+func TestRateLimiter_PerClient(t *testing.T) {
+	l, _ := newTestLimiter(1, 1)
+	handler := l.Wrap(okHandler)
+
+	if w := doRateLimited(handler, "203.0.113.1"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := doRateLimited(handler, "203.0.113.2"); w.Code != http.StatusOK {
+		t.Errorf("second client should have its own bucket, got %d", w.Code)
+	}
+	if w := doRateLimited(handler, "203.0.113.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestRateLimiter_ExpiresIdleBuckets(t *testing.T) {
+	l, now := newTestLimiter(1, 1)
+	handler := l.Wrap(okHandler)
+
+	doRateLimited(handler, "203.0.113.1")
+	doRateLimited(handler, "203.0.113.2")
+	if len(l.buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(l.buckets))
+	}
+
+	*now = now.Add(l.idleTTL)
+	doRateLimited(handler, "203.0.113.3")
+	if len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be dropped, got %d", len(l.buckets))
+	}
+	if _, ok := l.buckets["203.0.113.3"]; !ok {
+		t.Error("expected active client's bucket to be kept")
+	}
+}
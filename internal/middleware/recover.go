@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recoverer turns handler panics into logged 500 responses.
+type Recoverer struct {
+	logger Logger
+}
+
+func NewRecoverer(logger Logger) *Recoverer {
+	return &Recoverer{logger: logger}
+}
+
+func (rc *Recoverer) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// Deliberate aborts are handled by net/http
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			data := map[string]any{
+				"error":  fmt.Sprint(err),
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"stack":  string(debug.Stack()),
+			}
+			if id, ok := RequestID(r.Context()); ok {
+				data["request_id"] = id
+			}
+			rc.logger.Error("panic recovered", data)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "internal server error", "code": "internal"})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burakcan/ipburack/internal/logger/logtest"
+)
+
+func TestRecoverer(t *testing.T) {
+	log := &logtest.Recorder{}
+	handler := NewRecoverer(log).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++ // nil map write panics
+	}))
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/lookup/1.2.3.4")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", ct)
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body["error"] != "internal server error" {
+		t.Errorf("unexpected error message: %s", body["error"])
+	}
+
+	// The server keeps serving after a panic
+	resp2, err := http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatalf("second request failed: %v", err)
+	}
+	resp2.Body.Close()
+
+	if len(log.Entries()) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(log.Entries()))
+	}
+	entry := log.Entries()[0]
+	if entry.Message != "panic recovered" {
+		t.Errorf("expected message 'panic recovered', got %q", entry.Message)
+	}
+	if entry.Data["path"] != "/lookup/1.2.3.4" {
+		t.Errorf("expected path to be logged, got %v", entry.Data["path"])
+	}
+	if stack, _ := entry.Data["stack"].(string); !strings.Contains(stack, "TestRecoverer") {
+		t.Error("expected stack trace to be logged")
+	}
+}
+
+func TestRecoverer_NoPanic(t *testing.T) {
+	log := &logtest.Recorder{}
+	handler := NewRecoverer(log).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected status %d, got %d", http.StatusTeapot, w.Code)
+	}
+	if len(log.Entries()) != 0 {
+		t.Errorf("expected no log entries, got %d", len(log.Entries()))
+	}
+}
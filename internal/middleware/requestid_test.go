@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// serveWithRequestID runs a request through RequestIDs and returns the
+// response and the ID the handler saw in its context.
+func serveWithRequestID(t *testing.T, header string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	var seen string
+	handler := NewRequestIDs().Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+	if header != "" {
+		req.Header.Set(RequestIDHeader, header)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestRequestIDs_Propagates(t *testing.T) {
+	w, seen := serveWithRequestID(t, "abc-123")
+
+	if seen != "abc-123" {
+		t.Errorf("expected handler to see the caller's ID, got %q", seen)
+	}
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("expected ID echoed in the response, got %q", got)
+	}
+}
+
+func TestRequestIDs_Generates(t *testing.T) {
+	for _, header := range []string{"", "bad id\nforged log line", strings.Repeat("a", maxRequestIDLen+1)} {
+		w, seen := serveWithRequestID(t, header)
+
+		if !uuidPattern.MatchString(seen) {
+			t.Errorf("header %q: expected a generated UUID, got %q", header, seen)
+		}
+		if got := w.Header().Get(RequestIDHeader); got != seen {
+			t.Errorf("header %q: expected response ID %q, got %q", header, seen, got)
+		}
+	}
+}
+
+func TestRequestID_Missing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := RequestID(req.Context()); ok {
+		t.Error("expected no request ID outside the middleware")
+	}
+}
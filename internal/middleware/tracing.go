@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/burakcan/ipburack/internal/middleware"
+
+// Tracing starts a server span per request, continuing any trace the caller
+// propagated.
+type Tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracing uses the global tracer provider and propagator, so it must be
+// created after tracing is set up.
+func NewTracing() *Tracing {
+	return &Tracing{
+		tracer:     otel.Tracer(tracerName),
+		propagator: otel.GetTextMapPropagator(),
+	}
+}
+
+func (t *Tracing) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := t.tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		rw := &responseWriter{ResponseWriter: w}
+		req := r.WithContext(ctx)
+		next.ServeHTTP(rw, req)
+
+		// The mux records the matched pattern on the request it was given,
+		// which keeps span names low-cardinality
+		if req.Pattern != "" {
+			span.SetName(req.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(req.Pattern))
+		}
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
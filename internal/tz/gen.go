@@ -0,0 +1,115 @@
+//go:build ignore
+
+// gen.go generates zones.go from the tzdata zone.tab file, which lists a
+// reference location for every zone in every country.
+//
+//	go run gen.go -input /usr/share/zoneinfo/zone.tab
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	input := flag.String("input", "/usr/share/zoneinfo/zone.tab", "path to tzdata zone.tab")
+	output := flag.String("output", "zones.go", "output file")
+	flag.Parse()
+
+	f, err := os.Open(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go from tzdata zone.tab; DO NOT EDIT.\n\n")
+	buf.WriteString("package tz\n\n")
+	buf.WriteString("var zones = []zone{\n")
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			log.Fatalf("malformed line: %q", line)
+		}
+		lat, lon, err := parseISO6709(fields[1])
+		if err != nil {
+			log.Fatalf("%s: %v", fields[2], err)
+		}
+		fmt.Fprintf(&buf, "\t{Country: %q, Name: %q, Lat: %.4f, Lon: %.4f},\n", fields[0], fields[2], lat, lon)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseISO6709 parses zone.tab coordinates: ±DDMM±DDDMM or ±DDMMSS±DDDMMSS.
+func parseISO6709(s string) (float64, float64, error) {
+	i := strings.IndexAny(s[1:], "+-") + 1
+	if i == 0 {
+		return 0, 0, fmt.Errorf("invalid coordinates %q", s)
+	}
+	lat, err := parseDMS(s[:i], 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	lon, err := parseDMS(s[i:], 3)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+func parseDMS(s string, degDigits int) (float64, error) {
+	sign := 1.0
+	if s[0] == '-' {
+		sign = -1
+	}
+	digits := s[1:]
+
+	var parts []float64
+	for _, width := range []int{degDigits, 2, 2} {
+		if len(digits) == 0 {
+			break
+		}
+		if len(digits) < width {
+			return 0, fmt.Errorf("invalid coordinate %q", s)
+		}
+		v, err := strconv.Atoi(digits[:width])
+		if err != nil {
+			return 0, fmt.Errorf("invalid coordinate %q", s)
+		}
+		parts = append(parts, float64(v))
+		digits = digits[width:]
+	}
+
+	deg := parts[0]
+	if len(parts) > 1 {
+		deg += parts[1] / 60
+	}
+	if len(parts) > 2 {
+		deg += parts[2] / 3600
+	}
+	return sign * deg, nil
+}
@@ -0,0 +1,65 @@
+// Package tz maps coordinates to IANA time zones using the reference
+// locations from tzdata. It picks the nearest zone, which is approximate
+// near zone borders but needs no polygon data.
+package tz
+
+//go:generate go run gen.go -input /usr/share/zoneinfo/zone.tab
+
+import (
+	"math"
+	"strings"
+)
+
+// zone is a time zone's reference location (usually its principal city).
+type zone struct {
+	Country string
+	Name    string
+	Lat     float64
+	Lon     float64
+}
+
+// Lookup returns the IANA zone nearest to lat/lon. When countryCode is known
+// only that country's zones are considered, which keeps results on the right
+// side of national borders.
+func Lookup(lat, lon float64, countryCode string) string {
+	countryCode = strings.ToUpper(countryCode)
+	if countryCode != "" && hasZones(countryCode) {
+		return nearest(lat, lon, countryCode)
+	}
+	return nearest(lat, lon, "")
+}
+
+func hasZones(countryCode string) bool {
+	for _, z := range zones {
+		if z.Country == countryCode {
+			return true
+		}
+	}
+	return false
+}
+
+// nearest returns the closest zone, restricted to countryCode when non-empty.
+func nearest(lat, lon float64, countryCode string) string {
+	best := ""
+	bestDist := math.Inf(1)
+	for _, z := range zones {
+		if countryCode != "" && z.Country != countryCode {
+			continue
+		}
+		if d := distance(lat, lon, z.Lat, z.Lon); d < bestDist {
+			best, bestDist = z.Name, d
+		}
+	}
+	return best
+}
+
+// distance returns the great-circle angle between two points (haversine).
+// Only comparisons matter, so it isn't scaled to kilometers.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * math.Asin(math.Sqrt(a))
+}
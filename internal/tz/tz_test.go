@@ -0,0 +1,38 @@
+package tz
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		lat     float64
+		lon     float64
+		country string
+		want    string
+	}{
+		{name: "New York", lat: 40.71, lon: -74.01, country: "US", want: "America/New_York"},
+		{name: "Los Angeles", lat: 34.05, lon: -118.24, country: "US", want: "America/Los_Angeles"},
+		{name: "Chicago", lat: 41.88, lon: -87.63, country: "US", want: "America/Chicago"},
+		{name: "Berlin", lat: 52.52, lon: 13.40, country: "DE", want: "Europe/Berlin"},
+		{name: "Tokyo", lat: 35.68, lon: 139.69, country: "JP", want: "Asia/Tokyo"},
+		{name: "Sydney", lat: -33.87, lon: 151.21, country: "AU", want: "Australia/Sydney"},
+		{name: "Lowercase country", lat: 48.86, lon: 2.35, country: "fr", want: "Europe/Paris"},
+		{name: "No country", lat: 51.51, lon: -0.13, want: "Europe/London"},
+		{name: "Unknown country", lat: 51.51, lon: -0.13, country: "ZZ", want: "Europe/London"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lookup(tt.lat, tt.lon, tt.country); got != tt.want {
+				t.Errorf("Lookup(%v, %v, %q) = %q, want %q", tt.lat, tt.lon, tt.country, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup_CountryKeepsBorderSide(t *testing.T) {
+	// Strasbourg is nearer Zurich's reference point than Paris's, but is in France
+	if got := Lookup(48.58, 7.75, "FR"); got != "Europe/Paris" {
+		t.Errorf("Lookup() = %q, want Europe/Paris", got)
+	}
+}
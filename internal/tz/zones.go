@@ -0,0 +1,424 @@
+// Code generated by gen.go from tzdata zone.tab; DO NOT EDIT.
+
+package tz
+
+var zones = []zone{
+	{Country: "AD", Name: "Europe/Andorra", Lat: 42.5000, Lon: 1.5167},
+	{Country: "AE", Name: "Asia/Dubai", Lat: 25.3000, Lon: 55.3000},
+	{Country: "AF", Name: "Asia/Kabul", Lat: 34.5167, Lon: 69.2000},
+	{Country: "AG", Name: "America/Antigua", Lat: 17.0500, Lon: -61.8000},
+	{Country: "AI", Name: "America/Anguilla", Lat: 18.2000, Lon: -63.0667},
+	{Country: "AL", Name: "Europe/Tirane", Lat: 41.3333, Lon: 19.8333},
+	{Country: "AM", Name: "Asia/Yerevan", Lat: 40.1833, Lon: 44.5000},
+	{Country: "AO", Name: "Africa/Luanda", Lat: -8.8000, Lon: 13.2333},
+	{Country: "AQ", Name: "Antarctica/McMurdo", Lat: -77.8333, Lon: 166.6000},
+	{Country: "AQ", Name: "Antarctica/Casey", Lat: -66.2833, Lon: 110.5167},
+	{Country: "AQ", Name: "Antarctica/Davis", Lat: -68.5833, Lon: 77.9667},
+	{Country: "AQ", Name: "Antarctica/DumontDUrville", Lat: -66.6667, Lon: 140.0167},
+	{Country: "AQ", Name: "Antarctica/Mawson", Lat: -67.6000, Lon: 62.8833},
+	{Country: "AQ", Name: "Antarctica/Palmer", Lat: -64.8000, Lon: -64.1000},
+	{Country: "AQ", Name: "Antarctica/Rothera", Lat: -67.5667, Lon: -68.1333},
+	{Country: "AQ", Name: "Antarctica/Syowa", Lat: -69.0061, Lon: 39.5900},
+	{Country: "AQ", Name: "Antarctica/Troll", Lat: -72.0114, Lon: 2.5350},
+	{Country: "AQ", Name: "Antarctica/Vostok", Lat: -78.4000, Lon: 106.9000},
+	{Country: "AR", Name: "America/Argentina/Buenos_Aires", Lat: -34.6000, Lon: -58.4500},
+	{Country: "AR", Name: "America/Argentina/Cordoba", Lat: -31.4000, Lon: -64.1833},
+	{Country: "AR", Name: "America/Argentina/Salta", Lat: -24.7833, Lon: -65.4167},
+	{Country: "AR", Name: "America/Argentina/Jujuy", Lat: -24.1833, Lon: -65.3000},
+	{Country: "AR", Name: "America/Argentina/Tucuman", Lat: -26.8167, Lon: -65.2167},
+	{Country: "AR", Name: "America/Argentina/Catamarca", Lat: -28.4667, Lon: -65.7833},
+	{Country: "AR", Name: "America/Argentina/La_Rioja", Lat: -29.4333, Lon: -66.8500},
+	{Country: "AR", Name: "America/Argentina/San_Juan", Lat: -31.5333, Lon: -68.5167},
+	{Country: "AR", Name: "America/Argentina/Mendoza", Lat: -32.8833, Lon: -68.8167},
+	{Country: "AR", Name: "America/Argentina/San_Luis", Lat: -33.3167, Lon: -66.3500},
+	{Country: "AR", Name: "America/Argentina/Rio_Gallegos", Lat: -51.6333, Lon: -69.2167},
+	{Country: "AR", Name: "America/Argentina/Ushuaia", Lat: -54.8000, Lon: -68.3000},
+	{Country: "AS", Name: "Pacific/Pago_Pago", Lat: -14.2667, Lon: -170.7000},
+	{Country: "AT", Name: "Europe/Vienna", Lat: 48.2167, Lon: 16.3333},
+	{Country: "AU", Name: "Australia/Lord_Howe", Lat: -31.5500, Lon: 159.0833},
+	{Country: "AU", Name: "Antarctica/Macquarie", Lat: -54.5000, Lon: 158.9500},
+	{Country: "AU", Name: "Australia/Hobart", Lat: -42.8833, Lon: 147.3167},
+	{Country: "AU", Name: "Australia/Melbourne", Lat: -37.8167, Lon: 144.9667},
+	{Country: "AU", Name: "Australia/Sydney", Lat: -33.8667, Lon: 151.2167},
+	{Country: "AU", Name: "Australia/Broken_Hill", Lat: -31.9500, Lon: 141.4500},
+	{Country: "AU", Name: "Australia/Brisbane", Lat: -27.4667, Lon: 153.0333},
+	{Country: "AU", Name: "Australia/Lindeman", Lat: -20.2667, Lon: 149.0000},
+	{Country: "AU", Name: "Australia/Adelaide", Lat: -34.9167, Lon: 138.5833},
+	{Country: "AU", Name: "Australia/Darwin", Lat: -12.4667, Lon: 130.8333},
+	{Country: "AU", Name: "Australia/Perth", Lat: -31.9500, Lon: 115.8500},
+	{Country: "AU", Name: "Australia/Eucla", Lat: -31.7167, Lon: 128.8667},
+	{Country: "AW", Name: "America/Aruba", Lat: 12.5000, Lon: -69.9667},
+	{Country: "AX", Name: "Europe/Mariehamn", Lat: 60.1000, Lon: 19.9500},
+	{Country: "AZ", Name: "Asia/Baku", Lat: 40.3833, Lon: 49.8500},
+	{Country: "BA", Name: "Europe/Sarajevo", Lat: 43.8667, Lon: 18.4167},
+	{Country: "BB", Name: "America/Barbados", Lat: 13.1000, Lon: -59.6167},
+	{Country: "BD", Name: "Asia/Dhaka", Lat: 23.7167, Lon: 90.4167},
+	{Country: "BE", Name: "Europe/Brussels", Lat: 50.8333, Lon: 4.3333},
+	{Country: "BF", Name: "Africa/Ouagadougou", Lat: 12.3667, Lon: -1.5167},
+	{Country: "BG", Name: "Europe/Sofia", Lat: 42.6833, Lon: 23.3167},
+	{Country: "BH", Name: "Asia/Bahrain", Lat: 26.3833, Lon: 50.5833},
+	{Country: "BI", Name: "Africa/Bujumbura", Lat: -3.3833, Lon: 29.3667},
+	{Country: "BJ", Name: "Africa/Porto-Novo", Lat: 6.4833, Lon: 2.6167},
+	{Country: "BL", Name: "America/St_Barthelemy", Lat: 17.8833, Lon: -62.8500},
+	{Country: "BM", Name: "Atlantic/Bermuda", Lat: 32.2833, Lon: -64.7667},
+	{Country: "BN", Name: "Asia/Brunei", Lat: 4.9333, Lon: 114.9167},
+	{Country: "BO", Name: "America/La_Paz", Lat: -16.5000, Lon: -68.1500},
+	{Country: "BQ", Name: "America/Kralendijk", Lat: 12.1508, Lon: -68.2767},
+	{Country: "BR", Name: "America/Noronha", Lat: -3.8500, Lon: -32.4167},
+	{Country: "BR", Name: "America/Belem", Lat: -1.4500, Lon: -48.4833},
+	{Country: "BR", Name: "America/Fortaleza", Lat: -3.7167, Lon: -38.5000},
+	{Country: "BR", Name: "America/Recife", Lat: -8.0500, Lon: -34.9000},
+	{Country: "BR", Name: "America/Araguaina", Lat: -7.2000, Lon: -48.2000},
+	{Country: "BR", Name: "America/Maceio", Lat: -9.6667, Lon: -35.7167},
+	{Country: "BR", Name: "America/Bahia", Lat: -12.9833, Lon: -38.5167},
+	{Country: "BR", Name: "America/Sao_Paulo", Lat: -23.5333, Lon: -46.6167},
+	{Country: "BR", Name: "America/Campo_Grande", Lat: -20.4500, Lon: -54.6167},
+	{Country: "BR", Name: "America/Cuiaba", Lat: -15.5833, Lon: -56.0833},
+	{Country: "BR", Name: "America/Santarem", Lat: -2.4333, Lon: -54.8667},
+	{Country: "BR", Name: "America/Porto_Velho", Lat: -8.7667, Lon: -63.9000},
+	{Country: "BR", Name: "America/Boa_Vista", Lat: 2.8167, Lon: -60.6667},
+	{Country: "BR", Name: "America/Manaus", Lat: -3.1333, Lon: -60.0167},
+	{Country: "BR", Name: "America/Eirunepe", Lat: -6.6667, Lon: -69.8667},
+	{Country: "BR", Name: "America/Rio_Branco", Lat: -9.9667, Lon: -67.8000},
+	{Country: "BS", Name: "America/Nassau", Lat: 25.0833, Lon: -77.3500},
+	{Country: "BT", Name: "Asia/Thimphu", Lat: 27.4667, Lon: 89.6500},
+	{Country: "BW", Name: "Africa/Gaborone", Lat: -24.6500, Lon: 25.9167},
+	{Country: "BY", Name: "Europe/Minsk", Lat: 53.9000, Lon: 27.5667},
+	{Country: "BZ", Name: "America/Belize", Lat: 17.5000, Lon: -88.2000},
+	{Country: "CA", Name: "America/St_Johns", Lat: 47.5667, Lon: -52.7167},
+	{Country: "CA", Name: "America/Halifax", Lat: 44.6500, Lon: -63.6000},
+	{Country: "CA", Name: "America/Glace_Bay", Lat: 46.2000, Lon: -59.9500},
+	{Country: "CA", Name: "America/Moncton", Lat: 46.1000, Lon: -64.7833},
+	{Country: "CA", Name: "America/Goose_Bay", Lat: 53.3333, Lon: -60.4167},
+	{Country: "CA", Name: "America/Blanc-Sablon", Lat: 51.4167, Lon: -57.1167},
+	{Country: "CA", Name: "America/Toronto", Lat: 43.6500, Lon: -79.3833},
+	{Country: "CA", Name: "America/Iqaluit", Lat: 63.7333, Lon: -68.4667},
+	{Country: "CA", Name: "America/Atikokan", Lat: 48.7586, Lon: -91.6217},
+	{Country: "CA", Name: "America/Winnipeg", Lat: 49.8833, Lon: -97.1500},
+	{Country: "CA", Name: "America/Resolute", Lat: 74.6956, Lon: -94.8292},
+	{Country: "CA", Name: "America/Rankin_Inlet", Lat: 62.8167, Lon: -92.0831},
+	{Country: "CA", Name: "America/Regina", Lat: 50.4000, Lon: -104.6500},
+	{Country: "CA", Name: "America/Swift_Current", Lat: 50.2833, Lon: -107.8333},
+	{Country: "CA", Name: "America/Edmonton", Lat: 53.5500, Lon: -113.4667},
+	{Country: "CA", Name: "America/Cambridge_Bay", Lat: 69.1139, Lon: -105.0528},
+	{Country: "CA", Name: "America/Inuvik", Lat: 68.3497, Lon: -133.7167},
+	{Country: "CA", Name: "America/Creston", Lat: 49.1000, Lon: -116.5167},
+	{Country: "CA", Name: "America/Dawson_Creek", Lat: 55.7667, Lon: -120.2333},
+	{Country: "CA", Name: "America/Fort_Nelson", Lat: 58.8000, Lon: -122.7000},
+	{Country: "CA", Name: "America/Whitehorse", Lat: 60.7167, Lon: -135.0500},
+	{Country: "CA", Name: "America/Dawson", Lat: 64.0667, Lon: -139.4167},
+	{Country: "CA", Name: "America/Vancouver", Lat: 49.2667, Lon: -123.1167},
+	{Country: "CC", Name: "Indian/Cocos", Lat: -12.1667, Lon: 96.9167},
+	{Country: "CD", Name: "Africa/Kinshasa", Lat: -4.3000, Lon: 15.3000},
+	{Country: "CD", Name: "Africa/Lubumbashi", Lat: -11.6667, Lon: 27.4667},
+	{Country: "CF", Name: "Africa/Bangui", Lat: 4.3667, Lon: 18.5833},
+	{Country: "CG", Name: "Africa/Brazzaville", Lat: -4.2667, Lon: 15.2833},
+	{Country: "CH", Name: "Europe/Zurich", Lat: 47.3833, Lon: 8.5333},
+	{Country: "CI", Name: "Africa/Abidjan", Lat: 5.3167, Lon: -4.0333},
+	{Country: "CK", Name: "Pacific/Rarotonga", Lat: -21.2333, Lon: -159.7667},
+	{Country: "CL", Name: "America/Santiago", Lat: -33.4500, Lon: -70.6667},
+	{Country: "CL", Name: "America/Coyhaique", Lat: -45.5667, Lon: -72.0667},
+	{Country: "CL", Name: "America/Punta_Arenas", Lat: -53.1500, Lon: -70.9167},
+	{Country: "CL", Name: "Pacific/Easter", Lat: -27.1500, Lon: -109.4333},
+	{Country: "CM", Name: "Africa/Douala", Lat: 4.0500, Lon: 9.7000},
+	{Country: "CN", Name: "Asia/Shanghai", Lat: 31.2333, Lon: 121.4667},
+	{Country: "CN", Name: "Asia/Urumqi", Lat: 43.8000, Lon: 87.5833},
+	{Country: "CO", Name: "America/Bogota", Lat: 4.6000, Lon: -74.0833},
+	{Country: "CR", Name: "America/Costa_Rica", Lat: 9.9333, Lon: -84.0833},
+	{Country: "CU", Name: "America/Havana", Lat: 23.1333, Lon: -82.3667},
+	{Country: "CV", Name: "Atlantic/Cape_Verde", Lat: 14.9167, Lon: -23.5167},
+	{Country: "CW", Name: "America/Curacao", Lat: 12.1833, Lon: -69.0000},
+	{Country: "CX", Name: "Indian/Christmas", Lat: -10.4167, Lon: 105.7167},
+	{Country: "CY", Name: "Asia/Nicosia", Lat: 35.1667, Lon: 33.3667},
+	{Country: "CY", Name: "Asia/Famagusta", Lat: 35.1167, Lon: 33.9500},
+	{Country: "CZ", Name: "Europe/Prague", Lat: 50.0833, Lon: 14.4333},
+	{Country: "DE", Name: "Europe/Berlin", Lat: 52.5000, Lon: 13.3667},
+	{Country: "DE", Name: "Europe/Busingen", Lat: 47.7000, Lon: 8.6833},
+	{Country: "DJ", Name: "Africa/Djibouti", Lat: 11.6000, Lon: 43.1500},
+	{Country: "DK", Name: "Europe/Copenhagen", Lat: 55.6667, Lon: 12.5833},
+	{Country: "DM", Name: "America/Dominica", Lat: 15.3000, Lon: -61.4000},
+	{Country: "DO", Name: "America/Santo_Domingo", Lat: 18.4667, Lon: -69.9000},
+	{Country: "DZ", Name: "Africa/Algiers", Lat: 36.7833, Lon: 3.0500},
+	{Country: "EC", Name: "America/Guayaquil", Lat: -2.1667, Lon: -79.8333},
+	{Country: "EC", Name: "Pacific/Galapagos", Lat: -0.9000, Lon: -89.6000},
+	{Country: "EE", Name: "Europe/Tallinn", Lat: 59.4167, Lon: 24.7500},
+	{Country: "EG", Name: "Africa/Cairo", Lat: 30.0500, Lon: 31.2500},
+	{Country: "EH", Name: "Africa/El_Aaiun", Lat: 27.1500, Lon: -13.2000},
+	{Country: "ER", Name: "Africa/Asmara", Lat: 15.3333, Lon: 38.8833},
+	{Country: "ES", Name: "Europe/Madrid", Lat: 40.4000, Lon: -3.6833},
+	{Country: "ES", Name: "Africa/Ceuta", Lat: 35.8833, Lon: -5.3167},
+	{Country: "ES", Name: "Atlantic/Canary", Lat: 28.1000, Lon: -15.4000},
+	{Country: "ET", Name: "Africa/Addis_Ababa", Lat: 9.0333, Lon: 38.7000},
+	{Country: "FI", Name: "Europe/Helsinki", Lat: 60.1667, Lon: 24.9667},
+	{Country: "FJ", Name: "Pacific/Fiji", Lat: -18.1333, Lon: 178.4167},
+	{Country: "FK", Name: "Atlantic/Stanley", Lat: -51.7000, Lon: -57.8500},
+	{Country: "FM", Name: "Pacific/Chuuk", Lat: 7.4167, Lon: 151.7833},
+	{Country: "FM", Name: "Pacific/Pohnpei", Lat: 6.9667, Lon: 158.2167},
+	{Country: "FM", Name: "Pacific/Kosrae", Lat: 5.3167, Lon: 162.9833},
+	{Country: "FO", Name: "Atlantic/Faroe", Lat: 62.0167, Lon: -6.7667},
+	{Country: "FR", Name: "Europe/Paris", Lat: 48.8667, Lon: 2.3333},
+	{Country: "GA", Name: "Africa/Libreville", Lat: 0.3833, Lon: 9.4500},
+	{Country: "GB", Name: "Europe/London", Lat: 51.5083, Lon: -0.1253},
+	{Country: "GD", Name: "America/Grenada", Lat: 12.0500, Lon: -61.7500},
+	{Country: "GE", Name: "Asia/Tbilisi", Lat: 41.7167, Lon: 44.8167},
+	{Country: "GF", Name: "America/Cayenne", Lat: 4.9333, Lon: -52.3333},
+	{Country: "GG", Name: "Europe/Guernsey", Lat: 49.4547, Lon: -2.5361},
+	{Country: "GH", Name: "Africa/Accra", Lat: 5.5500, Lon: -0.2167},
+	{Country: "GI", Name: "Europe/Gibraltar", Lat: 36.1333, Lon: -5.3500},
+	{Country: "GL", Name: "America/Nuuk", Lat: 64.1833, Lon: -51.7333},
+	{Country: "GL", Name: "America/Danmarkshavn", Lat: 76.7667, Lon: -18.6667},
+	{Country: "GL", Name: "America/Scoresbysund", Lat: 70.4833, Lon: -21.9667},
+	{Country: "GL", Name: "America/Thule", Lat: 76.5667, Lon: -68.7833},
+	{Country: "GM", Name: "Africa/Banjul", Lat: 13.4667, Lon: -16.6500},
+	{Country: "GN", Name: "Africa/Conakry", Lat: 9.5167, Lon: -13.7167},
+	{Country: "GP", Name: "America/Guadeloupe", Lat: 16.2333, Lon: -61.5333},
+	{Country: "GQ", Name: "Africa/Malabo", Lat: 3.7500, Lon: 8.7833},
+	{Country: "GR", Name: "Europe/Athens", Lat: 37.9667, Lon: 23.7167},
+	{Country: "GS", Name: "Atlantic/South_Georgia", Lat: -54.2667, Lon: -36.5333},
+	{Country: "GT", Name: "America/Guatemala", Lat: 14.6333, Lon: -90.5167},
+	{Country: "GU", Name: "Pacific/Guam", Lat: 13.4667, Lon: 144.7500},
+	{Country: "GW", Name: "Africa/Bissau", Lat: 11.8500, Lon: -15.5833},
+	{Country: "GY", Name: "America/Guyana", Lat: 6.8000, Lon: -58.1667},
+	{Country: "HK", Name: "Asia/Hong_Kong", Lat: 22.2833, Lon: 114.1500},
+	{Country: "HN", Name: "America/Tegucigalpa", Lat: 14.1000, Lon: -87.2167},
+	{Country: "HR", Name: "Europe/Zagreb", Lat: 45.8000, Lon: 15.9667},
+	{Country: "HT", Name: "America/Port-au-Prince", Lat: 18.5333, Lon: -72.3333},
+	{Country: "HU", Name: "Europe/Budapest", Lat: 47.5000, Lon: 19.0833},
+	{Country: "ID", Name: "Asia/Jakarta", Lat: -6.1667, Lon: 106.8000},
+	{Country: "ID", Name: "Asia/Pontianak", Lat: -0.0333, Lon: 109.3333},
+	{Country: "ID", Name: "Asia/Makassar", Lat: -5.1167, Lon: 119.4000},
+	{Country: "ID", Name: "Asia/Jayapura", Lat: -2.5333, Lon: 140.7000},
+	{Country: "IE", Name: "Europe/Dublin", Lat: 53.3333, Lon: -6.2500},
+	{Country: "IL", Name: "Asia/Jerusalem", Lat: 31.7806, Lon: 35.2239},
+	{Country: "IM", Name: "Europe/Isle_of_Man", Lat: 54.1500, Lon: -4.4667},
+	{Country: "IN", Name: "Asia/Kolkata", Lat: 22.5333, Lon: 88.3667},
+	{Country: "IO", Name: "Indian/Chagos", Lat: -7.3333, Lon: 72.4167},
+	{Country: "IQ", Name: "Asia/Baghdad", Lat: 33.3500, Lon: 44.4167},
+	{Country: "IR", Name: "Asia/Tehran", Lat: 35.6667, Lon: 51.4333},
+	{Country: "IS", Name: "Atlantic/Reykjavik", Lat: 64.1500, Lon: -21.8500},
+	{Country: "IT", Name: "Europe/Rome", Lat: 41.9000, Lon: 12.4833},
+	{Country: "JE", Name: "Europe/Jersey", Lat: 49.1836, Lon: -2.1067},
+	{Country: "JM", Name: "America/Jamaica", Lat: 17.9681, Lon: -76.7933},
+	{Country: "JO", Name: "Asia/Amman", Lat: 31.9500, Lon: 35.9333},
+	{Country: "JP", Name: "Asia/Tokyo", Lat: 35.6544, Lon: 139.7447},
+	{Country: "KE", Name: "Africa/Nairobi", Lat: -1.2833, Lon: 36.8167},
+	{Country: "KG", Name: "Asia/Bishkek", Lat: 42.9000, Lon: 74.6000},
+	{Country: "KH", Name: "Asia/Phnom_Penh", Lat: 11.5500, Lon: 104.9167},
+	{Country: "KI", Name: "Pacific/Tarawa", Lat: 1.4167, Lon: 173.0000},
+	{Country: "KI", Name: "Pacific/Kanton", Lat: -2.7833, Lon: -171.7167},
+	{Country: "KI", Name: "Pacific/Kiritimati", Lat: 1.8667, Lon: -157.3333},
+	{Country: "KM", Name: "Indian/Comoro", Lat: -11.6833, Lon: 43.2667},
+	{Country: "KN", Name: "America/St_Kitts", Lat: 17.3000, Lon: -62.7167},
+	{Country: "KP", Name: "Asia/Pyongyang", Lat: 39.0167, Lon: 125.7500},
+	{Country: "KR", Name: "Asia/Seoul", Lat: 37.5500, Lon: 126.9667},
+	{Country: "KW", Name: "Asia/Kuwait", Lat: 29.3333, Lon: 47.9833},
+	{Country: "KY", Name: "America/Cayman", Lat: 19.3000, Lon: -81.3833},
+	{Country: "KZ", Name: "Asia/Almaty", Lat: 43.2500, Lon: 76.9500},
+	{Country: "KZ", Name: "Asia/Qyzylorda", Lat: 44.8000, Lon: 65.4667},
+	{Country: "KZ", Name: "Asia/Qostanay", Lat: 53.2000, Lon: 63.6167},
+	{Country: "KZ", Name: "Asia/Aqtobe", Lat: 50.2833, Lon: 57.1667},
+	{Country: "KZ", Name: "Asia/Aqtau", Lat: 44.5167, Lon: 50.2667},
+	{Country: "KZ", Name: "Asia/Atyrau", Lat: 47.1167, Lon: 51.9333},
+	{Country: "KZ", Name: "Asia/Oral", Lat: 51.2167, Lon: 51.3500},
+	{Country: "LA", Name: "Asia/Vientiane", Lat: 17.9667, Lon: 102.6000},
+	{Country: "LB", Name: "Asia/Beirut", Lat: 33.8833, Lon: 35.5000},
+	{Country: "LC", Name: "America/St_Lucia", Lat: 14.0167, Lon: -61.0000},
+	{Country: "LI", Name: "Europe/Vaduz", Lat: 47.1500, Lon: 9.5167},
+	{Country: "LK", Name: "Asia/Colombo", Lat: 6.9333, Lon: 79.8500},
+	{Country: "LR", Name: "Africa/Monrovia", Lat: 6.3000, Lon: -10.7833},
+	{Country: "LS", Name: "Africa/Maseru", Lat: -29.4667, Lon: 27.5000},
+	{Country: "LT", Name: "Europe/Vilnius", Lat: 54.6833, Lon: 25.3167},
+	{Country: "LU", Name: "Europe/Luxembourg", Lat: 49.6000, Lon: 6.1500},
+	{Country: "LV", Name: "Europe/Riga", Lat: 56.9500, Lon: 24.1000},
+	{Country: "LY", Name: "Africa/Tripoli", Lat: 32.9000, Lon: 13.1833},
+	{Country: "MA", Name: "Africa/Casablanca", Lat: 33.6500, Lon: -7.5833},
+	{Country: "MC", Name: "Europe/Monaco", Lat: 43.7000, Lon: 7.3833},
+	{Country: "MD", Name: "Europe/Chisinau", Lat: 47.0000, Lon: 28.8333},
+	{Country: "ME", Name: "Europe/Podgorica", Lat: 42.4333, Lon: 19.2667},
+	{Country: "MF", Name: "America/Marigot", Lat: 18.0667, Lon: -63.0833},
+	{Country: "MG", Name: "Indian/Antananarivo", Lat: -18.9167, Lon: 47.5167},
+	{Country: "MH", Name: "Pacific/Majuro", Lat: 7.1500, Lon: 171.2000},
+	{Country: "MH", Name: "Pacific/Kwajalein", Lat: 9.0833, Lon: 167.3333},
+	{Country: "MK", Name: "Europe/Skopje", Lat: 41.9833, Lon: 21.4333},
+	{Country: "ML", Name: "Africa/Bamako", Lat: 12.6500, Lon: -8.0000},
+	{Country: "MM", Name: "Asia/Yangon", Lat: 16.7833, Lon: 96.1667},
+	{Country: "MN", Name: "Asia/Ulaanbaatar", Lat: 47.9167, Lon: 106.8833},
+	{Country: "MN", Name: "Asia/Hovd", Lat: 48.0167, Lon: 91.6500},
+	{Country: "MO", Name: "Asia/Macau", Lat: 22.1972, Lon: 113.5417},
+	{Country: "MP", Name: "Pacific/Saipan", Lat: 15.2000, Lon: 145.7500},
+	{Country: "MQ", Name: "America/Martinique", Lat: 14.6000, Lon: -61.0833},
+	{Country: "MR", Name: "Africa/Nouakchott", Lat: 18.1000, Lon: -15.9500},
+	{Country: "MS", Name: "America/Montserrat", Lat: 16.7167, Lon: -62.2167},
+	{Country: "MT", Name: "Europe/Malta", Lat: 35.9000, Lon: 14.5167},
+	{Country: "MU", Name: "Indian/Mauritius", Lat: -20.1667, Lon: 57.5000},
+	{Country: "MV", Name: "Indian/Maldives", Lat: 4.1667, Lon: 73.5000},
+	{Country: "MW", Name: "Africa/Blantyre", Lat: -15.7833, Lon: 35.0000},
+	{Country: "MX", Name: "America/Mexico_City", Lat: 19.4000, Lon: -99.1500},
+	{Country: "MX", Name: "America/Cancun", Lat: 21.0833, Lon: -86.7667},
+	{Country: "MX", Name: "America/Merida", Lat: 20.9667, Lon: -89.6167},
+	{Country: "MX", Name: "America/Monterrey", Lat: 25.6667, Lon: -100.3167},
+	{Country: "MX", Name: "America/Matamoros", Lat: 25.8333, Lon: -97.5000},
+	{Country: "MX", Name: "America/Chihuahua", Lat: 28.6333, Lon: -106.0833},
+	{Country: "MX", Name: "America/Ciudad_Juarez", Lat: 31.7333, Lon: -106.4833},
+	{Country: "MX", Name: "America/Ojinaga", Lat: 29.5667, Lon: -104.4167},
+	{Country: "MX", Name: "America/Mazatlan", Lat: 23.2167, Lon: -106.4167},
+	{Country: "MX", Name: "America/Bahia_Banderas", Lat: 20.8000, Lon: -105.2500},
+	{Country: "MX", Name: "America/Hermosillo", Lat: 29.0667, Lon: -110.9667},
+	{Country: "MX", Name: "America/Tijuana", Lat: 32.5333, Lon: -117.0167},
+	{Country: "MY", Name: "Asia/Kuala_Lumpur", Lat: 3.1667, Lon: 101.7000},
+	{Country: "MY", Name: "Asia/Kuching", Lat: 1.5500, Lon: 110.3333},
+	{Country: "MZ", Name: "Africa/Maputo", Lat: -25.9667, Lon: 32.5833},
+	{Country: "NA", Name: "Africa/Windhoek", Lat: -22.5667, Lon: 17.1000},
+	{Country: "NC", Name: "Pacific/Noumea", Lat: -22.2667, Lon: 166.4500},
+	{Country: "NE", Name: "Africa/Niamey", Lat: 13.5167, Lon: 2.1167},
+	{Country: "NF", Name: "Pacific/Norfolk", Lat: -29.0500, Lon: 167.9667},
+	{Country: "NG", Name: "Africa/Lagos", Lat: 6.4500, Lon: 3.4000},
+	{Country: "NI", Name: "America/Managua", Lat: 12.1500, Lon: -86.2833},
+	{Country: "NL", Name: "Europe/Amsterdam", Lat: 52.3667, Lon: 4.9000},
+	{Country: "NO", Name: "Europe/Oslo", Lat: 59.9167, Lon: 10.7500},
+	{Country: "NP", Name: "Asia/Kathmandu", Lat: 27.7167, Lon: 85.3167},
+	{Country: "NR", Name: "Pacific/Nauru", Lat: -0.5167, Lon: 166.9167},
+	{Country: "NU", Name: "Pacific/Niue", Lat: -19.0167, Lon: -169.9167},
+	{Country: "NZ", Name: "Pacific/Auckland", Lat: -36.8667, Lon: 174.7667},
+	{Country: "NZ", Name: "Pacific/Chatham", Lat: -43.9500, Lon: -176.5500},
+	{Country: "OM", Name: "Asia/Muscat", Lat: 23.6000, Lon: 58.5833},
+	{Country: "PA", Name: "America/Panama", Lat: 8.9667, Lon: -79.5333},
+	{Country: "PE", Name: "America/Lima", Lat: -12.0500, Lon: -77.0500},
+	{Country: "PF", Name: "Pacific/Tahiti", Lat: -17.5333, Lon: -149.5667},
+	{Country: "PF", Name: "Pacific/Marquesas", Lat: -9.0000, Lon: -139.5000},
+	{Country: "PF", Name: "Pacific/Gambier", Lat: -23.1333, Lon: -134.9500},
+	{Country: "PG", Name: "Pacific/Port_Moresby", Lat: -9.5000, Lon: 147.1667},
+	{Country: "PG", Name: "Pacific/Bougainville", Lat: -6.2167, Lon: 155.5667},
+	{Country: "PH", Name: "Asia/Manila", Lat: 14.5867, Lon: 120.9678},
+	{Country: "PK", Name: "Asia/Karachi", Lat: 24.8667, Lon: 67.0500},
+	{Country: "PL", Name: "Europe/Warsaw", Lat: 52.2500, Lon: 21.0000},
+	{Country: "PM", Name: "America/Miquelon", Lat: 47.0500, Lon: -56.3333},
+	{Country: "PN", Name: "Pacific/Pitcairn", Lat: -25.0667, Lon: -130.0833},
+	{Country: "PR", Name: "America/Puerto_Rico", Lat: 18.4683, Lon: -66.1061},
+	{Country: "PS", Name: "Asia/Gaza", Lat: 31.5000, Lon: 34.4667},
+	{Country: "PS", Name: "Asia/Hebron", Lat: 31.5333, Lon: 35.0950},
+	{Country: "PT", Name: "Europe/Lisbon", Lat: 38.7167, Lon: -9.1333},
+	{Country: "PT", Name: "Atlantic/Madeira", Lat: 32.6333, Lon: -16.9000},
+	{Country: "PT", Name: "Atlantic/Azores", Lat: 37.7333, Lon: -25.6667},
+	{Country: "PW", Name: "Pacific/Palau", Lat: 7.3333, Lon: 134.4833},
+	{Country: "PY", Name: "America/Asuncion", Lat: -25.2667, Lon: -57.6667},
+	{Country: "QA", Name: "Asia/Qatar", Lat: 25.2833, Lon: 51.5333},
+	{Country: "RE", Name: "Indian/Reunion", Lat: -20.8667, Lon: 55.4667},
+	{Country: "RO", Name: "Europe/Bucharest", Lat: 44.4333, Lon: 26.1000},
+	{Country: "RS", Name: "Europe/Belgrade", Lat: 44.8333, Lon: 20.5000},
+	{Country: "RU", Name: "Europe/Kaliningrad", Lat: 54.7167, Lon: 20.5000},
+	{Country: "RU", Name: "Europe/Moscow", Lat: 55.7558, Lon: 37.6178},
+	{Country: "UA", Name: "Europe/Simferopol", Lat: 44.9500, Lon: 34.1000},
+	{Country: "RU", Name: "Europe/Kirov", Lat: 58.6000, Lon: 49.6500},
+	{Country: "RU", Name: "Europe/Volgograd", Lat: 48.7333, Lon: 44.4167},
+	{Country: "RU", Name: "Europe/Astrakhan", Lat: 46.3500, Lon: 48.0500},
+	{Country: "RU", Name: "Europe/Saratov", Lat: 51.5667, Lon: 46.0333},
+	{Country: "RU", Name: "Europe/Ulyanovsk", Lat: 54.3333, Lon: 48.4000},
+	{Country: "RU", Name: "Europe/Samara", Lat: 53.2000, Lon: 50.1500},
+	{Country: "RU", Name: "Asia/Yekaterinburg", Lat: 56.8500, Lon: 60.6000},
+	{Country: "RU", Name: "Asia/Omsk", Lat: 55.0000, Lon: 73.4000},
+	{Country: "RU", Name: "Asia/Novosibirsk", Lat: 55.0333, Lon: 82.9167},
+	{Country: "RU", Name: "Asia/Barnaul", Lat: 53.3667, Lon: 83.7500},
+	{Country: "RU", Name: "Asia/Tomsk", Lat: 56.5000, Lon: 84.9667},
+	{Country: "RU", Name: "Asia/Novokuznetsk", Lat: 53.7500, Lon: 87.1167},
+	{Country: "RU", Name: "Asia/Krasnoyarsk", Lat: 56.0167, Lon: 92.8333},
+	{Country: "RU", Name: "Asia/Irkutsk", Lat: 52.2667, Lon: 104.3333},
+	{Country: "RU", Name: "Asia/Chita", Lat: 52.0500, Lon: 113.4667},
+	{Country: "RU", Name: "Asia/Yakutsk", Lat: 62.0000, Lon: 129.6667},
+	{Country: "RU", Name: "Asia/Khandyga", Lat: 62.6564, Lon: 135.5539},
+	{Country: "RU", Name: "Asia/Vladivostok", Lat: 43.1667, Lon: 131.9333},
+	{Country: "RU", Name: "Asia/Ust-Nera", Lat: 64.5603, Lon: 143.2267},
+	{Country: "RU", Name: "Asia/Magadan", Lat: 59.5667, Lon: 150.8000},
+	{Country: "RU", Name: "Asia/Sakhalin", Lat: 46.9667, Lon: 142.7000},
+	{Country: "RU", Name: "Asia/Srednekolymsk", Lat: 67.4667, Lon: 153.7167},
+	{Country: "RU", Name: "Asia/Kamchatka", Lat: 53.0167, Lon: 158.6500},
+	{Country: "RU", Name: "Asia/Anadyr", Lat: 64.7500, Lon: 177.4833},
+	{Country: "RW", Name: "Africa/Kigali", Lat: -1.9500, Lon: 30.0667},
+	{Country: "SA", Name: "Asia/Riyadh", Lat: 24.6333, Lon: 46.7167},
+	{Country: "SB", Name: "Pacific/Guadalcanal", Lat: -9.5333, Lon: 160.2000},
+	{Country: "SC", Name: "Indian/Mahe", Lat: -4.6667, Lon: 55.4667},
+	{Country: "SD", Name: "Africa/Khartoum", Lat: 15.6000, Lon: 32.5333},
+	{Country: "SE", Name: "Europe/Stockholm", Lat: 59.3333, Lon: 18.0500},
+	{Country: "SG", Name: "Asia/Singapore", Lat: 1.2833, Lon: 103.8500},
+	{Country: "SH", Name: "Atlantic/St_Helena", Lat: -15.9167, Lon: -5.7000},
+	{Country: "SI", Name: "Europe/Ljubljana", Lat: 46.0500, Lon: 14.5167},
+	{Country: "SJ", Name: "Arctic/Longyearbyen", Lat: 78.0000, Lon: 16.0000},
+	{Country: "SK", Name: "Europe/Bratislava", Lat: 48.1500, Lon: 17.1167},
+	{Country: "SL", Name: "Africa/Freetown", Lat: 8.5000, Lon: -13.2500},
+	{Country: "SM", Name: "Europe/San_Marino", Lat: 43.9167, Lon: 12.4667},
+	{Country: "SN", Name: "Africa/Dakar", Lat: 14.6667, Lon: -17.4333},
+	{Country: "SO", Name: "Africa/Mogadishu", Lat: 2.0667, Lon: 45.3667},
+	{Country: "SR", Name: "America/Paramaribo", Lat: 5.8333, Lon: -55.1667},
+	{Country: "SS", Name: "Africa/Juba", Lat: 4.8500, Lon: 31.6167},
+	{Country: "ST", Name: "Africa/Sao_Tome", Lat: 0.3333, Lon: 6.7333},
+	{Country: "SV", Name: "America/El_Salvador", Lat: 13.7000, Lon: -89.2000},
+	{Country: "SX", Name: "America/Lower_Princes", Lat: 18.0514, Lon: -63.0472},
+	{Country: "SY", Name: "Asia/Damascus", Lat: 33.5000, Lon: 36.3000},
+	{Country: "SZ", Name: "Africa/Mbabane", Lat: -26.3000, Lon: 31.1000},
+	{Country: "TC", Name: "America/Grand_Turk", Lat: 21.4667, Lon: -71.1333},
+	{Country: "TD", Name: "Africa/Ndjamena", Lat: 12.1167, Lon: 15.0500},
+	{Country: "TF", Name: "Indian/Kerguelen", Lat: -49.3528, Lon: 70.2175},
+	{Country: "TG", Name: "Africa/Lome", Lat: 6.1333, Lon: 1.2167},
+	{Country: "TH", Name: "Asia/Bangkok", Lat: 13.7500, Lon: 100.5167},
+	{Country: "TJ", Name: "Asia/Dushanbe", Lat: 38.5833, Lon: 68.8000},
+	{Country: "TK", Name: "Pacific/Fakaofo", Lat: -9.3667, Lon: -171.2333},
+	{Country: "TL", Name: "Asia/Dili", Lat: -8.5500, Lon: 125.5833},
+	{Country: "TM", Name: "Asia/Ashgabat", Lat: 37.9500, Lon: 58.3833},
+	{Country: "TN", Name: "Africa/Tunis", Lat: 36.8000, Lon: 10.1833},
+	{Country: "TO", Name: "Pacific/Tongatapu", Lat: -21.1333, Lon: -175.2000},
+	{Country: "TR", Name: "Europe/Istanbul", Lat: 41.0167, Lon: 28.9667},
+	{Country: "TT", Name: "America/Port_of_Spain", Lat: 10.6500, Lon: -61.5167},
+	{Country: "TV", Name: "Pacific/Funafuti", Lat: -8.5167, Lon: 179.2167},
+	{Country: "TW", Name: "Asia/Taipei", Lat: 25.0500, Lon: 121.5000},
+	{Country: "TZ", Name: "Africa/Dar_es_Salaam", Lat: -6.8000, Lon: 39.2833},
+	{Country: "UA", Name: "Europe/Kyiv", Lat: 50.4333, Lon: 30.5167},
+	{Country: "UG", Name: "Africa/Kampala", Lat: 0.3167, Lon: 32.4167},
+	{Country: "UM", Name: "Pacific/Midway", Lat: 28.2167, Lon: -177.3667},
+	{Country: "UM", Name: "Pacific/Wake", Lat: 19.2833, Lon: 166.6167},
+	{Country: "US", Name: "America/New_York", Lat: 40.7142, Lon: -74.0064},
+	{Country: "US", Name: "America/Detroit", Lat: 42.3314, Lon: -83.0458},
+	{Country: "US", Name: "America/Kentucky/Louisville", Lat: 38.2542, Lon: -85.7594},
+	{Country: "US", Name: "America/Kentucky/Monticello", Lat: 36.8297, Lon: -84.8492},
+	{Country: "US", Name: "America/Indiana/Indianapolis", Lat: 39.7683, Lon: -86.1581},
+	{Country: "US", Name: "America/Indiana/Vincennes", Lat: 38.6772, Lon: -87.5286},
+	{Country: "US", Name: "America/Indiana/Winamac", Lat: 41.0514, Lon: -86.6031},
+	{Country: "US", Name: "America/Indiana/Marengo", Lat: 38.3756, Lon: -86.3447},
+	{Country: "US", Name: "America/Indiana/Petersburg", Lat: 38.4919, Lon: -87.2786},
+	{Country: "US", Name: "America/Indiana/Vevay", Lat: 38.7478, Lon: -85.0672},
+	{Country: "US", Name: "America/Chicago", Lat: 41.8500, Lon: -87.6500},
+	{Country: "US", Name: "America/Indiana/Tell_City", Lat: 37.9531, Lon: -86.7614},
+	{Country: "US", Name: "America/Indiana/Knox", Lat: 41.2958, Lon: -86.6250},
+	{Country: "US", Name: "America/Menominee", Lat: 45.1078, Lon: -87.6142},
+	{Country: "US", Name: "America/North_Dakota/Center", Lat: 47.1164, Lon: -101.2992},
+	{Country: "US", Name: "America/North_Dakota/New_Salem", Lat: 46.8450, Lon: -101.4108},
+	{Country: "US", Name: "America/North_Dakota/Beulah", Lat: 47.2642, Lon: -101.7778},
+	{Country: "US", Name: "America/Denver", Lat: 39.7392, Lon: -104.9842},
+	{Country: "US", Name: "America/Boise", Lat: 43.6136, Lon: -116.2025},
+	{Country: "US", Name: "America/Phoenix", Lat: 33.4483, Lon: -112.0733},
+	{Country: "US", Name: "America/Los_Angeles", Lat: 34.0522, Lon: -118.2428},
+	{Country: "US", Name: "America/Anchorage", Lat: 61.2181, Lon: -149.9003},
+	{Country: "US", Name: "America/Juneau", Lat: 58.3019, Lon: -134.4197},
+	{Country: "US", Name: "America/Sitka", Lat: 57.1764, Lon: -135.3019},
+	{Country: "US", Name: "America/Metlakatla", Lat: 55.1269, Lon: -131.5764},
+	{Country: "US", Name: "America/Yakutat", Lat: 59.5469, Lon: -139.7272},
+	{Country: "US", Name: "America/Nome", Lat: 64.5011, Lon: -165.4064},
+	{Country: "US", Name: "America/Adak", Lat: 51.8800, Lon: -176.6581},
+	{Country: "US", Name: "Pacific/Honolulu", Lat: 21.3069, Lon: -157.8583},
+	{Country: "UY", Name: "America/Montevideo", Lat: -34.9092, Lon: -56.2125},
+	{Country: "UZ", Name: "Asia/Samarkand", Lat: 39.6667, Lon: 66.8000},
+	{Country: "UZ", Name: "Asia/Tashkent", Lat: 41.3333, Lon: 69.3000},
+	{Country: "VA", Name: "Europe/Vatican", Lat: 41.9022, Lon: 12.4531},
+	{Country: "VC", Name: "America/St_Vincent", Lat: 13.1500, Lon: -61.2333},
+	{Country: "VE", Name: "America/Caracas", Lat: 10.5000, Lon: -66.9333},
+	{Country: "VG", Name: "America/Tortola", Lat: 18.4500, Lon: -64.6167},
+	{Country: "VI", Name: "America/St_Thomas", Lat: 18.3500, Lon: -64.9333},
+	{Country: "VN", Name: "Asia/Ho_Chi_Minh", Lat: 10.7500, Lon: 106.6667},
+	{Country: "VU", Name: "Pacific/Efate", Lat: -17.6667, Lon: 168.4167},
+	{Country: "WF", Name: "Pacific/Wallis", Lat: -13.3000, Lon: -176.1667},
+	{Country: "WS", Name: "Pacific/Apia", Lat: -13.8333, Lon: -171.7333},
+	{Country: "YE", Name: "Asia/Aden", Lat: 12.7500, Lon: 45.2000},
+	{Country: "YT", Name: "Indian/Mayotte", Lat: -12.7833, Lon: 45.2333},
+	{Country: "ZA", Name: "Africa/Johannesburg", Lat: -26.2500, Lon: 28.0000},
+	{Country: "ZM", Name: "Africa/Lusaka", Lat: -15.4167, Lon: 28.2833},
+	{Country: "ZW", Name: "Africa/Harare", Lat: -17.8333, Lon: 31.0500},
+}